@@ -5,6 +5,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/hybridgroup/yzma/pkg/llama"
@@ -26,11 +27,13 @@ var (
 	contextSize *int
 	predictSize *int
 	batchSize   *int
+	keepSize    *int
 
 	vocab   llama.Vocab
 	model   llama.Model
 	lctx    llama.Context
 	sampler llama.Sampler
+	gen     *llama.Generator
 
 	messages []llama.ChatMessage
 )
@@ -41,12 +44,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	lib, err := loader.LoadLibrary(*libPath)
-	if err != nil {
-		fmt.Println("unable to load library", err.Error())
-		os.Exit(1)
-	}
-	if err := llama.Load(lib); err != nil {
+	if _, err := loader.LoadLibraries(*libPath, llama.Load); err != nil {
 		fmt.Println("unable to load library", err.Error())
 		os.Exit(1)
 	}
@@ -83,6 +81,10 @@ func main() {
 	llama.SamplerChainAdd(sampler, llama.SamplerInitTempExt(float32(*temperature), 0, 1.0))
 	llama.SamplerChainAdd(sampler, llama.SamplerInitDist(llama.DEFAULT_SEED))
 
+	// when the context is full, the oldest messages are discarded to make room
+	gen = llama.NewGenerator(lctx, vocab, sampler)
+	gen.Keep = *keepSize
+
 	if *template == "" {
 		*template = llama.ModelChatTemplate(model, "")
 	}
@@ -121,48 +123,54 @@ func main() {
 }
 
 func chat(text string, first bool) {
-	// call once to get the size
-	count := llama.Tokenize(vocab, text, nil, first, true)
-
-	// now get the actual tokens
-	tokens := make([]llama.Token, count)
-	llama.Tokenize(vocab, text, tokens, first, true)
-
-	batch := llama.BatchGetOne(tokens)
+	tokens, err := llama.TokenizeString(vocab, text, llama.TokenizeOptions{AddSpecial: first, ParseSpecial: true})
+	if err != nil {
+		fmt.Println("unable to tokenize", err.Error())
+		os.Exit(1)
+	}
 
 	if llama.ModelHasEncoder(model) {
-		llama.Encode(lctx, batch)
+		llama.Encode(lctx, llama.BatchGetOne(tokens))
 
 		start := llama.ModelDecoderStartToken(model)
 		if start == llama.TOKEN_NULL {
 			start = llama.VocabBOS(vocab)
 		}
 
-		batch = llama.BatchGetOne([]llama.Token{start})
+		tokens = []llama.Token{start}
+	}
+
+	if err := gen.Decode(tokens); err != nil {
+		fmt.Println("unable to decode prompt", err.Error())
+		os.Exit(1)
 	}
 
 	fmt.Println()
 
 	response := ""
-	for pos := int32(0); pos+batch.NTokens < int32(*predictSize); pos += batch.NTokens {
-		llama.Decode(lctx, batch)
-		token := llama.SamplerSample(sampler, lctx, -1)
-
-		if llama.VocabIsEOG(vocab, token) {
-			fmt.Println()
+	detok := llama.NewDetokenizer(vocab, 0, false)
+	// the Generator makes room for the response when the context is full, so without a predict
+	// size it keeps going until the end of the response
+	for n := 0; *predictSize < 0 || n < *predictSize; n++ {
+		token, err := gen.Next()
+		if err == io.EOF {
 			break
 		}
+		if err != nil {
+			fmt.Println("unable to decode", err.Error())
+			os.Exit(1)
+		}
 
-		buf := make([]byte, 256)
-		l := llama.TokenToPiece(vocab, token, buf, 0, false)
-		next := string(buf[:l])
-
-		batch = llama.BatchGetOne([]llama.Token{token})
+		next := detok.Add(token)
 
 		fmt.Print(next)
 		response += next
 	}
 
+	rest := detok.Flush()
+	fmt.Print(rest)
+	response += rest
+
 	fmt.Println()
 }
 
@@ -193,8 +201,9 @@ func handleFlags() error {
 	topP = flag.Float64("top-p", 0.9, "top-p for model")
 
 	contextSize = flag.Int("c", 4096, "context size for model")
-	predictSize = flag.Int("n", -1, "predict size for model")
+	predictSize = flag.Int("n", -1, "predict size for model, or -1 to predict until the end of each response")
 	batchSize = flag.Int("b", 2048, "max batch size for model")
+	keepSize = flag.Int("keep", 0, "tokens at the start of the chat to keep when the context is full")
 
 	flag.Parse()
 
@@ -206,13 +215,5 @@ func handleFlags() error {
 		*libPath = os.Getenv("YZMA_LIB")
 	}
 
-	if len(*libPath) == 0 {
-		return errors.New("missing lib flag or YZMA_LIB env var")
-	}
-
-	if *predictSize < 0 {
-		*predictSize = *contextSize //llama.MaxToken
-	}
-
 	return nil
 }
@@ -54,25 +54,27 @@ func describe(tmpFile string) {
 
 	fmt.Println()
 
+	detok := llama.NewDetokenizer(vocab, 0, true)
 	for i := 0; i < llama.MaxToken; i++ {
 		token := llama.SamplerSample(sampler, lctx, -1)
 
 		if llama.VocabIsEOG(vocab, token) {
-			fmt.Println()
 			break
 		}
 
-		buf := make([]byte, 128)
-		llama.TokenToPiece(vocab, token, buf, 0, true)
-
-		fmt.Print(string(buf))
+		fmt.Print(detok.Add(token))
 
 		batch.Token = &token
 		batch.Pos = &n
 
-		llama.Decode(lctx, batch)
+		if llama.Decode(lctx, batch) != 0 {
+			break
+		}
 		n++
 	}
+
+	// print any text that is still held back, however generation stopped
+	fmt.Println(detok.Flush())
 }
 
 func chatTemplate(add bool) string {
@@ -17,16 +17,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	lib, err := loader.LoadLibrary(libPath)
-	if err != nil {
-		fmt.Println("unable to load library", err.Error())
-		os.Exit(1)
-	}
-	if err := llama.Load(lib); err != nil {
-		fmt.Println("unable to load library", err.Error())
-		os.Exit(1)
-	}
-	if err := mtmd.Load(lib); err != nil {
+	if _, err := loader.LoadLibraries(libPath, llama.Load, mtmd.Load); err != nil {
 		fmt.Println("unable to load library", err.Error())
 		os.Exit(1)
 	}
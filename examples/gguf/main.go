@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hybridgroup/yzma/pkg/gguf"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		showUsage()
+		os.Exit(0)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "info":
+		err = info(os.Args[2:])
+	case "set":
+		err = set(os.Args[2:])
+	case "rm":
+		err = rm(os.Args[2:])
+	default:
+		showUsage()
+		os.Exit(0)
+	}
+
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+}
+
+func showUsage() {
+	fmt.Println(`
+Usage:
+gguf info [model file path]
+gguf set -type [value type, default is the type of the existing key or str] -o [output file path, default is to change the model file] [model file path] [key] [value]
+gguf rm -o [output file path, default is to change the model file] [model file path] [key]`)
+}
+
+func info(args []string) error {
+	flags := flag.NewFlagSet("info", flag.ExitOnError)
+	all := flags.Bool("a", false, "show array values in full")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		showUsage()
+		os.Exit(0)
+	}
+
+	f, err := gguf.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Printf("GGUF v%d, %d tensors, %d params", f.Version, len(f.Tensors), f.NParams())
+	if ftype, ok := f.Ftype(); ok {
+		fmt.Printf(", %s", ftype)
+	}
+	fmt.Print("\n\n")
+	for _, kv := range f.Metadata {
+		value := kv.Value.String()
+		if kv.Value.Type == gguf.TypeArray && !*all {
+			value = fmt.Sprintf("arr[%s,%d]", kv.Value.ArrayType, kv.Value.Len())
+		}
+
+		if len(value) > 80 && !*all {
+			value = value[:77] + "..."
+		}
+
+		fmt.Printf("%-40s %-4s %s\n", kv.Key, kv.Value.Type, strings.ReplaceAll(value, "\n", `\n`))
+	}
+
+	fmt.Println()
+	for _, t := range f.Tensors {
+		fmt.Println(t)
+	}
+
+	return nil
+}
+
+func set(args []string) error {
+	flags := flag.NewFlagSet("set", flag.ExitOnError)
+	typ := flags.String("type", "", "value type, such as str, u32, i32, f32 or bool")
+	out := flags.String("o", "", "output file path")
+	flags.Parse(args)
+
+	if flags.NArg() != 3 {
+		showUsage()
+		os.Exit(0)
+	}
+
+	src, key, text := flags.Arg(0), flags.Arg(1), flags.Arg(2)
+	if *out == "" {
+		*out = src
+	}
+
+	return gguf.Rewrite(src, *out, func(f *gguf.File) error {
+		t := gguf.TypeString
+		if existing, ok := f.Get(key); ok {
+			t = existing.Type
+		}
+
+		if *typ != "" {
+			var err error
+			if t, err = gguf.ParseValueType(*typ); err != nil {
+				return err
+			}
+		}
+
+		v, err := gguf.ParseValue(t, text)
+		if err != nil {
+			return err
+		}
+
+		f.Set(key, v)
+		return nil
+	})
+}
+
+func rm(args []string) error {
+	flags := flag.NewFlagSet("rm", flag.ExitOnError)
+	out := flags.String("o", "", "output file path")
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		showUsage()
+		os.Exit(0)
+	}
+
+	src, key := flags.Arg(0), flags.Arg(1)
+	if *out == "" {
+		*out = src
+	}
+
+	return gguf.Rewrite(src, *out, func(f *gguf.File) error {
+		if !f.Delete(key) {
+			return fmt.Errorf("%s does not have the key %s", src, key)
+		}
+
+		return nil
+	})
+}
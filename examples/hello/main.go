@@ -15,47 +15,54 @@ var (
 )
 
 func main() {
-	lib, err := loader.LoadLibrary(libPath)
-	if err != nil {
-		panic(err)
-	}
-	if err := llama.Load(lib); err != nil {
+	if _, err := loader.LoadLibraries(libPath, llama.Load); err != nil {
 		panic(err)
 	}
 
 	llama.Init()
 
-	model := llama.ModelLoadFromFile(modelFile, llama.ModelDefaultParams())
+	model, err := llama.LoadModel(modelFile, llama.ModelDefaultParams())
+	if err != nil {
+		panic(err)
+	}
+	defer model.Close()
+
 	vocab := llama.ModelGetVocab(model)
 
 	sampler := llama.SamplerChainInit(llama.SamplerChainDefaultParams())
 	llama.SamplerChainAdd(sampler, llama.SamplerInitGreedy())
+	defer sampler.Close()
 
-	// call once to get the size of the tokens from the prompt
-	count := llama.Tokenize(vocab, prompt, nil, true, false)
+	tokens, err := llama.TokenizeString(vocab, prompt, llama.TokenizeOptions{AddSpecial: true})
+	if err != nil {
+		panic(err)
+	}
+	count := int32(len(tokens))
+
+	lctx, err := llama.NewContext(model, llama.ContextDefaultParams())
+	if err != nil {
+		panic(err)
+	}
+	defer lctx.Close()
 
-	// now get the actual tokens
-	tokens := make([]llama.Token, count)
-	llama.Tokenize(vocab, prompt, tokens, true, false)
+	detok := llama.NewDetokenizer(vocab, 0, true)
 
-	lctx := llama.InitFromModel(model, llama.ContextDefaultParams())
 	batch := llama.BatchGetOne(tokens)
 	for pos := int32(0); pos+batch.NTokens < count+responseLength; pos += batch.NTokens {
-		llama.Decode(lctx, batch)
+		if err := lctx.Decode(batch); err != nil {
+			panic(err)
+		}
+
 		token := llama.SamplerSample(sampler, lctx, -1)
 
 		if llama.VocabIsEOG(vocab, token) {
-			fmt.Println()
 			break
 		}
 
-		buf := make([]byte, 36)
-		len := llama.TokenToPiece(vocab, token, buf, 0, true)
-
-		fmt.Print(string(buf[:len]))
+		fmt.Print(detok.Add(token))
 
 		batch = llama.BatchGetOne([]llama.Token{token})
 	}
 
-	fmt.Println()
+	fmt.Println(detok.Flush())
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hybridgroup/yzma/pkg/gguf"
+	"github.com/hybridgroup/yzma/pkg/llama"
+	"github.com/hybridgroup/yzma/pkg/loader"
+)
+
+var (
+	srcFile string
+	dstFile string
+
+	ftype              *string
+	threads            *int
+	outputType         *string
+	tokenEmbeddingType *string
+	allowRequantize    *bool
+	leaveOutput        *bool
+	pure               *bool
+	keepSplit          *bool
+	libPath            *string
+	verbose            *bool
+)
+
+func main() {
+	if err := handleFlags(); err != nil {
+		showUsage()
+		os.Exit(0)
+	}
+
+	params, err := quantizeParams()
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	if _, err := loader.LoadLibraries(*libPath, llama.Load); err != nil {
+		fmt.Println("unable to load library", err.Error())
+		os.Exit(1)
+	}
+
+	if !*verbose {
+		llama.LogSet(llama.LogSilent(), uintptr(0))
+	}
+
+	llama.Init()
+	defer llama.BackendFree()
+
+	fmt.Printf("quantizing %s to %s as %s\n", srcFile, dstFile, params.Ftype)
+	if err := llama.Quantize(srcFile, dstFile, params); err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+}
+
+func quantizeParams() (llama.ModelQuantizeParams, error) {
+	params := llama.ModelQuantizeDefaultParams()
+
+	var err error
+	if params.Ftype, err = llama.ParseFtype(*ftype); err != nil {
+		return params, err
+	}
+
+	if *outputType != "" {
+		t, err := gguf.ParseTensorType(*outputType)
+		if err != nil {
+			return params, err
+		}
+		params.OutputTensorType = int32(t)
+	}
+
+	if *tokenEmbeddingType != "" {
+		t, err := gguf.ParseTensorType(*tokenEmbeddingType)
+		if err != nil {
+			return params, err
+		}
+		params.TokenEmbeddingType = int32(t)
+	}
+
+	params.NThread = int32(*threads)
+	params.AllowRequantize = boolToUint8(*allowRequantize)
+	params.QuantizeOutputTensor = boolToUint8(!*leaveOutput)
+	params.Pure = boolToUint8(*pure)
+	params.KeepSplit = boolToUint8(*keepSplit)
+
+	return params, nil
+}
+
+func boolToUint8(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func showUsage() {
+	fmt.Println(`
+Usage:
+quantize -type [Q4_K_M, Q8_0 and so on] -lib [llama.cpp .so file path] [input model file path] [output model file path]`)
+}
+
+func handleFlags() error {
+	ftype = flag.String("type", "Q4_K_M", "type to quantize to, such as Q4_K_M or Q8_0")
+	threads = flag.Int("t", 0, "number of threads to use, 0 for the number of CPUs")
+	outputType = flag.String("output-type", "", "tensor type to use for the output.weight tensor, such as q8_0")
+	tokenEmbeddingType = flag.String("token-embedding-type", "", "tensor type to use for the token embeddings tensor, such as q8_0")
+	allowRequantize = flag.Bool("allow-requantize", false, "allow requantizing tensors that are already quantized")
+	leaveOutput = flag.Bool("leave-output", false, "leave the output.weight tensor unquantized")
+	pure = flag.Bool("pure", false, "quantize all tensors to the same type, without k-quant mixtures")
+	keepSplit = flag.Bool("keep-split", false, "quantize to the same number of shards")
+	libPath = flag.String("lib", "", "path to llama.cpp compiled library files")
+	verbose = flag.Bool("v", false, "verbose logging")
+
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		return errors.New("missing input or output file")
+	}
+
+	srcFile, dstFile = flag.Arg(0), flag.Arg(1)
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/hybridgroup/yzma/pkg/llama"
+	"github.com/hybridgroup/yzma/pkg/loader"
+)
+
+var (
+	modelFile    *string
+	libPath      *string
+	noBOS        *bool
+	parseSpecial *bool
+	idsOnly      *bool
+	countOnly    *bool
+	verbose      *bool
+
+	inputFile string
+)
+
+func main() {
+	if err := handleFlags(); err != nil {
+		showUsage()
+		os.Exit(0)
+	}
+
+	text, err := readInput()
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	if _, err := loader.LoadLibraries(*libPath, llama.Load); err != nil {
+		fmt.Println("unable to load library", err.Error())
+		os.Exit(1)
+	}
+
+	if !*verbose {
+		llama.LogSet(llama.LogSilent(), uintptr(0))
+	}
+
+	llama.Init()
+	defer llama.BackendFree()
+
+	tok, err := llama.LoadTokenizer(*modelFile)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	defer tok.Close()
+
+	tokens, err := tok.Encode(text, !*noBOS, *parseSpecial)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	switch {
+	case *countOnly:
+		fmt.Println(len(tokens))
+	case *idsOnly:
+		for _, token := range tokens {
+			fmt.Println(token)
+		}
+	default:
+		for _, token := range tokens {
+			fmt.Printf("%6d -> %s\n", token, strconv.Quote(tok.Piece(token, true)))
+		}
+		fmt.Printf("%d tokens\n", len(tokens))
+	}
+}
+
+// readInput reads the text to tokenize from the input file, or from stdin if there is none.
+func readInput() (string, error) {
+	if inputFile == "" || inputFile == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return string(data), err
+	}
+
+	data, err := os.ReadFile(inputFile)
+	return string(data), err
+}
+
+func showUsage() {
+	fmt.Println(`
+Usage:
+tokenize -model [model file path] -lib [llama.cpp .so file path] [text file path, default is stdin]`)
+}
+
+func handleFlags() error {
+	modelFile = flag.String("model", "", "model file to use for the vocabulary")
+	libPath = flag.String("lib", "", "path to llama.cpp compiled library files")
+	noBOS = flag.Bool("no-bos", false, "do not add the BOS token and any other special tokens that the model adds")
+	parseSpecial = flag.Bool("parse-special", false, "convert special tokens in the text, such as <|im_start|>, into their tokens")
+	idsOnly = flag.Bool("ids", false, "only print the token ids")
+	countOnly = flag.Bool("count", false, "only print the number of tokens")
+	verbose = flag.Bool("v", false, "verbose logging")
+
+	flag.Parse()
+
+	if *modelFile == "" {
+		return errors.New("missing model file")
+	}
+
+	if flag.NArg() > 1 {
+		return errors.New("too many arguments")
+	}
+	inputFile = flag.Arg(0)
+
+	return nil
+}
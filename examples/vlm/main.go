@@ -44,16 +44,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	lib, err := loader.LoadLibrary(*libPath)
-	if err != nil {
-		fmt.Println("unable to load library", err.Error())
-		os.Exit(1)
-	}
-	if err := llama.Load(lib); err != nil {
-		fmt.Println("unable to load library", err.Error())
-		os.Exit(1)
-	}
-	if err := mtmd.Load(lib); err != nil {
+	if _, err := loader.LoadLibraries(*libPath, llama.Load, mtmd.Load); err != nil {
 		fmt.Println("unable to load library", err.Error())
 		os.Exit(1)
 	}
@@ -107,25 +98,27 @@ func main() {
 
 	fmt.Println()
 
-	for i := 0; i < llama.MaxToken; i++ {
+	detok := llama.NewDetokenizer(vocab, 0, true)
+	for i := 0; *predictSize < 0 || i < *predictSize; i++ {
 		token := llama.SamplerSample(sampler, lctx, -1)
 
 		if llama.VocabIsEOG(vocab, token) {
-			fmt.Println()
 			break
 		}
 
-		buf := make([]byte, 128)
-		llama.TokenToPiece(vocab, token, buf, 0, true)
-
-		fmt.Print(string(buf))
+		fmt.Print(detok.Add(token))
 
 		batch.Token = &token
 		batch.Pos = &n
 
-		llama.Decode(lctx, batch)
+		if llama.Decode(lctx, batch) != 0 {
+			break
+		}
 		n++
 	}
+
+	// print any text that is still held back, however generation stopped
+	fmt.Println(detok.Flush())
 }
 
 func chatTemplate(add bool) string {
@@ -157,7 +150,7 @@ func handleFlags() error {
 	topP = flag.Float64("top-p", 0.9, "top-p for model")
 
 	contextSize = flag.Int("c", 4096, "context size for model")
-	predictSize = flag.Int("n", -1, "predict size for model")
+	predictSize = flag.Int("n", -1, "predict size for model, or -1 to predict until the end of the response")
 	batchSize = flag.Int("b", 2048, "max batch size for model")
 
 	flag.Parse()
@@ -174,9 +167,5 @@ func handleFlags() error {
 		*libPath = os.Getenv("YZMA_LIB")
 	}
 
-	if len(*libPath) == 0 {
-		return errors.New("missing lib flag or YZMA_LIB env var")
-	}
-
 	return nil
 }
@@ -0,0 +1,234 @@
+// Package fake is a pure-Go backend for the llama and mtmd packages, so that code built on yzma
+// can be tested without the llama.cpp libraries being installed.
+//
+// It serves a deterministic toy model with a byte-level vocabulary, and every sampler picks the
+// most likely token. Pass it wherever a [loader.Library] is expected:
+//
+//	lib := fake.New()
+//	llama.Load(lib)
+//	mtmd.Load(lib)
+//
+// The functions are real C function pointers created with libffi closures, so the bindings are
+// exercised exactly as they are when calling into llama.cpp.
+package fake
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/hybridgroup/yzma/pkg/utils"
+	"github.com/jupiterrider/ffi"
+)
+
+// handler implements a single library function. ret points to the memory for the return value,
+// and each of args points to the value of an argument as described by the ffi.Cif.
+type handler func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer)
+
+// handlers has the implementation of every function in the fake library.
+var handlers = func() map[string]handler {
+	all := make(map[string]handler)
+	for _, m := range []map[string]handler{llamaHandlers, loraHandlers, stateHandlers, mtmdHandlers} {
+		for name, fn := range m {
+			all[name] = fn
+		}
+	}
+	return all
+}()
+
+var (
+	callbackOnce sync.Once
+	callback     uintptr
+
+	// prepared maps the ffi.Cif of every prepared function to its implementation.
+	prepared sync.Map
+)
+
+type binding struct {
+	lib *Lib
+	fn  handler
+}
+
+// Lib is a fake llama.cpp library. Use [New] to create one.
+type Lib struct {
+	mu      sync.Mutex
+	next    uintptr
+	objects map[uintptr]any
+
+	// keep holds Go memory that has been handed out to callers as a C pointer.
+	keep map[unsafe.Pointer]any
+
+	omitted map[string]bool
+}
+
+// New returns a new fake library with no models or contexts.
+func New() *Lib {
+	return &Lib{
+		next:    0x1000,
+		objects: make(map[uintptr]any),
+		keep:    make(map[unsafe.Pointer]any),
+		omitted: make(map[string]bool),
+	}
+}
+
+// Omit removes functions from the library, to act like a build of llama.cpp that does not have them.
+func (l *Lib) Omit(names ...string) *Lib {
+	for _, name := range names {
+		l.omitted[name] = true
+	}
+
+	return l
+}
+
+// Get reports whether the named function is implemented by returning a nil error.
+// The fake functions only have an address once they are prepared, so the address is always 0.
+func (l *Lib) Get(name string) (uintptr, error) {
+	if _, ok := handlers[name]; !ok || l.omitted[name] {
+		return 0, fmt.Errorf("%s: undefined symbol", name)
+	}
+
+	return 0, nil
+}
+
+// Prep returns a function that calls into the fake implementation of the named function.
+// It returns an error if the function is not implemented, just as a missing symbol would.
+func (l *Lib) Prep(name string, ret *ffi.Type, args ...*ffi.Type) (ffi.Fun, error) {
+	if _, err := l.Get(name); err != nil {
+		return ffi.Fun{}, err
+	}
+	fn := handlers[name]
+
+	callbackOnce.Do(func() {
+		callback = ffi.NewCallback(dispatch)
+	})
+
+	cif := new(ffi.Cif)
+	if status := ffi.PrepCif(cif, ffi.DefaultAbi, uint32(len(args)), ret, args...); status != ffi.OK {
+		return ffi.Fun{}, fmt.Errorf("%s: error preparing function: %s", name, status)
+	}
+
+	var code unsafe.Pointer
+	closure := ffi.ClosureAlloc(unsafe.Sizeof(ffi.Closure{}), &code)
+	if closure == nil {
+		return ffi.Fun{}, fmt.Errorf("%s: unable to allocate closure", name)
+	}
+
+	if status := ffi.PrepClosureLoc(closure, cif, callback, nil, code); status != ffi.OK {
+		return ffi.Fun{}, fmt.Errorf("%s: error preparing closure: %s", name, status)
+	}
+
+	prepared.Store(cif, binding{lib: l, fn: fn})
+
+	return ffi.Fun{Addr: uintptr(code), Cif: cif}, nil
+}
+
+func dispatch(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer, userData unsafe.Pointer) uintptr {
+	v, ok := prepared.Load(cif)
+	if !ok {
+		panic("fake: call to a function that was not prepared")
+	}
+
+	b := v.(binding)
+	b.lib.mu.Lock()
+	defer b.lib.mu.Unlock()
+
+	b.fn(b.lib, ret, unsafe.Slice(args, cif.NArgs))
+	return 0
+}
+
+// add stores an object and returns the opaque handle that represents it.
+func (l *Lib) add(obj any) uintptr {
+	l.next += 0x10
+	l.objects[l.next] = obj
+
+	return l.next
+}
+
+// remove deletes the object for a handle.
+func (l *Lib) remove(h uintptr) {
+	delete(l.objects, h)
+}
+
+// get returns the object of type T for a handle, or nil if there is none.
+func get[T any](l *Lib, h uintptr) *T {
+	obj, ok := l.objects[h].(*T)
+	if !ok {
+		return nil
+	}
+
+	return obj
+}
+
+// cString returns a pointer to a NUL terminated copy of s that stays valid for the life of the Lib.
+func (l *Lib) cString(s string) unsafe.Pointer {
+	b := []byte(s + "\x00")
+	p := unsafe.Pointer(&b[0])
+	l.keep[p] = b
+
+	return p
+}
+
+func handleArg(args []unsafe.Pointer, i int) uintptr {
+	return *(*uintptr)(args[i])
+}
+
+func pointerArg(args []unsafe.Pointer, i int) unsafe.Pointer {
+	return *(*unsafe.Pointer)(args[i])
+}
+
+func stringArg(args []unsafe.Pointer, i int) string {
+	return utils.BytePtrToString((*byte)(pointerArg(args, i)))
+}
+
+func int32Arg(args []unsafe.Pointer, i int) int32 {
+	return *(*int32)(args[i])
+}
+
+func uint32Arg(args []unsafe.Pointer, i int) uint32 {
+	return *(*uint32)(args[i])
+}
+
+func uint64Arg(args []unsafe.Pointer, i int) uint64 {
+	return *(*uint64)(args[i])
+}
+
+func boolArg(args []unsafe.Pointer, i int) bool {
+	return *(*uint8)(args[i]) != 0
+}
+
+// setInt sets an integer return value. libffi always expects a full ffi.Arg for integers.
+func setInt(ret unsafe.Pointer, v int64) {
+	*(*ffi.Arg)(ret) = ffi.Arg(v)
+}
+
+func setBool(ret unsafe.Pointer, v bool) {
+	if v {
+		setInt(ret, 1)
+		return
+	}
+	setInt(ret, 0)
+}
+
+func setFloat(ret unsafe.Pointer, v float32) {
+	*(*float32)(ret) = v
+}
+
+// snprintf copies s into the buffer at args[buf] with the size at args[size] like C's snprintf,
+// and returns the length of s.
+func snprintf(args []unsafe.Pointer, buf, size int, s string) int64 {
+	n := uint64Arg(args, size)
+	if p := pointerArg(args, buf); p != nil && n > 0 {
+		out := unsafe.Slice((*byte)(p), n)
+		out[copy(out[:n-1], s)] = 0
+	}
+
+	return int64(len(s))
+}
+
+func setHandle(ret unsafe.Pointer, h uintptr) {
+	*(*uintptr)(ret) = h
+}
+
+func setPointer(ret unsafe.Pointer, p unsafe.Pointer) {
+	*(*unsafe.Pointer)(ret) = p
+}
@@ -0,0 +1,59 @@
+package fake_test
+
+import (
+	"testing"
+
+	"github.com/hybridgroup/yzma/pkg/fake"
+	"github.com/hybridgroup/yzma/pkg/llama"
+	"github.com/jupiterrider/ffi"
+)
+
+func TestPrepUndefined(t *testing.T) {
+	if _, err := fake.New().Prep("llama_does_not_exist", &ffi.TypeVoid); err == nil {
+		t.Fatal("expected an error for an undefined function")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	if err := llama.Load(fake.New()); err != nil {
+		t.Fatal("unable to load fake library", err.Error())
+	}
+
+	model := llama.ModelLoadFromFile("toy.gguf", llama.ModelDefaultParams())
+	defer llama.ModelFree(model)
+
+	lctx := llama.InitFromModel(model, llama.ContextDefaultParams())
+	defer llama.Free(lctx)
+
+	sampler := llama.SamplerChainInit(llama.SamplerChainDefaultParams())
+	llama.SamplerChainAdd(sampler, llama.SamplerInitGreedy())
+	defer llama.SamplerFree(sampler)
+
+	vocab := llama.ModelGetVocab(model)
+	count := llama.Tokenize(vocab, "wx", nil, true, false)
+	tokens := make([]llama.Token, count)
+	llama.Tokenize(vocab, "wx", tokens, true, false)
+
+	result := ""
+	batch := llama.BatchGetOne(tokens)
+	for i := 0; i < 10; i++ {
+		if llama.Decode(lctx, batch) != 0 {
+			t.Fatal("unable to decode")
+		}
+
+		token := llama.SamplerSample(sampler, lctx, -1)
+		if llama.VocabIsEOG(vocab, token) {
+			break
+		}
+
+		buf := make([]byte, 8)
+		l := llama.TokenToPiece(vocab, token, buf, 0, false)
+		result += string(buf[:l])
+
+		batch = llama.BatchGetOne([]llama.Token{token})
+	}
+
+	if result != "yz." {
+		t.Fatalf("unexpected output from toy model: %q", result)
+	}
+}
@@ -0,0 +1,1250 @@
+package fake
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/hybridgroup/yzma/pkg/utils"
+	"github.com/jupiterrider/ffi"
+)
+
+// The following types mirror the memory layout of the llama.cpp structs that are passed by value.
+
+type batchType struct {
+	NTokens int32
+	Token   *int32
+	Embd    *float32
+	Pos     *int32
+	NSeqId  *int32
+	SeqId   **int32
+	Logits  *int8
+}
+
+type modelParamsType struct {
+	Devices                  uintptr
+	TensorBuftOverrides      uintptr
+	NGpuLayers               int32
+	SplitMode                int32
+	MainGpu                  int32
+	TensorSplit              uintptr
+	ProgressCallback         uintptr
+	ProgressCallbackUserData uintptr
+	KvOverrides              unsafe.Pointer
+	VocabOnly                uint8
+	UseMmap                  uint8
+	UseMlock                 uint8
+	CheckTensors             uint8
+	UseExtraBufts            uint8
+}
+
+// ggmlTypeCount is GGML_TYPE_COUNT, which the quantize params use for the default tensor type.
+const ggmlTypeCount = 40
+
+type modelQuantizeParamsType struct {
+	NThread              int32
+	Ftype                int32
+	OutputTensorType     int32
+	TokenEmbeddingType   int32
+	AllowRequantize      uint8
+	QuantizeOutputTensor uint8
+	OnlyCopy             uint8
+	Pure                 uint8
+	KeepSplit            uint8
+	IMatrix              *cxxHashtable
+	KvOverrides          uintptr
+	TensorTypes          *cxxVector
+	PruneLayers          uintptr
+}
+
+// cxxString, cxxVector and cxxHashNode have the libstdc++ layouts of the containers in the
+// quantize params, which are read the way that llama.cpp reads them.
+type cxxString struct {
+	data     *byte
+	size     uintptr
+	capacity uintptr
+	_        uintptr
+}
+
+type cxxVector struct {
+	begin unsafe.Pointer
+	end   unsafe.Pointer
+	cap   unsafe.Pointer
+}
+
+type cxxHashNode struct {
+	next  *cxxHashNode
+	key   cxxString
+	value cxxVector
+	hash  uintptr
+}
+
+type cxxHashtable struct {
+	buckets       *unsafe.Pointer
+	bucketCount   uintptr
+	beforeBegin   *cxxHashNode
+	elementCount  uintptr
+	maxLoadFactor float32
+	nextResize    uintptr
+	singleBucket  unsafe.Pointer
+}
+
+type tensorQuantizationType struct {
+	name  cxxString
+	quant int32
+}
+
+type contextParamsType struct {
+	NCtx               uint32
+	NBatch             uint32
+	NUbatch            uint32
+	NSeqMax            uint32
+	NThreads           int32
+	NThreadsBatch      int32
+	RopeScalingType    int32
+	PoolingType        int32
+	AttentionType      int32
+	FlashAttentionType int32
+	RopeFreqBase       float32
+	RopeFreqScale      float32
+	YarnExtFactor      float32
+	YarnAttnFactor     float32
+	YarnBetaFast       float32
+	YarnBetaSlow       float32
+	YarnOrigCtx        uint32
+	DefragThold        float32
+	CbEval             uintptr
+	CbEvalUserData     uintptr
+	TypeK              int32
+	TypeV              int32
+	AbortCallback      uintptr
+	AbortCallbackData  uintptr
+	Embeddings         uint8
+	OffloadKqv         uint8
+	NoPerf             uint8
+	OpOffload          uint8
+	SwaFull            uint8
+	KVUnified          uint8
+}
+
+type chatMessageType struct {
+	Role    *byte
+	Content *byte
+}
+
+var defaultModelParams = modelParamsType{
+	NGpuLayers:    999,
+	SplitMode:     1,
+	UseMmap:       1,
+	UseExtraBufts: 1,
+}
+
+var defaultContextParams = contextParamsType{
+	NCtx:               4096,
+	NBatch:             2048,
+	NUbatch:            512,
+	NSeqMax:            1,
+	NThreads:           4,
+	NThreadsBatch:      4,
+	RopeScalingType:    -1,
+	PoolingType:        -1,
+	AttentionType:      -1,
+	FlashAttentionType: -1,
+	YarnExtFactor:      -1,
+	YarnAttnFactor:     -1,
+	YarnBetaFast:       -1,
+	YarnBetaSlow:       -1,
+	DefragThold:        -1,
+	TypeK:              1,
+	TypeV:              1,
+	OffloadKqv:         1,
+	NoPerf:             1,
+	OpOffload:          1,
+	SwaFull:            1,
+}
+
+// cell is one entry in the KV cache of a context.
+type cell struct {
+	pos   int32
+	token int32
+	seqs  map[int32]bool
+}
+
+type context struct {
+	model   *model
+	handle  uintptr // of the model
+	params  contextParamsType
+	memory  uintptr
+	cells   []*cell
+	outputs map[int32][]float32
+	last    int32
+
+	// adapters has the scale of every LoRA adapter applied to the context.
+	adapters map[uintptr]float32
+	cvec     *controlVector
+
+	// embd has the embeddings of the outputs of the last batch, and embdSeq the pooled embeddings
+	// of each sequence in it, when the context computes embeddings.
+	embd    map[int32][]float32
+	embdSeq map[int32][]float32
+
+	// logitsBuf and embdBuf are the buffers returned by llama_get_logits and llama_get_embeddings.
+	logitsBuf []float32
+	embdBuf   []float32
+}
+
+type memory struct {
+	ctx *context
+}
+
+type sampler struct {
+	chain []uintptr
+}
+
+// batchMemory is the Go memory behind a batch created by llama_batch_init.
+type batchMemory struct {
+	token  []int32
+	embd   []float32
+	pos    []int32
+	nSeqId []int32
+	seqId  []*int32
+	seqs   [][]int32
+	logits []int8
+}
+
+var llamaHandlers = map[string]handler{
+	"llama_backend_init":              func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {},
+	"llama_backend_free":              func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {},
+	"ggml_backend_load_all":           func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {},
+	"ggml_backend_load_all_from_path": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {},
+	"llama_log_set":                   func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {},
+
+	"llama_print_system_info": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setPointer(ret, l.cString("CPU : FAKE = 1 | "))
+	},
+	"ggml_version": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setPointer(ret, l.cString(Version))
+	},
+	"ggml_commit": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setPointer(ret, l.cString("fake"))
+	},
+
+	"llama_model_default_params": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		*(*modelParamsType)(ret) = defaultModelParams
+	},
+	"llama_model_load_from_file":   modelLoadFromFile,
+	"llama_model_load_from_splits": modelLoadFromSplits,
+	"llama_model_save_to_file":     modelSaveToFile,
+	"llama_split_path": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		path := stringArg(args, 2) + splitSuffix(int32Arg(args, 3), int32Arg(args, 4))
+		setInt(ret, min(snprintf(args, 0, 1, path), int64(uint64Arg(args, 1))-1))
+	},
+	"llama_split_prefix": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		prefix, ok := strings.CutSuffix(stringArg(args, 2), splitSuffix(int32Arg(args, 3), int32Arg(args, 4)))
+		if !ok {
+			setInt(ret, 0)
+			return
+		}
+		snprintf(args, 0, 1, prefix)
+		setInt(ret, int64(len(prefix)))
+	},
+	"llama_model_free": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		h := handleArg(args, 0)
+		if m := get[model](l, h); m != nil {
+			l.remove(m.vocab)
+		}
+		l.remove(h)
+	},
+	"llama_init_from_model": initFromModel,
+	"llama_model_chat_template": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		if pointerArg(args, 1) != nil {
+			setPointer(ret, nil)
+			return
+		}
+		setPointer(ret, l.cString(chatTemplate))
+	},
+	"llama_model_has_encoder": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setBool(ret, false)
+	},
+	"llama_model_has_decoder": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setBool(ret, true)
+	},
+	"llama_model_decoder_start_token": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, -1)
+	},
+	"llama_model_n_ctx_train": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		m := get[model](l, handleArg(args, 0))
+		if m == nil {
+			setInt(ret, 0)
+			return
+		}
+		setInt(ret, m.nCtxTrain)
+	},
+	"llama_model_n_embd": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, NEmbd)
+	},
+	"llama_model_n_layer": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, NLayer)
+	},
+	"llama_model_n_head": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, NHead)
+	},
+	"llama_model_n_head_kv": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, NHead)
+	},
+	"llama_model_rope_type": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, 0)
+	},
+	"llama_model_rope_freq_scale_train": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setFloat(ret, 1)
+	},
+	"llama_model_meta_count": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		m := get[model](l, handleArg(args, 0))
+		if m == nil {
+			setInt(ret, -1)
+			return
+		}
+		setInt(ret, int64(len(m.metadata)))
+	},
+	"llama_model_meta_key_by_index": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		m := get[model](l, handleArg(args, 0))
+		i := int32Arg(args, 1)
+		if m == nil || i < 0 || int(i) >= len(m.metadata) {
+			setInt(ret, -1)
+			return
+		}
+		setInt(ret, snprintf(args, 2, 3, m.metadata[i][0]))
+	},
+	"llama_model_meta_val_str": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		m := get[model](l, handleArg(args, 0))
+		if m == nil {
+			setInt(ret, -1)
+			return
+		}
+		v, ok := m.metaValue(stringArg(args, 1))
+		if !ok {
+			setInt(ret, -1)
+			return
+		}
+		setInt(ret, snprintf(args, 2, 3, v))
+	},
+	"llama_model_desc": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, snprintf(args, 1, 2, Description))
+	},
+	"llama_model_size": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, NParams*2)
+	},
+	"llama_model_n_params": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, NParams)
+	},
+
+	"llama_model_quantize_default_params": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		*(*modelQuantizeParamsType)(ret) = modelQuantizeParamsType{
+			Ftype:                9,
+			OutputTensorType:     ggmlTypeCount,
+			TokenEmbeddingType:   ggmlTypeCount,
+			QuantizeOutputTensor: 1,
+		}
+	},
+	"llama_model_quantize": modelQuantize,
+
+	"llama_batch_init":    batchInit,
+	"llama_batch_free":    batchFree,
+	"llama_batch_get_one": batchGetOne,
+
+	"llama_model_get_vocab": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		var h uintptr
+		if m := get[model](l, handleArg(args, 0)); m != nil {
+			h = m.vocab
+		}
+		setHandle(ret, h)
+	},
+	"llama_vocab_bos": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, int64(TokenBOS))
+	},
+	"llama_vocab_eos": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, int64(TokenEOS))
+	},
+	"llama_vocab_is_eog": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setBool(ret, int32Arg(args, 1) == TokenEOS)
+	},
+	"llama_vocab_is_control": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		token := int32Arg(args, 1)
+		setBool(ret, token == TokenBOS || token == TokenEOS)
+	},
+	"llama_vocab_n_tokens": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, NVocab)
+	},
+	"llama_vocab_type": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, VocabType)
+	},
+	"llama_vocab_get_text": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setPointer(ret, l.cString(tokenText(int32Arg(args, 1))))
+	},
+	"llama_vocab_get_score": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setFloat(ret, tokenScore(int32Arg(args, 1)))
+	},
+	"llama_vocab_get_attr": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, int64(tokenAttr(int32Arg(args, 1))))
+	},
+	"llama_vocab_eot": vocabTokenNull,
+	"llama_vocab_sep": vocabTokenNull,
+	"llama_vocab_nl": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, int64(TokenByte0+'\n'))
+	},
+	"llama_vocab_pad":     vocabTokenNull,
+	"llama_vocab_fim_pre": vocabTokenNull,
+	"llama_vocab_fim_suf": vocabTokenNull,
+	"llama_vocab_fim_mid": vocabTokenNull,
+	"llama_vocab_fim_pad": vocabTokenNull,
+	"llama_vocab_fim_rep": vocabTokenNull,
+	"llama_vocab_fim_sep": vocabTokenNull,
+	"llama_vocab_get_add_bos": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setBool(ret, true)
+	},
+	"llama_vocab_get_add_eos": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setBool(ret, false)
+	},
+	"llama_token_to_piece": tokenToPiece,
+	"llama_tokenize":       tokenizeText,
+	"llama_detokenize":     detokenize,
+
+	"llama_sampler_chain_default_params": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		*(*uint8)(ret) = 1
+	},
+	"llama_sampler_chain_init": samplerInit,
+	"llama_sampler_chain_add": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		if chain := get[sampler](l, handleArg(args, 0)); chain != nil {
+			chain.chain = append(chain.chain, handleArg(args, 1))
+		}
+	},
+	"llama_sampler_init_greedy":      samplerInit,
+	"llama_sampler_init_dist":        samplerInit,
+	"llama_sampler_init_logit_bias":  samplerInit,
+	"llama_sampler_init_penalties":   samplerInit,
+	"llama_sampler_init_dry":         samplerInit,
+	"llama_sampler_init_top_n_sigma": samplerInit,
+	"llama_sampler_init_top_k":       samplerInit,
+	"llama_sampler_init_typical":     samplerInit,
+	"llama_sampler_init_top_p":       samplerInit,
+	"llama_sampler_init_min_p":       samplerInit,
+	"llama_sampler_init_xtc":         samplerInit,
+	"llama_sampler_init_temp_ext":    samplerInit,
+	"llama_sampler_init_grammar":     samplerInit,
+	"llama_sampler_sample":           samplerSample,
+	"llama_sampler_accept":           func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {},
+	"llama_sampler_reset":            func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {},
+	"llama_sampler_free":             samplerFree,
+
+	"llama_chat_apply_template": chatApplyTemplate,
+
+	"llama_context_default_params": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		*(*contextParamsType)(ret) = defaultContextParams
+	},
+	"llama_free": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		h := handleArg(args, 0)
+		if ctx := get[context](l, h); ctx != nil {
+			l.remove(ctx.memory)
+		}
+		l.remove(h)
+	},
+	"llama_set_warmup": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {},
+	"llama_encode": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		// the toy model has no encoder
+		setInt(ret, -1)
+	},
+	"llama_decode":             decode,
+	"llama_perf_context_reset": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {},
+	"llama_memory_clear": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		if mem := get[memory](l, handleArg(args, 0)); mem != nil {
+			mem.ctx.cells = nil
+		}
+	},
+	"llama_get_memory": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		var h uintptr
+		if ctx := get[context](l, handleArg(args, 0)); ctx != nil {
+			h = ctx.memory
+		}
+		setHandle(ret, h)
+	},
+	"llama_memory_seq_rm": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		mem := get[memory](l, handleArg(args, 0))
+		if mem == nil {
+			setBool(ret, false)
+			return
+		}
+		mem.ctx.seqRm(int32Arg(args, 1), int32Arg(args, 2), int32Arg(args, 3))
+		setBool(ret, true)
+	},
+	"llama_memory_seq_cp": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		if mem := get[memory](l, handleArg(args, 0)); mem != nil {
+			mem.ctx.seqCp(int32Arg(args, 1), int32Arg(args, 2), int32Arg(args, 3), int32Arg(args, 4))
+		}
+	},
+	"llama_memory_seq_keep": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		if mem := get[memory](l, handleArg(args, 0)); mem != nil {
+			mem.ctx.seqKeep(int32Arg(args, 1))
+		}
+	},
+	"llama_memory_seq_add": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		if mem := get[memory](l, handleArg(args, 0)); mem != nil {
+			delta := int32Arg(args, 4)
+			mem.ctx.seqShift(int32Arg(args, 1), int32Arg(args, 2), int32Arg(args, 3), func(pos int32) int32 { return pos + delta })
+		}
+	},
+	"llama_memory_seq_div": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		if mem := get[memory](l, handleArg(args, 0)); mem != nil {
+			if d := int32Arg(args, 4); d > 1 {
+				mem.ctx.seqShift(int32Arg(args, 1), int32Arg(args, 2), int32Arg(args, 3), func(pos int32) int32 { return pos / d })
+			}
+		}
+	},
+	"llama_memory_seq_pos_min": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		pos := int32(-1)
+		if mem := get[memory](l, handleArg(args, 0)); mem != nil {
+			pos = mem.ctx.posMin(int32Arg(args, 1))
+		}
+		setInt(ret, int64(pos))
+	},
+	"llama_memory_seq_pos_max": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		pos := int32(-1)
+		if mem := get[memory](l, handleArg(args, 0)); mem != nil {
+			pos = mem.ctx.posMax(int32Arg(args, 1))
+		}
+		setInt(ret, int64(pos))
+	},
+	"llama_memory_can_shift": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setBool(ret, get[memory](l, handleArg(args, 0)) != nil)
+	},
+	"llama_synchronize": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {},
+	"llama_get_model": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		var h uintptr
+		if ctx := get[context](l, handleArg(args, 0)); ctx != nil {
+			h = ctx.handle
+		}
+		setHandle(ret, h)
+	},
+	"llama_pooling_type": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		var t int32
+		if ctx := get[context](l, handleArg(args, 0)); ctx != nil {
+			t = ctx.poolingType()
+		}
+		setInt(ret, int64(t))
+	},
+	"llama_model_n_cls_out": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setInt(ret, 1)
+	},
+	"llama_get_logits": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		ctx := get[context](l, handleArg(args, 0))
+		if ctx == nil {
+			setPointer(ret, nil)
+			return
+		}
+		ctx.logitsBuf = contiguous(ctx.outputs)
+		setPointer(ret, floatsPtr(ctx.logitsBuf))
+	},
+	"llama_get_logits_ith": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		var out []float32
+		if ctx := get[context](l, handleArg(args, 0)); ctx != nil {
+			out = ctx.output(int32Arg(args, 1))
+		}
+		setPointer(ret, floatsPtr(out))
+	},
+	"llama_get_embeddings": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		ctx := get[context](l, handleArg(args, 0))
+		if ctx == nil {
+			setPointer(ret, nil)
+			return
+		}
+		ctx.embdBuf = contiguous(ctx.embd)
+		setPointer(ret, floatsPtr(ctx.embdBuf))
+	},
+	"llama_get_embeddings_ith": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		var out []float32
+		if ctx := get[context](l, handleArg(args, 0)); ctx != nil {
+			i := int32Arg(args, 1)
+			if i < 0 {
+				i = ctx.last
+			}
+			out = ctx.embd[i]
+		}
+		setPointer(ret, floatsPtr(out))
+	},
+	"llama_get_embeddings_seq": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		var out []float32
+		if ctx := get[context](l, handleArg(args, 0)); ctx != nil {
+			out = ctx.embdSeq[int32Arg(args, 1)]
+		}
+		setPointer(ret, floatsPtr(out))
+	},
+	"llama_n_ctx": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		var n uint32
+		if ctx := get[context](l, handleArg(args, 0)); ctx != nil {
+			n = ctx.params.NCtx
+		}
+		setInt(ret, int64(n))
+	},
+	"llama_n_batch": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		var n uint32
+		if ctx := get[context](l, handleArg(args, 0)); ctx != nil {
+			n = ctx.params.NBatch
+		}
+		setInt(ret, int64(n))
+	},
+}
+
+func modelLoadFromFile(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	setHandle(ret, l.loadModel([]string{stringArg(args, 0)}, (*modelParamsType)(args[1])))
+}
+
+func modelLoadFromSplits(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	n := uint64Arg(args, 1)
+	if n == 0 {
+		setHandle(ret, 0)
+		return
+	}
+
+	paths := make([]string, n)
+	for i, p := range unsafe.Slice((**byte)(pointerArg(args, 0)), n) {
+		paths[i] = utils.BytePtrToString(p)
+	}
+	setHandle(ret, l.loadModel(paths, (*modelParamsType)(args[2])))
+}
+
+// loadModel loads the toy model from the files at paths, and returns its handle or 0 if it
+// fails to load. The files are not read, so they do not need to exist.
+func (l *Lib) loadModel(paths []string, params *modelParamsType) uintptr {
+	if slices.Contains(paths, "") {
+		return 0
+	}
+
+	if params.ProgressCallback != 0 {
+		for _, progress := range []float32{0, 0.5, 1} {
+			if !l.callProgress(params.ProgressCallback, progress, params.ProgressCallbackUserData) {
+				return 0
+			}
+		}
+	}
+
+	m := &model{path: paths[0], metadata: slices.Clone(metadata), nCtxTrain: NCtxTrain, vocabOnly: params.VocabOnly != 0}
+	if params.KvOverrides != nil && !m.applyOverrides(params.KvOverrides) {
+		return 0
+	}
+
+	m.vocab = l.add(&vocab{model: m})
+	return l.add(m)
+}
+
+// modelSaveToFile copies the file that the model was loaded from, since the toy model has no weights.
+func modelSaveToFile(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	m := get[model](l, handleArg(args, 0))
+	if m == nil {
+		return
+	}
+
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		return
+	}
+	os.WriteFile(stringArg(args, 1), data, 0o644)
+}
+
+// splitSuffix returns the suffix of the path of a split, as formatted by llama_split_path.
+func splitSuffix(splitNo, splitCount int32) string {
+	return fmt.Sprintf("-%05d-of-%05d.gguf", splitNo+1, splitCount)
+}
+
+var (
+	progressCifOnce  sync.Once
+	progressCif      ffi.Cif
+	progressArgTypes = []*ffi.Type{&ffi.TypeFloat, &ffi.TypePointer}
+)
+
+// callProgress calls a llama_progress_callback, and returns false if the load should be aborted.
+// The lock is released while it is called, so that the callback can call into the library.
+func (l *Lib) callProgress(fn uintptr, progress float32, userData uintptr) bool {
+	progressCifOnce.Do(func() {
+		if status := ffi.PrepCif(&progressCif, ffi.DefaultAbi, uint32(len(progressArgTypes)), &ffi.TypeUint8, progressArgTypes...); status != ffi.OK {
+			panic(status)
+		}
+	})
+
+	l.mu.Unlock()
+	defer l.mu.Lock()
+
+	var result ffi.Arg
+	ffi.Call(&progressCif, fn, unsafe.Pointer(&result), unsafe.Pointer(&progress), unsafe.Pointer(&userData))
+
+	return result.Bool()
+}
+
+func initFromModel(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	// a model loaded with vocab_only has no weights to run
+	m := get[model](l, handleArg(args, 0))
+	if m == nil || m.vocabOnly {
+		setHandle(ret, 0)
+		return
+	}
+
+	params := *(*contextParamsType)(args[1])
+	if params.NCtx == 0 {
+		params.NCtx = uint32(m.nCtxTrain)
+	}
+
+	ctx := &context{model: m, handle: handleArg(args, 0), params: params, last: -1}
+	ctx.memory = l.add(&memory{ctx: ctx})
+	setHandle(ret, l.add(ctx))
+}
+
+// modelQuantize copies the input file to the output file, since the toy model has no weights.
+func modelQuantize(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	params := (*modelQuantizeParamsType)(pointerArg(args, 2))
+	if params.Ftype <= 0 || params.Ftype >= 1024 {
+		setInt(ret, 1)
+		return
+	}
+
+	if !validIMatrix(params.IMatrix) || !validTensorTypes(params.TensorTypes) {
+		setInt(ret, 1)
+		return
+	}
+
+	data, err := os.ReadFile(stringArg(args, 0))
+	if err != nil {
+		setInt(ret, 1)
+		return
+	}
+
+	if err := os.WriteFile(stringArg(args, 1), data, 0o644); err != nil {
+		setInt(ret, 1)
+		return
+	}
+	setInt(ret, 0)
+}
+
+// validIMatrix reports whether every value in the importance matrix is finite, like llama.cpp
+// checks, and that every entry can be found in its bucket.
+func validIMatrix(t *cxxHashtable) bool {
+	if t == nil {
+		return true
+	}
+
+	buckets := unsafe.Slice(t.buckets, t.bucketCount)
+	n := uintptr(0)
+	for node := t.beforeBegin; node != nil; node = node.next {
+		n++
+		if !bucketHas(buckets[node.hash%t.bucketCount], t.bucketCount, node) {
+			return false
+		}
+
+		for _, v := range unsafe.Slice((*float32)(node.value.begin), vectorLen[float32](node.value)) {
+			if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+				return false
+			}
+		}
+	}
+
+	return n == t.elementCount
+}
+
+// bucketHas reports whether node is in the bucket that starts after before, which is either a node
+// or the beforeBegin field of the table, so only its next pointer is read.
+func bucketHas(before unsafe.Pointer, bucketCount uintptr, node *cxxHashNode) bool {
+	if before == nil {
+		return false
+	}
+
+	bucket := node.hash % bucketCount
+	for p := *(**cxxHashNode)(before); p != nil && p.hash%bucketCount == bucket; p = p.next {
+		if p == node {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validTensorTypes reports whether every tensor type has a pattern and a valid ggml_type.
+func validTensorTypes(v *cxxVector) bool {
+	if v == nil {
+		return true
+	}
+
+	for _, tt := range unsafe.Slice((*tensorQuantizationType)(v.begin), vectorLen[tensorQuantizationType](*v)) {
+		if tt.name.size == 0 || tt.quant < 0 || tt.quant >= ggmlTypeCount {
+			return false
+		}
+	}
+
+	return true
+}
+
+// vectorLen returns the number of elements of type T in a std::vector.
+func vectorLen[T any](v cxxVector) int {
+	var zero T
+	return int((uintptr(v.end) - uintptr(v.begin)) / unsafe.Sizeof(zero))
+}
+
+func batchInit(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	nTokens, embd, nSeqMax := max(int32Arg(args, 0), 1), int32Arg(args, 1), max(int32Arg(args, 2), 1)
+
+	mem := &batchMemory{
+		pos:    make([]int32, nTokens),
+		nSeqId: make([]int32, nTokens),
+		seqId:  make([]*int32, nTokens+1),
+		seqs:   make([][]int32, nTokens),
+		logits: make([]int8, nTokens),
+	}
+	for i := range mem.seqs {
+		mem.seqs[i] = make([]int32, nSeqMax)
+		mem.seqId[i] = &mem.seqs[i][0]
+	}
+
+	batch := batchType{
+		Pos:    &mem.pos[0],
+		NSeqId: &mem.nSeqId[0],
+		SeqId:  &mem.seqId[0],
+		Logits: &mem.logits[0],
+	}
+	if embd != 0 {
+		mem.embd = make([]float32, nTokens*embd)
+		batch.Embd = &mem.embd[0]
+		l.keep[unsafe.Pointer(batch.Embd)] = mem
+	} else {
+		mem.token = make([]int32, nTokens)
+		batch.Token = &mem.token[0]
+		l.keep[unsafe.Pointer(batch.Token)] = mem
+	}
+
+	*(*batchType)(ret) = batch
+}
+
+func batchFree(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	batch := (*batchType)(args[0])
+	delete(l.keep, unsafe.Pointer(batch.Token))
+	delete(l.keep, unsafe.Pointer(batch.Embd))
+}
+
+func batchGetOne(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	*(*batchType)(ret) = batchType{
+		NTokens: int32Arg(args, 1),
+		Token:   (*int32)(pointerArg(args, 0)),
+	}
+}
+
+// vocabTokenNull is the handler for special tokens that the toy vocabulary does not have.
+func vocabTokenNull(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	setInt(ret, -1)
+}
+
+func tokenToPiece(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	token := int32Arg(args, 1)
+	buf := pointerArg(args, 2)
+	length := int32Arg(args, 3)
+	lstrip := int32Arg(args, 4)
+	special := boolArg(args, 5)
+
+	p := piece(token, special)
+	for ; lstrip > 0 && len(p) > 0 && p[0] == ' '; lstrip-- {
+		p = p[1:]
+	}
+
+	if int32(len(p)) > length {
+		setInt(ret, -int64(len(p)))
+		return
+	}
+
+	if len(p) > 0 {
+		copy(unsafe.Slice((*byte)(buf), length), p)
+	}
+	setInt(ret, int64(len(p)))
+}
+
+func detokenize(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	var tokens []int32
+	if n := int32Arg(args, 2); n > 0 {
+		tokens = unsafe.Slice((*int32)(pointerArg(args, 1)), n)
+	}
+
+	// remove_special removes the BOS token that the toy vocabulary adds
+	if boolArg(args, 5) && len(tokens) > 0 && tokens[0] == TokenBOS {
+		tokens = tokens[1:]
+	}
+
+	var text []byte
+	for _, token := range tokens {
+		text = append(text, piece(token, boolArg(args, 6))...)
+	}
+
+	length := int32Arg(args, 4)
+	if int32(len(text)) > length {
+		setInt(ret, -int64(len(text)))
+		return
+	}
+
+	if len(text) > 0 {
+		copy(unsafe.Slice((*byte)(pointerArg(args, 3)), length), text)
+	}
+	setInt(ret, int64(len(text)))
+}
+
+func tokenizeText(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	var text string
+	if n := int32Arg(args, 2); n > 0 {
+		text = string(unsafe.Slice((*byte)(pointerArg(args, 1)), n))
+	}
+
+	tokens := tokenize(text, boolArg(args, 5), boolArg(args, 6))
+
+	nMax := int32Arg(args, 4)
+	if int32(len(tokens)) > nMax {
+		setInt(ret, -int64(len(tokens)))
+		return
+	}
+
+	if len(tokens) > 0 {
+		copy(unsafe.Slice((*int32)(pointerArg(args, 3)), nMax), tokens)
+	}
+	setInt(ret, int64(len(tokens)))
+}
+
+func samplerInit(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	setHandle(ret, l.add(&sampler{}))
+}
+
+func samplerFree(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	h := handleArg(args, 0)
+	if s := get[sampler](l, h); s != nil {
+		for _, c := range s.chain {
+			l.remove(c)
+		}
+	}
+	l.remove(h)
+}
+
+// samplerSample always picks the token with the highest logit.
+func samplerSample(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	ctx := get[context](l, handleArg(args, 1))
+	if ctx == nil {
+		setInt(ret, -1)
+		return
+	}
+
+	out := ctx.output(int32Arg(args, 2))
+	if out == nil {
+		setInt(ret, -1)
+		return
+	}
+
+	best := 0
+	for i := range out {
+		if out[i] > out[best] {
+			best = i
+		}
+	}
+	setInt(ret, int64(best))
+}
+
+func chatApplyTemplate(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	tmpl := stringArg(args, 0)
+	if tmpl != "chatml" && tmpl != chatTemplate {
+		setInt(ret, -1)
+		return
+	}
+
+	n := uint32Arg(args, 2)
+	msgs := unsafe.Slice((*chatMessageType)(pointerArg(args, 1)), n)
+
+	roles := make([]string, n)
+	contents := make([]string, n)
+	for i, msg := range msgs {
+		roles[i] = utils.BytePtrToString(msg.Role)
+		contents[i] = utils.BytePtrToString(msg.Content)
+	}
+
+	out := applyChatML(roles, contents, boolArg(args, 3))
+	if length := int32Arg(args, 5); length > 0 {
+		copy(unsafe.Slice((*byte)(pointerArg(args, 4)), length), out)
+	}
+	setInt(ret, int64(len(out)))
+}
+
+func decode(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	ctx := get[context](l, handleArg(args, 0))
+	if ctx == nil {
+		setInt(ret, -1)
+		return
+	}
+
+	setInt(ret, int64(ctx.decodeBatch((*batchType)(args[1]))))
+}
+
+// decodeBatch adds the tokens in a batch to the KV cache and computes the requested outputs.
+// It returns 0 on success, 1 if there is no space left in the KV cache and -1 for an invalid batch.
+func (c *context) decodeBatch(batch *batchType) int32 {
+	n := batch.NTokens
+	if n <= 0 {
+		return -1
+	}
+
+	tokens := make([]int32, n)
+	if batch.Token != nil {
+		copy(tokens, unsafe.Slice(batch.Token, n))
+	}
+
+	// llama.cpp rejects a batch with a token that is not in the vocabulary
+	for _, token := range tokens {
+		if token < 0 || token >= NVocab {
+			return -1
+		}
+	}
+
+	pos := make([]int32, n)
+	if batch.Pos != nil {
+		copy(pos, unsafe.Slice(batch.Pos, n))
+	} else {
+		next := c.posMax(0) + 1
+		for i := range pos {
+			pos[i] = next + int32(i)
+		}
+	}
+
+	seqs := make([][]int32, n)
+	for i := range seqs {
+		seqs[i] = []int32{0}
+	}
+	if batch.NSeqId != nil && batch.SeqId != nil {
+		nSeqId := unsafe.Slice(batch.NSeqId, n)
+		seqId := unsafe.Slice(batch.SeqId, n)
+		for i := range seqs {
+			seqs[i] = unsafe.Slice(seqId[i], nSeqId[i])
+		}
+	}
+
+	outputs := make([]bool, n)
+	if batch.Logits != nil {
+		for i, v := range unsafe.Slice(batch.Logits, n) {
+			outputs[i] = v != 0
+		}
+	} else {
+		outputs[n-1] = true
+	}
+
+	return c.decode(tokens, pos, seqs, outputs)
+}
+
+func (c *context) decode(tokens, pos []int32, seqs [][]int32, outputs []bool) int32 {
+	if uint32(len(c.cells)+len(tokens)) > c.params.NCtx {
+		return 1
+	}
+
+	c.outputs = make(map[int32][]float32)
+	c.last = -1
+	for i, token := range tokens {
+		ce := &cell{pos: pos[i], token: token, seqs: make(map[int32]bool)}
+		for _, s := range seqs[i] {
+			ce.seqs[s] = true
+		}
+		c.cells = append(c.cells, ce)
+
+		if outputs[i] {
+			c.outputs[int32(i)] = logits(token)
+			c.last = int32(i)
+		}
+	}
+	c.embed(tokens, seqs, outputs)
+
+	return 0
+}
+
+// embed computes the embeddings of a batch when the context computes embeddings. They are pooled
+// for each sequence, unless the pooling type is none.
+func (c *context) embed(tokens []int32, seqs [][]int32, outputs []bool) {
+	c.embd, c.embdSeq = nil, nil
+	if c.params.Embeddings == 0 {
+		return
+	}
+
+	if c.poolingType() == poolingNone {
+		c.embd = make(map[int32][]float32)
+		for i, token := range tokens {
+			if outputs[i] {
+				c.embd[int32(i)] = embedding(token)
+			}
+		}
+		return
+	}
+
+	bySeq := make(map[int32][]int32)
+	for i, token := range tokens {
+		for _, s := range seqs[i] {
+			bySeq[s] = append(bySeq[s], token)
+		}
+	}
+
+	c.embdSeq = make(map[int32][]float32)
+	for s, toks := range bySeq {
+		var out []float32
+		switch c.poolingType() {
+		case poolingCLS:
+			out = embedding(toks[0])
+		case poolingLast:
+			out = embedding(toks[len(toks)-1])
+		default:
+			out = make([]float32, NEmbd)
+			for _, token := range toks {
+				for j, v := range embedding(token) {
+					out[j] += v / float32(len(toks))
+				}
+			}
+			if c.poolingType() == poolingRank {
+				// the score of the sequence is the share of its most common embedding
+				out = []float32{slices.Max(out)}
+			}
+		}
+		c.embdSeq[s] = out
+	}
+}
+
+// The llama_pooling_type values that the toy model supports.
+const (
+	poolingNone = 0
+	poolingMean = 1
+	poolingCLS  = 2
+	poolingLast = 3
+	poolingRank = 4
+)
+
+// poolingType returns the pooling type of the context. The toy model does not pool by default.
+func (c *context) poolingType() int32 {
+	return max(c.params.PoolingType, poolingNone)
+}
+
+// contiguous returns the values of outputs in the order of their index, one after another.
+func contiguous(outputs map[int32][]float32) []float32 {
+	var buf []float32
+	for _, i := range slices.Sorted(maps.Keys(outputs)) {
+		buf = append(buf, outputs[i]...)
+	}
+
+	return buf
+}
+
+// floatsPtr returns a pointer to the first value of s, or nil if it is empty.
+func floatsPtr(s []float32) unsafe.Pointer {
+	if len(s) == 0 {
+		return nil
+	}
+
+	return unsafe.Pointer(&s[0])
+}
+
+// output returns the logits for the i-th token of the last batch. -1 is the last output.
+func (c *context) output(i int32) []float32 {
+	if i < 0 {
+		i = c.last
+	}
+
+	return c.outputs[i]
+}
+
+// posMax returns the largest position in a sequence, or -1 if the sequence is empty.
+func (c *context) posMax(seq int32) int32 {
+	result := int32(-1)
+	for _, ce := range c.cells {
+		if ce.seqs[seq] && ce.pos > result {
+			result = ce.pos
+		}
+	}
+
+	return result
+}
+
+// posMin returns the smallest position in a sequence, or -1 if the sequence is empty.
+func (c *context) posMin(seq int32) int32 {
+	result := int32(-1)
+	for _, ce := range c.cells {
+		if ce.seqs[seq] && (result < 0 || ce.pos < result) {
+			result = ce.pos
+		}
+	}
+
+	return result
+}
+
+// seqRange returns the range [p0, p1) with negative bounds replaced, like llama.cpp.
+func seqRange(p0, p1 int32) (int32, int32) {
+	if p0 < 0 {
+		p0 = 0
+	}
+	if p1 < 0 {
+		p1 = math.MaxInt32
+	}
+
+	return p0, p1
+}
+
+// seqCp adds the positions in [p0, p1) of sequence src to sequence dst.
+func (c *context) seqCp(src, dst, p0, p1 int32) {
+	p0, p1 = seqRange(p0, p1)
+	for _, ce := range c.cells {
+		if ce.seqs[src] && ce.pos >= p0 && ce.pos < p1 {
+			ce.seqs[dst] = true
+		}
+	}
+}
+
+// seqKeep removes every sequence except seq.
+func (c *context) seqKeep(seq int32) {
+	cells := c.cells[:0]
+	for _, ce := range c.cells {
+		if ce.seqs[seq] {
+			ce.seqs = map[int32]bool{seq: true}
+			cells = append(cells, ce)
+		}
+	}
+	c.cells = cells
+}
+
+// seqShift changes the positions in [p0, p1) of a sequence with fn. Cells whose position becomes
+// negative are removed.
+func (c *context) seqShift(seq, p0, p1 int32, fn func(int32) int32) {
+	p0, p1 = seqRange(p0, p1)
+
+	cells := c.cells[:0]
+	for _, ce := range c.cells {
+		if ce.seqs[seq] && ce.pos >= p0 && ce.pos < p1 {
+			ce.pos = fn(ce.pos)
+			if ce.pos < 0 {
+				continue
+			}
+		}
+		cells = append(cells, ce)
+	}
+	c.cells = cells
+}
+
+// seqRm removes the positions in [p0, p1) from a sequence, or from all sequences if seq < 0.
+func (c *context) seqRm(seq, p0, p1 int32) {
+	p0, p1 = seqRange(p0, p1)
+
+	cells := c.cells[:0]
+	for _, ce := range c.cells {
+		if ce.pos >= p0 && ce.pos < p1 {
+			if seq < 0 {
+				continue
+			}
+			delete(ce.seqs, seq)
+			if len(ce.seqs) == 0 {
+				continue
+			}
+		}
+		cells = append(cells, ce)
+	}
+	c.cells = cells
+}
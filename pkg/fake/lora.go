@@ -0,0 +1,128 @@
+package fake
+
+import (
+	"slices"
+	"unsafe"
+)
+
+// loraMetadata is the GGUF metadata of every LoRA adapter of the toy model.
+var loraMetadata = [][2]string{
+	{"general.architecture", "fake"},
+	{"general.type", "adapter"},
+	{"adapter.type", "lora"},
+	{"adapter.lora.alpha", "16.000000"},
+}
+
+// controlVector is the control vector applied to a context.
+type controlVector struct {
+	data           []float32
+	ilStart, ilEnd int32
+}
+
+type adapter struct {
+	model *model
+	path  string
+}
+
+var loraHandlers = map[string]handler{
+	"llama_adapter_lora_init": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		m := get[model](l, handleArg(args, 0))
+		path := stringArg(args, 1)
+		if m == nil || path == "" {
+			setHandle(ret, 0)
+			return
+		}
+		setHandle(ret, l.add(&adapter{model: m, path: path}))
+	},
+	"llama_adapter_lora_free": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		l.remove(handleArg(args, 0))
+	},
+	"llama_set_adapter_lora": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		ctx := get[context](l, handleArg(args, 0))
+		h := handleArg(args, 1)
+		a := get[adapter](l, h)
+		if ctx == nil || a == nil || a.model != ctx.model {
+			setInt(ret, -1)
+			return
+		}
+		if ctx.adapters == nil {
+			ctx.adapters = make(map[uintptr]float32)
+		}
+		ctx.adapters[h] = *(*float32)(args[2])
+		setInt(ret, 0)
+	},
+	"llama_rm_adapter_lora": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		ctx := get[context](l, handleArg(args, 0))
+		h := handleArg(args, 1)
+		if ctx == nil {
+			setInt(ret, -1)
+			return
+		}
+		if _, ok := ctx.adapters[h]; !ok {
+			setInt(ret, -1)
+			return
+		}
+		delete(ctx.adapters, h)
+		setInt(ret, 0)
+	},
+	"llama_clear_adapter_lora": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		if ctx := get[context](l, handleArg(args, 0)); ctx != nil {
+			clear(ctx.adapters)
+		}
+	},
+	"llama_adapter_meta_count": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		if get[adapter](l, handleArg(args, 0)) == nil {
+			setInt(ret, -1)
+			return
+		}
+		setInt(ret, int64(len(loraMetadata)))
+	},
+	"llama_adapter_meta_key_by_index": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		i := int32Arg(args, 1)
+		if get[adapter](l, handleArg(args, 0)) == nil || i < 0 || int(i) >= len(loraMetadata) {
+			setInt(ret, -1)
+			return
+		}
+		setInt(ret, snprintf(args, 2, 3, loraMetadata[i][0]))
+	},
+	"llama_adapter_meta_val_str": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		if get[adapter](l, handleArg(args, 0)) == nil {
+			setInt(ret, -1)
+			return
+		}
+		key := stringArg(args, 1)
+		for _, kv := range loraMetadata {
+			if kv[0] == key {
+				setInt(ret, snprintf(args, 2, 3, kv[1]))
+				return
+			}
+		}
+		setInt(ret, -1)
+	},
+	"llama_apply_adapter_cvec": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		ctx := get[context](l, handleArg(args, 0))
+		if ctx == nil {
+			setInt(ret, 1)
+			return
+		}
+
+		data := (*float32)(pointerArg(args, 1))
+		if data == nil {
+			ctx.cvec = nil
+			setInt(ret, 0)
+			return
+		}
+
+		if int32Arg(args, 3) != NEmbd {
+			setInt(ret, 1)
+			return
+		}
+
+		ctx.cvec = &controlVector{
+			data:    slices.Clone(unsafe.Slice(data, uint64Arg(args, 2))),
+			ilStart: int32Arg(args, 4),
+			ilEnd:   int32Arg(args, 5),
+		}
+		setInt(ret, 0)
+	},
+}
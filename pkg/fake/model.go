@@ -0,0 +1,211 @@
+package fake
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// The toy vocabulary has three special tokens followed by one token for every byte value,
+// so any text can be tokenized and multibyte characters are split across tokens.
+const (
+	TokenUnknown int32 = 0
+	TokenBOS     int32 = 1
+	TokenEOS     int32 = 2
+	TokenByte0   int32 = 3
+
+	NVocab    = 3 + 256
+	NCtxTrain = 2048
+	NEmbd     = 16
+	NLayer    = 4
+	NHead     = 4
+	NParams   = NVocab*NEmbd*2 + NLayer*NEmbd*NEmbd*12
+
+	// Ftype is the llama_ftype of the toy model, which has F16 weights.
+	Ftype = 1
+
+	// VocabType is the llama_vocab_type of the toy vocabulary, which is BPE.
+	VocabType = 2
+)
+
+// Description is the description of the toy model returned by llama_model_desc.
+const Description = "fake 21K F16"
+
+// Version is the ggml version that the fake library reports.
+const Version = "0.0.0"
+
+var specials = []string{"<unk>", "<s>", "</s>"}
+
+// chatTemplate is the chat template that the toy model reports in its metadata.
+const chatTemplate = "{% for message in messages %}{{'<|im_start|>' + message['role'] + '\\n' + message['content'] + '<|im_end|>' + '\\n'}}{% endfor %}{% if add_generation_prompt %}{{ '<|im_start|>assistant\\n' }}{% endif %}"
+
+// metadata is the GGUF metadata of the toy model, in the order that it is in the file.
+var metadata = [][2]string{
+	{"general.architecture", "fake"},
+	{"general.name", "Fake Toy Model"},
+	{"general.file_type", "1"},
+	{"fake.context_length", "2048"},
+	{"fake.embedding_length", "16"},
+	{"fake.block_count", "4"},
+	{"fake.attention.head_count", "4"},
+	{"tokenizer.ggml.model", "fake"},
+	{"tokenizer.ggml.bos_token_id", "1"},
+	{"tokenizer.ggml.eos_token_id", "2"},
+	{"tokenizer.chat_template", chatTemplate},
+}
+
+type model struct {
+	path      string
+	vocab     uintptr
+	metadata  [][2]string
+	nCtxTrain int64
+	vocabOnly bool
+}
+
+// metaValue returns the value of a metadata key of the model.
+func (m *model) metaValue(key string) (string, bool) {
+	for _, kv := range m.metadata {
+		if kv[0] == key {
+			return kv[1], true
+		}
+	}
+
+	return "", false
+}
+
+type vocab struct {
+	model *model
+}
+
+// tokenize converts text into tokens of the toy vocabulary.
+func tokenize(text string, addSpecial, parseSpecial bool) []int32 {
+	tokens := make([]int32, 0, len(text)+1)
+	if addSpecial {
+		tokens = append(tokens, TokenBOS)
+	}
+
+next:
+	for i := 0; i < len(text); {
+		if parseSpecial {
+			for id, s := range specials {
+				if strings.HasPrefix(text[i:], s) {
+					tokens = append(tokens, int32(id))
+					i += len(s)
+					continue next
+				}
+			}
+		}
+
+		tokens = append(tokens, TokenByte0+int32(text[i]))
+		i++
+	}
+
+	return tokens
+}
+
+// piece returns the text for a token, or nil if the token is special and special is false.
+func piece(token int32, special bool) []byte {
+	switch {
+	case token < 0 || token >= NVocab:
+		return nil
+	case token < TokenByte0:
+		if !special {
+			return nil
+		}
+		return []byte(specials[token])
+	default:
+		return []byte{byte(token - TokenByte0)}
+	}
+}
+
+// tokenText returns the text of a token as it is stored in the vocabulary. Bytes that are not
+// printable ASCII are stored as <0xXX>, like in SentencePiece vocabularies.
+func tokenText(token int32) string {
+	switch {
+	case token < 0 || token >= NVocab:
+		return ""
+	case token < TokenByte0:
+		return specials[token]
+	}
+
+	if b := byte(token - TokenByte0); b <= ' ' || b >= 0x7f {
+		return fmt.Sprintf("<0x%02X>", b)
+	}
+
+	return string(rune(token - TokenByte0))
+}
+
+// tokenScore returns the score of a token, which is higher for lower ids.
+func tokenScore(token int32) float32 {
+	return -float32(token)
+}
+
+// tokenAttr returns the llama_token_attr of a token.
+func tokenAttr(token int32) int32 {
+	const (
+		attrUnknown = 1 << 0
+		attrNormal  = 1 << 2
+		attrControl = 1 << 3
+		attrByte    = 1 << 5
+	)
+
+	switch {
+	case token < 0 || token >= NVocab:
+		return 0
+	case token == TokenUnknown:
+		return attrUnknown
+	case token < TokenByte0:
+		return attrControl
+	case strings.HasPrefix(tokenText(token), "<0x"):
+		return attrByte
+	default:
+		return attrNormal
+	}
+}
+
+// predict returns the token that the toy model expects after token.
+// Lowercase letters are continued in alphabetical order up to 'z', which is followed by '.'
+// and then the end of the sequence. Any other token starts again at 'a'.
+func predict(token int32) int32 {
+	b := token - TokenByte0
+	switch {
+	case b >= 'a' && b < 'z':
+		return token + 1
+	case b == 'z':
+		return TokenByte0 + '.'
+	case b == '.':
+		return TokenEOS
+	default:
+		return TokenByte0 + 'a'
+	}
+}
+
+// logits returns the output of the toy model after token.
+func logits(token int32) []float32 {
+	out := make([]float32, NVocab)
+	out[predict(token)] = 10
+
+	return out
+}
+
+// embedding returns the embedding of token in the toy model, which is a unit vector.
+func embedding(token int32) []float32 {
+	out := make([]float32, NEmbd)
+	out[token%NEmbd] = 1
+
+	return out
+}
+
+// applyChatML formats messages using the chatml template.
+func applyChatML(roles, contents []string, addAssistant bool) []byte {
+	var buf bytes.Buffer
+	for i := range roles {
+		buf.WriteString("<|im_start|>" + roles[i] + "\n" + contents[i] + "<|im_end|>\n")
+	}
+
+	if addAssistant {
+		buf.WriteString("<|im_start|>assistant\n")
+	}
+
+	return buf.Bytes()
+}
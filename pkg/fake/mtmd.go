@@ -0,0 +1,231 @@
+package fake
+
+import (
+	"bytes"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"strings"
+	"unsafe"
+
+	"github.com/hybridgroup/yzma/pkg/utils"
+)
+
+// NImageTokens is the number of tokens that the toy projector uses for every image.
+const NImageTokens = 16
+
+const defaultMarker = "<__media__>"
+
+const (
+	chunkTypeText int32 = iota
+	chunkTypeImage
+)
+
+type mtmdContextParamsType struct {
+	UseGPU       uint8
+	PrintTimings uint8
+	Threads      int32
+	Verbosity    int32
+	ImageMarker  *byte
+	MediaMarker  *byte
+}
+
+type inputTextType struct {
+	Text         *byte
+	AddSpecial   uint8
+	ParseSpecial uint8
+}
+
+type mtmdContext struct {
+	model  *model
+	marker string
+}
+
+type bitmap struct {
+	nx, ny uint32
+	data   []byte
+}
+
+type chunk struct {
+	typ    int32
+	tokens []int32
+}
+
+type chunks struct {
+	items []chunk
+}
+
+var mtmdHandlers = map[string]handler{
+	"mtmd_default_marker": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setPointer(ret, l.cString(defaultMarker))
+	},
+	"mtmd_context_params_default": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		marker := (*byte)(l.cString(defaultMarker))
+		*(*mtmdContextParamsType)(ret) = mtmdContextParamsType{
+			UseGPU:       1,
+			PrintTimings: 1,
+			Threads:      4,
+			Verbosity:    2,
+			ImageMarker:  marker,
+			MediaMarker:  marker,
+		}
+	},
+	"mtmd_init_from_file": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		m := get[model](l, handleArg(args, 1))
+		if stringArg(args, 0) == "" || m == nil {
+			setHandle(ret, 0)
+			return
+		}
+
+		params := (*mtmdContextParamsType)(args[2])
+		marker := defaultMarker
+		if params.MediaMarker != nil {
+			marker = utils.BytePtrToString(params.MediaMarker)
+		}
+		setHandle(ret, l.add(&mtmdContext{model: m, marker: marker}))
+	},
+	"mtmd_free": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		l.remove(handleArg(args, 0))
+	},
+	"mtmd_support_vision": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setBool(ret, get[mtmdContext](l, handleArg(args, 0)) != nil)
+	},
+	"mtmd_support_audio": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setBool(ret, false)
+	},
+	"mtmd_input_chunks_init": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		setHandle(ret, l.add(&chunks{}))
+	},
+	"mtmd_input_chunks_free": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		l.remove(handleArg(args, 0))
+	},
+	"mtmd_input_chunks_size": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		var n int
+		if c := get[chunks](l, handleArg(args, 0)); c != nil {
+			n = len(c.items)
+		}
+		setInt(ret, int64(n))
+	},
+	"mtmd_tokenize":           mtmdTokenize,
+	"mtmd_helper_eval_chunks": helperEvalChunks,
+
+	"mtmd_bitmap_init": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		nx, ny := uint32Arg(args, 0), uint32Arg(args, 1)
+		data := make([]byte, nx*ny*3)
+		if p := pointerArg(args, 2); p != nil {
+			copy(data, unsafe.Slice((*byte)(p), len(data)))
+		}
+		setHandle(ret, l.add(&bitmap{nx: nx, ny: ny, data: data}))
+	},
+	"mtmd_bitmap_free": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		l.remove(handleArg(args, 0))
+	},
+	"mtmd_bitmap_get_n_bytes": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		var n int
+		if b := get[bitmap](l, handleArg(args, 0)); b != nil {
+			n = len(b.data)
+		}
+		setInt(ret, int64(n))
+	},
+	"mtmd_helper_bitmap_init_from_file": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		data, err := os.ReadFile(stringArg(args, 1))
+		if err != nil {
+			setHandle(ret, 0)
+			return
+		}
+		setHandle(ret, l.bitmapFromImage(data))
+	},
+	"mtmd_helper_bitmap_init_from_buf": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		n := uint32Arg(args, 2)
+		data := bytes.Clone(unsafe.Slice((*byte)(pointerArg(args, 1)), n))
+		setHandle(ret, l.bitmapFromImage(data))
+	},
+}
+
+// bitmapFromImage returns a handle to an RGB bitmap with the size of an encoded image, or 0 if it cannot be decoded.
+func (l *Lib) bitmapFromImage(data []byte) uintptr {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0
+	}
+
+	nx, ny := uint32(cfg.Width), uint32(cfg.Height)
+	return l.add(&bitmap{nx: nx, ny: ny, data: make([]byte, nx*ny*3)})
+}
+
+func mtmdTokenize(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	ctx := get[mtmdContext](l, handleArg(args, 0))
+	out := get[chunks](l, handleArg(args, 1))
+	if ctx == nil || out == nil {
+		setInt(ret, -1)
+		return
+	}
+
+	input := (*inputTextType)(pointerArg(args, 2))
+	text := utils.BytePtrToString(input.Text)
+
+	bitmaps := unsafe.Slice((*uintptr)(pointerArg(args, 3)), uint64Arg(args, 4))
+	parts := strings.Split(text, ctx.marker)
+	if len(parts)-1 != len(bitmaps) {
+		setInt(ret, 1)
+		return
+	}
+
+	for _, b := range bitmaps {
+		if get[bitmap](l, b) == nil {
+			setInt(ret, 2)
+			return
+		}
+	}
+
+	out.items = out.items[:0]
+	for i, part := range parts {
+		if i > 0 {
+			out.items = append(out.items, chunk{typ: chunkTypeImage, tokens: make([]int32, NImageTokens)})
+		}
+
+		tokens := tokenize(part, i == 0 && input.AddSpecial != 0, input.ParseSpecial != 0)
+		if len(tokens) > 0 {
+			out.items = append(out.items, chunk{typ: chunkTypeText, tokens: tokens})
+		}
+	}
+
+	setInt(ret, 0)
+}
+
+func helperEvalChunks(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+	lctx := get[context](l, handleArg(args, 1))
+	in := get[chunks](l, handleArg(args, 2))
+	if get[mtmdContext](l, handleArg(args, 0)) == nil || lctx == nil || in == nil {
+		setInt(ret, -1)
+		return
+	}
+
+	nPast := int32Arg(args, 3)
+	seq := int32Arg(args, 4)
+	logitsLast := boolArg(args, 6)
+
+	for i, c := range in.items {
+		n := len(c.tokens)
+		pos := make([]int32, n)
+		seqs := make([][]int32, n)
+		outputs := make([]bool, n)
+		for j := range c.tokens {
+			pos[j] = nPast + int32(j)
+			seqs[j] = []int32{seq}
+		}
+		outputs[n-1] = logitsLast && i == len(in.items)-1
+
+		if result := lctx.decode(c.tokens, pos, seqs, outputs); result != 0 {
+			setInt(ret, int64(result))
+			return
+		}
+		nPast += int32(n)
+	}
+
+	if p := pointerArg(args, 7); p != nil {
+		*(*int32)(p) = nPast
+	}
+	setInt(ret, 0)
+}
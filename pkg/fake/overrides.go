@@ -0,0 +1,83 @@
+package fake
+
+import (
+	"strconv"
+	"unsafe"
+)
+
+const (
+	kvOverrideInt = iota
+	kvOverrideFloat
+	kvOverrideBool
+	kvOverrideStr
+)
+
+// kvOverrideType is the memory layout of llama_model_kv_override.
+type kvOverrideType struct {
+	Tag   int32
+	Key   [128]byte
+	_     [4]byte
+	Value [128]byte
+}
+
+// applyOverrides applies a NULL-terminated array of llama_model_kv_override to the metadata of
+// the model. Unlike llama.cpp, the overridden values are also reported by llama_model_meta_val_str,
+// and overrides of unknown keys are added to the metadata. It returns false if an override has
+// the wrong type for a known key, which makes llama.cpp fail to load the model.
+func (m *model) applyOverrides(p unsafe.Pointer) bool {
+	for kv := (*kvOverrideType)(p); kv.Key[0] != 0; kv = (*kvOverrideType)(unsafe.Add(unsafe.Pointer(kv), unsafe.Sizeof(*kv))) {
+		key := cString(kv.Key[:])
+
+		var value string
+		switch kv.Tag {
+		case kvOverrideInt:
+			value = strconv.FormatInt(*(*int64)(unsafe.Pointer(&kv.Value)), 10)
+		case kvOverrideFloat:
+			value = strconv.FormatFloat(*(*float64)(unsafe.Pointer(&kv.Value)), 'f', 6, 64)
+		case kvOverrideBool:
+			value = strconv.FormatBool(kv.Value[0] != 0)
+		case kvOverrideStr:
+			value = cString(kv.Value[:])
+		default:
+			return false
+		}
+
+		i := -1
+		for j := range m.metadata {
+			if m.metadata[j][0] == key {
+				i = j
+			}
+		}
+
+		if i < 0 {
+			m.metadata = append(m.metadata, [2]string{key, value})
+			continue
+		}
+
+		// Every known key of the toy model is either an integer or a string.
+		if _, err := strconv.ParseInt(m.metadata[i][1], 10, 64); (err == nil) != (kv.Tag == kvOverrideInt) {
+			return false
+		}
+		if kv.Tag != kvOverrideInt && kv.Tag != kvOverrideStr {
+			return false
+		}
+
+		m.metadata[i][1] = value
+		if key == "fake.context_length" {
+			m.nCtxTrain = *(*int64)(unsafe.Pointer(&kv.Value))
+		}
+	}
+
+	return true
+}
+
+// cString returns the NUL-terminated string at the start of b.
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+
+	return string(b)
+}
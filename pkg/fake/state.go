@@ -0,0 +1,291 @@
+package fake
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"slices"
+	"unsafe"
+)
+
+// The magic numbers and versions of the files saved by llama_state_save_file and llama_state_seq_save_file.
+// They are the same as llama.SESSION_MAGIC and the others, which cannot be imported as the tests of
+// the llama package import this package. llama.StateLoadFile checks them before loading a file, so
+// the state file tests of the llama package fail if they differ.
+const (
+	sessionMagic    = 0x6767736e
+	sessionVersion  = 9
+	stateSeqMagic   = 0x67677371
+	stateSeqVersion = 2
+)
+
+var stateHandlers = map[string]handler{
+	"llama_state_get_size": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		var n int
+		if ctx := get[context](l, handleArg(args, 0)); ctx != nil {
+			n = len(ctx.state(-1))
+		}
+		setInt(ret, int64(n))
+	},
+	"llama_state_get_data": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		ctx := get[context](l, handleArg(args, 0))
+		if ctx == nil {
+			setInt(ret, 0)
+			return
+		}
+		setInt(ret, copyState(ctx.state(-1), pointerArg(args, 1), uint64Arg(args, 2)))
+	},
+	"llama_state_set_data": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		ctx := get[context](l, handleArg(args, 0))
+		if ctx == nil {
+			setInt(ret, 0)
+			return
+		}
+		data := unsafe.Slice((*byte)(pointerArg(args, 1)), uint64Arg(args, 2))
+		setInt(ret, int64(ctx.setState(data, -1)))
+	},
+	"llama_state_seq_get_size": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		var n int
+		if ctx := get[context](l, handleArg(args, 0)); ctx != nil {
+			n = len(ctx.state(int32Arg(args, 1)))
+		}
+		setInt(ret, int64(n))
+	},
+	"llama_state_seq_get_data": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		ctx := get[context](l, handleArg(args, 0))
+		if ctx == nil {
+			setInt(ret, 0)
+			return
+		}
+		setInt(ret, copyState(ctx.state(int32Arg(args, 3)), pointerArg(args, 1), uint64Arg(args, 2)))
+	},
+	"llama_state_seq_set_data": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		ctx := get[context](l, handleArg(args, 0))
+		if ctx == nil {
+			setInt(ret, 0)
+			return
+		}
+		data := unsafe.Slice((*byte)(pointerArg(args, 1)), uint64Arg(args, 2))
+		setInt(ret, int64(ctx.setState(data, int32Arg(args, 3))))
+	},
+	"llama_state_save_file": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		ctx := get[context](l, handleArg(args, 0))
+		if ctx == nil {
+			setBool(ret, false)
+			return
+		}
+		tokens := tokensArg(args, 2, 3)
+		setBool(ret, saveState(stringArg(args, 1), sessionMagic, sessionVersion, tokens, ctx.state(-1)) > 0)
+	},
+	"llama_state_load_file": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		ctx := get[context](l, handleArg(args, 0))
+		if ctx == nil {
+			setBool(ret, false)
+			return
+		}
+		setBool(ret, ctx.loadState(stringArg(args, 1), sessionMagic, sessionVersion, -1, args[2:]) > 0)
+	},
+	"llama_state_seq_save_file": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		ctx := get[context](l, handleArg(args, 0))
+		if ctx == nil {
+			setInt(ret, 0)
+			return
+		}
+		tokens := tokensArg(args, 3, 4)
+		setInt(ret, saveState(stringArg(args, 1), stateSeqMagic, stateSeqVersion, tokens, ctx.state(int32Arg(args, 2))))
+	},
+	"llama_state_seq_load_file": func(l *Lib, ret unsafe.Pointer, args []unsafe.Pointer) {
+		ctx := get[context](l, handleArg(args, 0))
+		if ctx == nil {
+			setInt(ret, 0)
+			return
+		}
+		setInt(ret, ctx.loadState(stringArg(args, 1), stateSeqMagic, stateSeqVersion, int32Arg(args, 2), args[3:]))
+	},
+}
+
+// tokensArg returns the tokens in the array and count arguments at ptr and count.
+func tokensArg(args []unsafe.Pointer, ptr, count int) []int32 {
+	n := uint64Arg(args, count)
+	if n == 0 {
+		return nil
+	}
+
+	return unsafe.Slice((*int32)(pointerArg(args, ptr)), n)
+}
+
+// copyState copies state to dst, and returns the number of bytes copied, or 0 if dst is too small.
+func copyState(state []byte, dst unsafe.Pointer, size uint64) int64 {
+	if dst == nil || size < uint64(len(state)) {
+		return 0
+	}
+
+	return int64(copy(unsafe.Slice((*byte)(dst), size), state))
+}
+
+// state returns the cells of the KV cache of a sequence, or of every sequence and the outputs
+// of the last batch if seq < 0.
+func (c *context) state(seq int32) []byte {
+	var buf bytes.Buffer
+	w := func(v any) { binary.Write(&buf, binary.LittleEndian, v) }
+
+	var cells []*cell
+	for _, ce := range c.cells {
+		if seq < 0 || ce.seqs[seq] {
+			cells = append(cells, ce)
+		}
+	}
+
+	w(uint32(len(cells)))
+	for _, ce := range cells {
+		w(ce.pos)
+		w(ce.token)
+		if seq >= 0 {
+			continue
+		}
+
+		seqs := make([]int32, 0, len(ce.seqs))
+		for s := range ce.seqs {
+			seqs = append(seqs, s)
+		}
+		slices.Sort(seqs)
+		w(uint32(len(seqs)))
+		w(seqs)
+	}
+
+	if seq < 0 {
+		w(c.last)
+		keys := make([]int32, 0, len(c.outputs))
+		for i := range c.outputs {
+			keys = append(keys, i)
+		}
+		slices.Sort(keys)
+		w(uint32(len(keys)))
+		for _, i := range keys {
+			w(i)
+			w(c.outputs[i])
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// setState restores the state returned by state. If seq >= 0, the cells are restored into seq,
+// replacing what was in it. It returns the number of bytes read, or 0 if the state is invalid.
+func (c *context) setState(data []byte, seq int32) int {
+	r := bytes.NewReader(data)
+	rd := func(v any) bool { return binary.Read(r, binary.LittleEndian, v) == nil }
+
+	var n uint32
+	if !rd(&n) || n > c.params.NCtx {
+		return 0
+	}
+
+	cells := make([]*cell, n)
+	for i := range cells {
+		ce := &cell{seqs: make(map[int32]bool)}
+		if !rd(&ce.pos) || !rd(&ce.token) {
+			return 0
+		}
+
+		if seq >= 0 {
+			ce.seqs[seq] = true
+		} else {
+			var nSeqs uint32
+			if !rd(&nSeqs) || uint64(nSeqs) > uint64(r.Len()) {
+				return 0
+			}
+			seqs := make([]int32, nSeqs)
+			if !rd(seqs) {
+				return 0
+			}
+			for _, s := range seqs {
+				ce.seqs[s] = true
+			}
+		}
+		cells[i] = ce
+	}
+
+	if seq >= 0 {
+		if uint32(len(c.cells))+n > c.params.NCtx {
+			return 0
+		}
+		c.seqRm(seq, -1, -1)
+		c.cells = append(c.cells, cells...)
+
+		return len(data) - r.Len()
+	}
+
+	var last int32
+	var nOutputs uint32
+	if !rd(&last) || !rd(&nOutputs) {
+		return 0
+	}
+
+	outputs := make(map[int32][]float32)
+	for range nOutputs {
+		var i int32
+		out := make([]float32, NVocab)
+		if !rd(&i) || !rd(out) {
+			return 0
+		}
+		outputs[i] = out
+	}
+
+	c.cells, c.last, c.outputs = cells, last, outputs
+
+	return len(data) - r.Len()
+}
+
+// saveState writes a state file with the tokens that were used to create the state, and returns
+// the number of bytes written, or 0 if it cannot be written.
+func saveState(path string, magic, version uint32, tokens []int32, state []byte) int64 {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint32{magic, version, uint32(len(tokens))})
+	binary.Write(&buf, binary.LittleEndian, tokens)
+	buf.Write(state)
+
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return 0
+	}
+
+	return int64(buf.Len())
+}
+
+// loadState reads a state file written by saveState into seq, or into the whole context if seq < 0.
+// args are the tokens_out, n_token_capacity and n_token_count_out arguments. It returns the number of
+// bytes read, or 0 if the file cannot be loaded.
+func (c *context) loadState(path string, magic, version uint32, seq int32, args []unsafe.Pointer) int64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+
+	r := bytes.NewReader(data)
+	var header [3]uint32
+	if binary.Read(r, binary.LittleEndian, &header) != nil || header[0] != magic || header[1] != version {
+		return 0
+	}
+
+	nTokens := header[2]
+	if uint64(nTokens) > uint64Arg(args, 1) || uint64(nTokens)*4 > uint64(r.Len()) {
+		return 0
+	}
+
+	tokens := make([]int32, nTokens)
+	if binary.Read(r, binary.LittleEndian, tokens) != nil {
+		return 0
+	}
+
+	state := data[len(data)-r.Len():]
+	if c.setState(state, seq) != len(state) {
+		return 0
+	}
+
+	if nTokens > 0 {
+		copy(unsafe.Slice((*int32)(pointerArg(args, 0)), nTokens), tokens)
+	}
+	*(*uint64)(pointerArg(args, 2)) = uint64(nTokens)
+
+	return int64(len(data))
+}
@@ -0,0 +1,98 @@
+package gguf
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ControlVector is a steering vector that is added to the output of each layer of a model.
+// Data has NEmbd values for every layer starting at layer 1, so the direction for layer il is
+// Data[(il-1)*NEmbd : il*NEmbd]. Apply it to a context with llama.ApplyControlVector.
+type ControlVector struct {
+	NEmbd int32
+	Data  []float32
+}
+
+// NLayer returns the number of layers that the control vector has a direction for.
+func (cv ControlVector) NLayer() int32 {
+	if cv.NEmbd <= 0 {
+		return 0
+	}
+
+	return int32(len(cv.Data)) / cv.NEmbd
+}
+
+// ReadControlVector reads a control vector from the GGUF file at path, such as one written by
+// llama.cpp's cvector-generator. Apply it to a context with llama.ApplyControlVector.
+func ReadControlVector(path string) (ControlVector, error) {
+	f, err := Open(path)
+	if err != nil {
+		return ControlVector{}, err
+	}
+	defer f.Close()
+
+	cv, err := f.ControlVector()
+	if err != nil {
+		return ControlVector{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return cv, nil
+}
+
+// ControlVector reads the control vector in the file, which has an F32 tensor named direction.N
+// for each layer N that it applies to. The file must have been opened with [Open].
+func (f *File) ControlVector() (ControlVector, error) {
+	var (
+		cv     ControlVector
+		layers = make(map[int][]float32)
+		nLayer int
+	)
+
+	for _, t := range f.Tensors {
+		name, ok := strings.CutPrefix(t.Name, "direction.")
+		if !ok {
+			continue
+		}
+
+		il, err := strconv.Atoi(name)
+		if err != nil || il < 1 {
+			return ControlVector{}, fmt.Errorf("%w: invalid control vector layer %q", ErrInvalid, t.Name)
+		}
+
+		if t.Type != TensorTypeF32 || len(t.Dims) != 1 {
+			return ControlVector{}, fmt.Errorf("%w: control vector tensor %s must be a 1-dimensional F32 tensor", ErrInvalid, t.Name)
+		}
+
+		if cv.NEmbd == 0 {
+			cv.NEmbd = int32(t.Dims[0])
+		} else if int32(t.Dims[0]) != cv.NEmbd {
+			return ControlVector{}, fmt.Errorf("%w: control vector tensor %s has %d values, expected %d", ErrInvalid, t.Name, t.Dims[0], cv.NEmbd)
+		}
+
+		r, err := f.TensorReader(t)
+		if err != nil {
+			return ControlVector{}, err
+		}
+
+		data := make([]float32, cv.NEmbd)
+		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
+			return ControlVector{}, fmt.Errorf("%w: reading %s: %w", ErrInvalid, t.Name, err)
+		}
+
+		layers[il] = data
+		nLayer = max(nLayer, il)
+	}
+
+	if nLayer == 0 {
+		return ControlVector{}, fmt.Errorf("%w: no control vector direction tensors", ErrInvalid)
+	}
+
+	cv.Data = make([]float32, nLayer*int(cv.NEmbd))
+	for il, data := range layers {
+		copy(cv.Data[(il-1)*int(cv.NEmbd):], data)
+	}
+
+	return cv, nil
+}
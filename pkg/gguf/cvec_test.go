@@ -0,0 +1,71 @@
+package gguf
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testControlVector returns a control vector file with directions for layers 1 and 3.
+func testControlVector(tensorType TensorType) []byte {
+	b := &testFile{}
+	b.put(uint32(Magic), uint32(3), uint64(2), uint64(2))
+	b.put(KeyArchitecture, TypeString, "controlvector")
+	b.put("controlvector.model_hint", TypeString, "llama")
+
+	b.put("direction.1", uint32(1), uint64(4), tensorType, uint64(0))
+	b.put("direction.3", uint32(1), uint64(4), tensorType, uint64(32))
+
+	for b.Len()%DefaultAlignment != 0 {
+		b.WriteByte(0)
+	}
+	b.put(float32(1), float32(2), float32(3), float32(4))
+	b.Write(make([]byte, 16))
+	b.put(float32(-1), float32(-2), float32(-3), float32(-4))
+
+	return b.Bytes()
+}
+
+func TestReadControlVector(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cvec.gguf")
+	if err := os.WriteFile(path, testControlVector(TensorTypeF32), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cv, err := ReadControlVector(path)
+	if err != nil {
+		t.Fatal("unable to read control vector", err)
+	}
+
+	if cv.NEmbd != 4 || cv.NLayer() != 3 {
+		t.Fatal("wrong control vector size", cv.NEmbd, cv.NLayer())
+	}
+
+	want := []float32{1, 2, 3, 4, 0, 0, 0, 0, -1, -2, -3, -4}
+	if !slices.Equal(cv.Data, want) {
+		t.Fatal("wrong control vector data", cv.Data)
+	}
+}
+
+func TestReadControlVectorInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	f16 := filepath.Join(dir, "f16.gguf")
+	if err := os.WriteFile(f16, testControlVector(TensorTypeF16), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadControlVector(f16); err == nil {
+		t.Fatal("expected error for F16 control vector")
+	}
+
+	model := filepath.Join(dir, "model.gguf")
+	if err := os.WriteFile(model, testModel(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadControlVector(model); err == nil {
+		t.Fatal("expected error for a file without directions")
+	}
+}
@@ -0,0 +1,198 @@
+package gguf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	// maxDims is the maximum number of dimensions of a tensor, GGML_MAX_DIMS.
+	maxDims = 4
+
+	// maxPrealloc limits the memory that is allocated up front for a string or an array, so that
+	// a corrupt length fails with an error when the data runs out instead of allocating it all.
+	maxPrealloc = 1 << 16
+)
+
+// decoder reads little-endian GGUF values from a stream, and keeps track of the offset.
+// Once there is an error, every read returns the zero value and the error is kept in err.
+type decoder struct {
+	r   *bufio.Reader
+	off int64
+	err error
+	buf [8]byte
+}
+
+func newDecoder(r io.Reader) *decoder {
+	return &decoder{r: bufio.NewReaderSize(r, 1<<16)}
+}
+
+// error returns the error with the offset that it happened at.
+func (d *decoder) error() error {
+	if errors.Is(d.err, io.EOF) || errors.Is(d.err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: unexpected end of file at offset %d", ErrInvalid, d.off)
+	}
+
+	return fmt.Errorf("offset %d: %w", d.off, d.err)
+}
+
+func (d *decoder) read(n int) []byte {
+	if d.err != nil {
+		return d.buf[:n]
+	}
+
+	_, d.err = io.ReadFull(d.r, d.buf[:n])
+	d.off += int64(n)
+
+	return d.buf[:n]
+}
+
+func (d *decoder) uint8() uint8 {
+	return d.read(1)[0]
+}
+
+func (d *decoder) uint16() uint16 {
+	return binary.LittleEndian.Uint16(d.read(2))
+}
+
+func (d *decoder) uint32() uint32 {
+	return binary.LittleEndian.Uint32(d.read(4))
+}
+
+func (d *decoder) uint64() uint64 {
+	return binary.LittleEndian.Uint64(d.read(8))
+}
+
+func (d *decoder) string() string {
+	n := d.uint64()
+	if d.err != nil {
+		return ""
+	}
+
+	buf := make([]byte, 0, min(n, maxPrealloc))
+	for uint64(len(buf)) < n && d.err == nil {
+		chunk := make([]byte, min(n-uint64(len(buf)), maxPrealloc))
+		var read int
+		read, d.err = io.ReadFull(d.r, chunk)
+		d.off += int64(read)
+		buf = append(buf, chunk[:read]...)
+	}
+
+	return string(buf)
+}
+
+// value reads a value of type t. depth is the number of arrays that the value is nested in.
+func (d *decoder) value(t ValueType, depth int) Value {
+	v := Value{Type: t}
+	switch t {
+	case TypeUint8:
+		v.data = d.uint8()
+	case TypeInt8:
+		v.data = int8(d.uint8())
+	case TypeUint16:
+		v.data = d.uint16()
+	case TypeInt16:
+		v.data = int16(d.uint16())
+	case TypeUint32:
+		v.data = d.uint32()
+	case TypeInt32:
+		v.data = int32(d.uint32())
+	case TypeFloat32:
+		v.data = math.Float32frombits(d.uint32())
+	case TypeBool:
+		v.data = d.uint8() != 0
+	case TypeString:
+		v.data = d.string()
+	case TypeUint64:
+		v.data = d.uint64()
+	case TypeInt64:
+		v.data = int64(d.uint64())
+	case TypeFloat64:
+		v.data = math.Float64frombits(d.uint64())
+	case TypeArray:
+		if depth > 1 {
+			d.fail("%w: arrays nested more than 2 deep", ErrInvalid)
+			break
+		}
+		v.ArrayType = ValueType(d.uint32())
+		v.data = d.array(v.ArrayType, d.uint64(), depth)
+	default:
+		d.fail("%w: unknown value type %d", ErrInvalid, t)
+	}
+
+	return v
+}
+
+// array reads n values of type t into a slice of the matching Go type.
+func (d *decoder) array(t ValueType, n uint64, depth int) any {
+	switch t {
+	case TypeUint8:
+		return readArray(d, n, d.uint8)
+	case TypeInt8:
+		return readArray(d, n, func() int8 { return int8(d.uint8()) })
+	case TypeUint16:
+		return readArray(d, n, d.uint16)
+	case TypeInt16:
+		return readArray(d, n, func() int16 { return int16(d.uint16()) })
+	case TypeUint32:
+		return readArray(d, n, d.uint32)
+	case TypeInt32:
+		return readArray(d, n, func() int32 { return int32(d.uint32()) })
+	case TypeFloat32:
+		return readArray(d, n, func() float32 { return math.Float32frombits(d.uint32()) })
+	case TypeBool:
+		return readArray(d, n, func() bool { return d.uint8() != 0 })
+	case TypeString:
+		return readArray(d, n, d.string)
+	case TypeUint64:
+		return readArray(d, n, d.uint64)
+	case TypeInt64:
+		return readArray(d, n, func() int64 { return int64(d.uint64()) })
+	case TypeFloat64:
+		return readArray(d, n, func() float64 { return math.Float64frombits(d.uint64()) })
+	case TypeArray:
+		return readArray(d, n, func() Value { return d.value(TypeArray, depth+1) })
+	default:
+		d.fail("%w: unknown array type %d", ErrInvalid, t)
+		return nil
+	}
+}
+
+func readArray[T any](d *decoder, n uint64, next func() T) []T {
+	values := make([]T, 0, min(n, maxPrealloc))
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		values = append(values, next())
+	}
+
+	return values
+}
+
+func (d *decoder) tensorInfo() TensorInfo {
+	t := TensorInfo{Name: d.string()}
+
+	nDims := d.uint32()
+	if nDims > maxDims {
+		d.fail("%w: tensor %s has %d dimensions", ErrInvalid, t.Name, nDims)
+		return t
+	}
+
+	t.Dims = make([]uint64, nDims)
+	for i := range t.Dims {
+		t.Dims[i] = d.uint64()
+	}
+
+	t.Type = TensorType(d.uint32())
+	t.Offset = d.uint64()
+
+	return t
+}
+
+func (d *decoder) fail(format string, args ...any) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+}
@@ -0,0 +1,147 @@
+package gguf
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// encoder writes little-endian GGUF values to a stream, and keeps track of the offset.
+// Once there is an error, every write does nothing and the error is kept in err.
+type encoder struct {
+	w   *bufio.Writer
+	off int64
+	err error
+	buf [8]byte
+}
+
+func newEncoder(w io.Writer) *encoder {
+	return &encoder{w: bufio.NewWriterSize(w, 1<<16)}
+}
+
+func (e *encoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+
+	var n int
+	n, e.err = e.w.Write(b)
+	e.off += int64(n)
+}
+
+func (e *encoder) uint8(v uint8) {
+	e.buf[0] = v
+	e.write(e.buf[:1])
+}
+
+func (e *encoder) uint16(v uint16) {
+	binary.LittleEndian.PutUint16(e.buf[:], v)
+	e.write(e.buf[:2])
+}
+
+func (e *encoder) uint32(v uint32) {
+	binary.LittleEndian.PutUint32(e.buf[:], v)
+	e.write(e.buf[:4])
+}
+
+func (e *encoder) uint64(v uint64) {
+	binary.LittleEndian.PutUint64(e.buf[:], v)
+	e.write(e.buf[:8])
+}
+
+func (e *encoder) bool(v bool) {
+	if v {
+		e.uint8(1)
+		return
+	}
+	e.uint8(0)
+}
+
+func (e *encoder) string(s string) {
+	e.uint64(uint64(len(s)))
+	e.write([]byte(s))
+}
+
+func (e *encoder) value(v Value) {
+	switch x := v.data.(type) {
+	case uint8:
+		e.uint8(x)
+	case int8:
+		e.uint8(uint8(x))
+	case uint16:
+		e.uint16(x)
+	case int16:
+		e.uint16(uint16(x))
+	case uint32:
+		e.uint32(x)
+	case int32:
+		e.uint32(uint32(x))
+	case float32:
+		e.uint32(math.Float32bits(x))
+	case bool:
+		e.bool(x)
+	case string:
+		e.string(x)
+	case uint64:
+		e.uint64(x)
+	case int64:
+		e.uint64(uint64(x))
+	case float64:
+		e.uint64(math.Float64bits(x))
+	case []uint8:
+		writeArray(e, v.ArrayType, x, e.uint8)
+	case []int8:
+		writeArray(e, v.ArrayType, x, func(v int8) { e.uint8(uint8(v)) })
+	case []uint16:
+		writeArray(e, v.ArrayType, x, e.uint16)
+	case []int16:
+		writeArray(e, v.ArrayType, x, func(v int16) { e.uint16(uint16(v)) })
+	case []uint32:
+		writeArray(e, v.ArrayType, x, e.uint32)
+	case []int32:
+		writeArray(e, v.ArrayType, x, func(v int32) { e.uint32(uint32(v)) })
+	case []float32:
+		writeArray(e, v.ArrayType, x, func(v float32) { e.uint32(math.Float32bits(v)) })
+	case []bool:
+		writeArray(e, v.ArrayType, x, e.bool)
+	case []string:
+		writeArray(e, v.ArrayType, x, e.string)
+	case []uint64:
+		writeArray(e, v.ArrayType, x, e.uint64)
+	case []int64:
+		writeArray(e, v.ArrayType, x, func(v int64) { e.uint64(uint64(v)) })
+	case []float64:
+		writeArray(e, v.ArrayType, x, func(v float64) { e.uint64(math.Float64bits(v)) })
+	case []Value:
+		writeArray(e, v.ArrayType, x, e.value)
+	default:
+		if e.err == nil {
+			e.err = fmt.Errorf("gguf: unable to write value of type %T", v.data)
+		}
+	}
+}
+
+func writeArray[T any](e *encoder, t ValueType, values []T, write func(T)) {
+	e.uint32(uint32(t))
+	e.uint64(uint64(len(values)))
+	for _, v := range values {
+		write(v)
+	}
+}
+
+func (e *encoder) tensorInfo(t TensorInfo) {
+	e.string(t.Name)
+	e.uint32(uint32(len(t.Dims)))
+	for _, d := range t.Dims {
+		e.uint64(d)
+	}
+	e.uint32(uint32(t.Type))
+	e.uint64(t.Offset)
+}
+
+// pad writes zeros up to the next multiple of align.
+func (e *encoder) pad(align uint64) {
+	e.write(make([]byte, alignOffset(uint64(e.off), align)-uint64(e.off)))
+}
@@ -0,0 +1,90 @@
+package gguf
+
+// Ftype is the general.file_type of a model, which is the llama_ftype that it was quantized to.
+type Ftype int32
+
+const (
+	FtypeAllF32        Ftype = 0
+	FtypeMostlyF16     Ftype = 1
+	FtypeMostlyQ4_0    Ftype = 2
+	FtypeMostlyQ4_1    Ftype = 3
+	FtypeMostlyQ8_0    Ftype = 7
+	FtypeMostlyQ5_0    Ftype = 8
+	FtypeMostlyQ5_1    Ftype = 9
+	FtypeMostlyQ2_K    Ftype = 10
+	FtypeMostlyQ3_K_S  Ftype = 11
+	FtypeMostlyQ3_K_M  Ftype = 12
+	FtypeMostlyQ3_K_L  Ftype = 13
+	FtypeMostlyQ4_K_S  Ftype = 14
+	FtypeMostlyQ4_K_M  Ftype = 15
+	FtypeMostlyQ5_K_S  Ftype = 16
+	FtypeMostlyQ5_K_M  Ftype = 17
+	FtypeMostlyQ6_K    Ftype = 18
+	FtypeMostlyIQ2_XXS Ftype = 19
+	FtypeMostlyIQ2_XS  Ftype = 20
+	FtypeMostlyQ2_K_S  Ftype = 21
+	FtypeMostlyIQ3_XS  Ftype = 22
+	FtypeMostlyIQ3_XXS Ftype = 23
+	FtypeMostlyIQ1_S   Ftype = 24
+	FtypeMostlyIQ4_NL  Ftype = 25
+	FtypeMostlyIQ3_S   Ftype = 26
+	FtypeMostlyIQ3_M   Ftype = 27
+	FtypeMostlyIQ2_S   Ftype = 28
+	FtypeMostlyIQ2_M   Ftype = 29
+	FtypeMostlyIQ4_XS  Ftype = 30
+	FtypeMostlyIQ1_M   Ftype = 31
+	FtypeMostlyBF16    Ftype = 32
+	FtypeMostlyTQ1_0   Ftype = 36
+	FtypeMostlyTQ2_0   Ftype = 37
+	FtypeMostlyMXFP4   Ftype = 38
+	FtypeGuessed       Ftype = 1024
+)
+
+var ftypeNames = map[Ftype]string{
+	FtypeAllF32:        "all F32",
+	FtypeMostlyF16:     "F16",
+	FtypeMostlyBF16:    "BF16",
+	FtypeMostlyQ4_0:    "Q4_0",
+	FtypeMostlyQ4_1:    "Q4_1",
+	FtypeMostlyQ5_0:    "Q5_0",
+	FtypeMostlyQ5_1:    "Q5_1",
+	FtypeMostlyQ8_0:    "Q8_0",
+	FtypeMostlyMXFP4:   "MXFP4 MoE",
+	FtypeMostlyQ2_K:    "Q2_K - Medium",
+	FtypeMostlyQ2_K_S:  "Q2_K - Small",
+	FtypeMostlyQ3_K_S:  "Q3_K - Small",
+	FtypeMostlyQ3_K_M:  "Q3_K - Medium",
+	FtypeMostlyQ3_K_L:  "Q3_K - Large",
+	FtypeMostlyQ4_K_S:  "Q4_K - Small",
+	FtypeMostlyQ4_K_M:  "Q4_K - Medium",
+	FtypeMostlyQ5_K_S:  "Q5_K - Small",
+	FtypeMostlyQ5_K_M:  "Q5_K - Medium",
+	FtypeMostlyQ6_K:    "Q6_K",
+	FtypeMostlyTQ1_0:   "TQ1_0 - 1.69 bpw ternary",
+	FtypeMostlyTQ2_0:   "TQ2_0 - 2.06 bpw ternary",
+	FtypeMostlyIQ2_XXS: "IQ2_XXS - 2.0625 bpw",
+	FtypeMostlyIQ2_XS:  "IQ2_XS - 2.3125 bpw",
+	FtypeMostlyIQ2_S:   "IQ2_S - 2.5 bpw",
+	FtypeMostlyIQ2_M:   "IQ2_M - 2.7 bpw",
+	FtypeMostlyIQ3_XS:  "IQ3_XS - 3.3 bpw",
+	FtypeMostlyIQ3_XXS: "IQ3_XXS - 3.0625 bpw",
+	FtypeMostlyIQ1_S:   "IQ1_S - 1.5625 bpw",
+	FtypeMostlyIQ1_M:   "IQ1_M - 1.75 bpw",
+	FtypeMostlyIQ4_NL:  "IQ4_NL - 4.5 bpw",
+	FtypeMostlyIQ4_XS:  "IQ4_XS - 4.25 bpw",
+	FtypeMostlyIQ3_S:   "IQ3_S - 3.4375 bpw",
+	FtypeMostlyIQ3_M:   "IQ3_S mix - 3.66 bpw",
+}
+
+// String returns the name of the Ftype, as shown in the description of a model by llama.cpp.
+func (f Ftype) String() string {
+	if f&FtypeGuessed != 0 {
+		return (f &^ FtypeGuessed).String() + " (guessed)"
+	}
+
+	if name, ok := ftypeNames[f]; ok {
+		return name
+	}
+
+	return "unknown, may not work"
+}
@@ -0,0 +1,250 @@
+// Package gguf reads and rewrites GGUF model files, such as language models and mmproj projector
+// files, without needing the llama.cpp libraries.
+//
+// The header, metadata and tensor descriptions are read in a single pass. The tensor data is not
+// read, but the absolute offset of each tensor is available so that it can be read or mmapped:
+//
+//	f, err := gguf.Open("model.gguf")
+//	if err != nil {
+//		return err
+//	}
+//	defer f.Close()
+//
+//	fmt.Println(f.Architecture(), f.Name())
+//	for _, t := range f.Tensors {
+//		fmt.Println(t.Name, t.Dims, t.Type)
+//	}
+//
+// Use [Rewrite] to copy a file with changes to its metadata. The tensor data is copied unchanged.
+package gguf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Magic is the magic number at the start of every GGUF file, "GGUF" in little-endian order.
+const Magic = 0x46554747
+
+// DefaultAlignment is the alignment of the tensor data when general.alignment is not set.
+const DefaultAlignment = 32
+
+// Well known metadata keys.
+const (
+	KeyArchitecture = "general.architecture"
+	KeyName         = "general.name"
+	KeyFileType     = "general.file_type"
+	KeyAlignment    = "general.alignment"
+	KeyChatTemplate = "tokenizer.chat_template"
+)
+
+var (
+	// ErrInvalid is returned when a file is not a GGUF file, or is corrupt.
+	ErrInvalid = errors.New("gguf: invalid file")
+
+	// ErrVersion is returned for versions of GGUF that are not supported.
+	ErrVersion = errors.New("gguf: unsupported version")
+)
+
+// KV is a single metadata key and value.
+type KV struct {
+	Key   string
+	Value Value
+}
+
+// File is a parsed GGUF file.
+type File struct {
+	// Version is the version of the GGUF format, 2 or 3.
+	Version uint32
+
+	// Metadata is every key/value pair in the file, in order.
+	Metadata []KV
+
+	// Tensors describes every tensor in the file, in order.
+	Tensors []TensorInfo
+
+	// Alignment is the alignment of the tensor data.
+	Alignment uint64
+
+	// DataOffset is the absolute offset of the tensor data in the file.
+	DataOffset int64
+
+	r      io.ReaderAt
+	size   int64
+	closer io.Closer
+}
+
+// Open opens and parses the GGUF file at path. The file stays open so that tensor data can be
+// read with [File.TensorReader], call [File.Close] when done with it.
+func Open(path string) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := Read(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	f.r = file
+	f.size = info.Size()
+	f.closer = file
+
+	return f, nil
+}
+
+// Read parses the header, metadata and tensor descriptions of a GGUF file from r.
+// It stops reading at the start of the tensor data.
+func Read(r io.Reader) (*File, error) {
+	d := newDecoder(r)
+
+	if magic := d.uint32(); d.err == nil && magic != Magic {
+		return nil, fmt.Errorf("%w: bad magic %#x", ErrInvalid, magic)
+	}
+
+	f := &File{Version: d.uint32(), Alignment: DefaultAlignment}
+	if d.err == nil && f.Version != 2 && f.Version != 3 {
+		return nil, fmt.Errorf("%w %d", ErrVersion, f.Version)
+	}
+
+	nTensors, nKV := d.uint64(), d.uint64()
+	if d.err != nil {
+		return nil, d.error()
+	}
+
+	for i := uint64(0); i < nKV && d.err == nil; i++ {
+		kv := KV{Key: d.string()}
+		kv.Value = d.value(ValueType(d.uint32()), 0)
+		f.Metadata = append(f.Metadata, kv)
+	}
+
+	for i := uint64(0); i < nTensors && d.err == nil; i++ {
+		f.Tensors = append(f.Tensors, d.tensorInfo())
+	}
+
+	if d.err != nil {
+		return nil, d.error()
+	}
+
+	if v, ok := f.Get(KeyAlignment); ok {
+		align, ok := v.Uint()
+		if !ok || align == 0 || align&(align-1) != 0 {
+			return nil, fmt.Errorf("%w: alignment %s is not a power of 2", ErrInvalid, v)
+		}
+		f.Alignment = align
+	}
+
+	f.DataOffset = int64(alignOffset(uint64(d.off), f.Alignment))
+	for _, t := range f.Tensors {
+		if t.Offset%f.Alignment != 0 {
+			return nil, fmt.Errorf("%w: tensor %s is not aligned", ErrInvalid, t.Name)
+		}
+	}
+
+	return f, nil
+}
+
+// Close closes the file, if it was opened with [Open].
+func (f *File) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+
+	err := f.closer.Close()
+	f.closer = nil
+
+	return err
+}
+
+// Get returns the value of a metadata key.
+func (f *File) Get(key string) (Value, bool) {
+	for _, kv := range f.Metadata {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+
+	return Value{}, false
+}
+
+// GetString returns the value of a metadata key if it is a string.
+func (f *File) GetString(key string) (string, bool) {
+	v, ok := f.Get(key)
+	if !ok {
+		return "", false
+	}
+
+	return v.Str()
+}
+
+// Architecture returns the general.architecture of the model, such as "llama" or "clip".
+func (f *File) Architecture() string {
+	arch, _ := f.GetString(KeyArchitecture)
+	return arch
+}
+
+// Name returns the general.name of the model.
+func (f *File) Name() string {
+	name, _ := f.GetString(KeyName)
+	return name
+}
+
+// Ftype returns the general.file_type of the model, or false if it is not set.
+func (f *File) Ftype() (Ftype, bool) {
+	v, ok := f.Get(KeyFileType)
+	if !ok {
+		return 0, false
+	}
+
+	ftype, ok := v.Uint()
+	return Ftype(ftype), ok
+}
+
+// Tensor returns the description of the named tensor.
+func (f *File) Tensor(name string) (TensorInfo, bool) {
+	for _, t := range f.Tensors {
+		if t.Name == name {
+			return t, true
+		}
+	}
+
+	return TensorInfo{}, false
+}
+
+// NParams returns the total number of elements in all of the tensors.
+func (f *File) NParams() uint64 {
+	var n uint64
+	for _, t := range f.Tensors {
+		n += t.NElements()
+	}
+
+	return n
+}
+
+// TensorReader returns a reader for the data of a tensor. The file must have been opened with [Open].
+func (f *File) TensorReader(t TensorInfo) (*io.SectionReader, error) {
+	if f.r == nil {
+		return nil, errors.New("gguf: tensor data is only available for files opened with Open")
+	}
+
+	return io.NewSectionReader(f.r, f.DataOffset+int64(t.Offset), int64(t.Size())), nil
+}
+
+// String returns the value formatted the same way as llama.cpp formats metadata.
+func (kv KV) String() string {
+	return kv.Key + " = " + kv.Value.String()
+}
+
+func alignOffset(off, align uint64) uint64 {
+	return off + (align-off%align)%align
+}
@@ -0,0 +1,177 @@
+package gguf
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testFile builds a GGUF file in memory.
+type testFile struct {
+	bytes.Buffer
+}
+
+func (b *testFile) put(values ...any) *testFile {
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			binary.Write(b, binary.LittleEndian, uint64(len(s)))
+			b.WriteString(s)
+			continue
+		}
+		binary.Write(b, binary.LittleEndian, v)
+	}
+
+	return b
+}
+
+// testModel returns a small GGUF file with a metadata value of every type and two tensors.
+func testModel() []byte {
+	b := &testFile{}
+	b.put(uint32(Magic), uint32(3), uint64(2), uint64(16))
+
+	b.put(KeyArchitecture, TypeString, "llama")
+	b.put(KeyName, TypeString, "Test Model")
+	b.put(KeyFileType, TypeUint32, uint32(FtypeMostlyQ4_K_M))
+	b.put("test.u8", TypeUint8, uint8(8))
+	b.put("test.i8", TypeInt8, int8(-8))
+	b.put("test.u16", TypeUint16, uint16(16))
+	b.put("test.i16", TypeInt16, int16(-16))
+	b.put("test.i32", TypeInt32, int32(-32))
+	b.put("test.f32", TypeFloat32, float32(0.5))
+	b.put("test.bool", TypeBool, true)
+	b.put("test.u64", TypeUint64, uint64(64))
+	b.put("test.i64", TypeInt64, int64(-64))
+	b.put("test.f64", TypeFloat64, float64(1.5))
+	b.put("tokenizer.ggml.tokens", TypeArray, TypeString, uint64(3), "<s>", "</s>", "a")
+	b.put("test.ints", TypeArray, TypeInt32, uint64(3), int32(1), int32(2), int32(3))
+	b.put("test.nested", TypeArray, TypeArray, uint64(2),
+		TypeUint8, uint64(1), uint8(1),
+		TypeUint8, uint64(2), uint8(2), uint8(3))
+
+	b.put("token_embd.weight", uint32(2), uint64(256), uint64(4), TensorTypeQ4_K, uint64(0))
+	b.put("output_norm.weight", uint32(1), uint64(256), TensorTypeF32, uint64(576))
+
+	for b.Len()%DefaultAlignment != 0 {
+		b.WriteByte(0)
+	}
+	b.Write(bytes.Repeat([]byte{1}, 576))
+	b.Write(bytes.Repeat([]byte{2}, 1024))
+
+	return b.Bytes()
+}
+
+func TestRead(t *testing.T) {
+	data := testModel()
+
+	f, err := Read(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("unable to read file", err)
+	}
+
+	if f.Version != 3 || len(f.Metadata) != 16 || len(f.Tensors) != 2 {
+		t.Fatal("unexpected header", f.Version, len(f.Metadata), len(f.Tensors))
+	}
+
+	if f.Architecture() != "llama" || f.Name() != "Test Model" {
+		t.Fatal("unexpected architecture or name", f.Architecture(), f.Name())
+	}
+
+	if ftype, ok := f.Ftype(); !ok || ftype != FtypeMostlyQ4_K_M || ftype.String() != "Q4_K - Medium" {
+		t.Fatal("unexpected ftype", ftype)
+	}
+
+	if f.DataOffset%DefaultAlignment != 0 || int(f.DataOffset) != len(data)-1600 {
+		t.Fatal("unexpected data offset", f.DataOffset)
+	}
+
+	for key, want := range map[string]string{
+		"test.u8": "8", "test.i8": "-8", "test.u16": "16", "test.i16": "-16", "test.i32": "-32",
+		"test.f32": "0.500000", "test.bool": "true", "test.u64": "64", "test.i64": "-64", "test.f64": "1.500000",
+		"tokenizer.ggml.tokens": `["<s>", "</s>", "a"]`, "test.ints": "[1, 2, 3]", "test.nested": "[[1], [2, 3]]",
+	} {
+		v, ok := f.Get(key)
+		if !ok || v.String() != want {
+			t.Errorf("%s is %q, expected %q", key, v, want)
+		}
+	}
+
+	tokens, _ := f.Get("tokenizer.ggml.tokens")
+	if s, ok := tokens.Strings(); !ok || tokens.Len() != 3 || s[2] != "a" || tokens.ArrayType != TypeString {
+		t.Fatal("unexpected tokens", tokens)
+	}
+
+	if v, _ := f.Get("test.i8"); func() bool { _, ok := v.Uint(); return ok }() {
+		t.Fatal("negative value returned as uint")
+	}
+
+	embd, ok := f.Tensor("token_embd.weight")
+	if !ok || embd.Type != TensorTypeQ4_K || embd.NElements() != 1024 || embd.Size() != 576 {
+		t.Fatal("unexpected tensor", embd, embd.Size())
+	}
+
+	if embd.String() != "token_embd.weight q4_K [256 4]" {
+		t.Fatal("unexpected tensor string", embd)
+	}
+
+	if typ, err := ParseTensorType("Q4_K"); err != nil || typ != TensorTypeQ4_K {
+		t.Fatal("unexpected tensor type", typ, err)
+	}
+
+	if f.NParams() != 1280 {
+		t.Fatal("unexpected number of params", f.NParams())
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.gguf")
+	if err := os.WriteFile(path, testModel(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Open(path)
+	if err != nil {
+		t.Fatal("unable to open file", err)
+	}
+	defer f.Close()
+
+	norm, _ := f.Tensor("output_norm.weight")
+	r, err := f.TensorReader(norm)
+	if err != nil {
+		t.Fatal("unable to read tensor", err)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(data, bytes.Repeat([]byte{2}, 1024)) {
+		t.Fatal("unexpected tensor data", len(data), err)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	data := testModel()
+
+	if _, err := Read(bytes.NewReader([]byte("GGML\x03\x00\x00\x00"))); !errors.Is(err, ErrInvalid) {
+		t.Fatal("expected ErrInvalid for bad magic", err)
+	}
+
+	v1 := bytes.Clone(data)
+	v1[4] = 1
+	if _, err := Read(bytes.NewReader(v1)); !errors.Is(err, ErrVersion) {
+		t.Fatal("expected ErrVersion", err)
+	}
+
+	header := len(data) - 1600
+	for _, n := range []int{0, 6, 30, 200, header - 40} {
+		if _, err := Read(bytes.NewReader(data[:n])); !errors.Is(err, ErrInvalid) {
+			t.Fatal("expected ErrInvalid for truncated file", n, err)
+		}
+	}
+
+	huge := (&testFile{}).put(uint32(Magic), uint32(3), uint64(0), uint64(1), "key", TypeString, uint64(1<<62))
+	if _, err := Read(bytes.NewReader(huge.Bytes())); !errors.Is(err, ErrInvalid) {
+		t.Fatal("expected ErrInvalid for huge string", err)
+	}
+}
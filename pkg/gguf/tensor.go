@@ -0,0 +1,158 @@
+package gguf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TensorType is the ggml_type of the data in a tensor.
+type TensorType uint32
+
+const (
+	TensorTypeF32     TensorType = 0
+	TensorTypeF16     TensorType = 1
+	TensorTypeQ4_0    TensorType = 2
+	TensorTypeQ4_1    TensorType = 3
+	TensorTypeQ5_0    TensorType = 6
+	TensorTypeQ5_1    TensorType = 7
+	TensorTypeQ8_0    TensorType = 8
+	TensorTypeQ8_1    TensorType = 9
+	TensorTypeQ2_K    TensorType = 10
+	TensorTypeQ3_K    TensorType = 11
+	TensorTypeQ4_K    TensorType = 12
+	TensorTypeQ5_K    TensorType = 13
+	TensorTypeQ6_K    TensorType = 14
+	TensorTypeQ8_K    TensorType = 15
+	TensorTypeIQ2_XXS TensorType = 16
+	TensorTypeIQ2_XS  TensorType = 17
+	TensorTypeIQ3_XXS TensorType = 18
+	TensorTypeIQ1_S   TensorType = 19
+	TensorTypeIQ4_NL  TensorType = 20
+	TensorTypeIQ3_S   TensorType = 21
+	TensorTypeIQ2_S   TensorType = 22
+	TensorTypeIQ4_XS  TensorType = 23
+	TensorTypeI8      TensorType = 24
+	TensorTypeI16     TensorType = 25
+	TensorTypeI32     TensorType = 26
+	TensorTypeI64     TensorType = 27
+	TensorTypeF64     TensorType = 28
+	TensorTypeIQ1_M   TensorType = 29
+	TensorTypeBF16    TensorType = 30
+	TensorTypeTQ1_0   TensorType = 34
+	TensorTypeTQ2_0   TensorType = 35
+	TensorTypeMXFP4   TensorType = 39
+)
+
+// tensorTypeTraits are the name, the number of elements in a block, and the size of a block in bytes
+// for each TensorType.
+var tensorTypeTraits = map[TensorType]struct {
+	name      string
+	blockSize uint64
+	typeSize  uint64
+}{
+	TensorTypeF32:     {"f32", 1, 4},
+	TensorTypeF16:     {"f16", 1, 2},
+	TensorTypeQ4_0:    {"q4_0", 32, 18},
+	TensorTypeQ4_1:    {"q4_1", 32, 20},
+	TensorTypeQ5_0:    {"q5_0", 32, 22},
+	TensorTypeQ5_1:    {"q5_1", 32, 24},
+	TensorTypeQ8_0:    {"q8_0", 32, 34},
+	TensorTypeQ8_1:    {"q8_1", 32, 36},
+	TensorTypeQ2_K:    {"q2_K", 256, 84},
+	TensorTypeQ3_K:    {"q3_K", 256, 110},
+	TensorTypeQ4_K:    {"q4_K", 256, 144},
+	TensorTypeQ5_K:    {"q5_K", 256, 176},
+	TensorTypeQ6_K:    {"q6_K", 256, 210},
+	TensorTypeQ8_K:    {"q8_K", 256, 292},
+	TensorTypeIQ2_XXS: {"iq2_xxs", 256, 66},
+	TensorTypeIQ2_XS:  {"iq2_xs", 256, 74},
+	TensorTypeIQ3_XXS: {"iq3_xxs", 256, 98},
+	TensorTypeIQ1_S:   {"iq1_s", 256, 50},
+	TensorTypeIQ4_NL:  {"iq4_nl", 32, 18},
+	TensorTypeIQ3_S:   {"iq3_s", 256, 110},
+	TensorTypeIQ2_S:   {"iq2_s", 256, 82},
+	TensorTypeIQ4_XS:  {"iq4_xs", 256, 136},
+	TensorTypeI8:      {"i8", 1, 1},
+	TensorTypeI16:     {"i16", 1, 2},
+	TensorTypeI32:     {"i32", 1, 4},
+	TensorTypeI64:     {"i64", 1, 8},
+	TensorTypeF64:     {"f64", 1, 8},
+	TensorTypeIQ1_M:   {"iq1_m", 256, 56},
+	TensorTypeBF16:    {"bf16", 1, 2},
+	TensorTypeTQ1_0:   {"tq1_0", 256, 54},
+	TensorTypeTQ2_0:   {"tq2_0", 256, 66},
+	TensorTypeMXFP4:   {"mxfp4", 32, 17},
+}
+
+// String returns the name of the type, the same as ggml_type_name.
+func (t TensorType) String() string {
+	if traits, ok := tensorTypeTraits[t]; ok {
+		return traits.name
+	}
+
+	return "type(" + strconv.Itoa(int(t)) + ")"
+}
+
+// BlockSize returns the number of elements in each block of the type, or 0 if the type is unknown.
+func (t TensorType) BlockSize() uint64 {
+	return tensorTypeTraits[t].blockSize
+}
+
+// TypeSize returns the size in bytes of each block of the type, or 0 if the type is unknown.
+func (t TensorType) TypeSize() uint64 {
+	return tensorTypeTraits[t].typeSize
+}
+
+// TensorInfo describes a tensor in a GGUF file.
+type TensorInfo struct {
+	Name string
+
+	// Dims are the number of elements in each dimension, starting with the innermost.
+	Dims []uint64
+
+	Type TensorType
+
+	// Offset is the offset of the tensor data from [File.DataOffset].
+	Offset uint64
+}
+
+// NElements returns the total number of elements in the tensor.
+func (t TensorInfo) NElements() uint64 {
+	n := uint64(1)
+	for _, d := range t.Dims {
+		n *= d
+	}
+
+	return n
+}
+
+// Size returns the size of the tensor data in bytes, or 0 if the type is unknown.
+func (t TensorInfo) Size() uint64 {
+	if t.Type.BlockSize() == 0 {
+		return 0
+	}
+
+	return t.NElements() / t.Type.BlockSize() * t.Type.TypeSize()
+}
+
+// String returns the name, type and shape of the tensor, for example "token_embd.weight q4_K [576 49152]".
+func (t TensorInfo) String() string {
+	dims := make([]string, len(t.Dims))
+	for i, d := range t.Dims {
+		dims[i] = strconv.FormatUint(d, 10)
+	}
+
+	return t.Name + " " + t.Type.String() + " [" + strings.Join(dims, " ") + "]"
+}
+
+// ParseTensorType returns the TensorType with a name such as "q8_0" or "f16", as returned by [TensorType.String].
+func ParseTensorType(name string) (TensorType, error) {
+	for t, traits := range tensorTypeTraits {
+		if strings.EqualFold(traits.name, name) {
+			return t, nil
+		}
+	}
+
+	return 0, fmt.Errorf("gguf: unknown tensor type %q", name)
+}
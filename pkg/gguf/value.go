@@ -0,0 +1,312 @@
+package gguf
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ValueType is the type of a metadata value.
+type ValueType uint32
+
+const (
+	TypeUint8 ValueType = iota
+	TypeInt8
+	TypeUint16
+	TypeInt16
+	TypeUint32
+	TypeInt32
+	TypeFloat32
+	TypeBool
+	TypeString
+	TypeArray
+	TypeUint64
+	TypeInt64
+	TypeFloat64
+)
+
+var valueTypeNames = []string{"u8", "i8", "u16", "i16", "u32", "i32", "f32", "bool", "str", "arr", "u64", "i64", "f64"}
+
+func (t ValueType) String() string {
+	if int(t) < len(valueTypeNames) {
+		return valueTypeNames[t]
+	}
+
+	return "type(" + strconv.Itoa(int(t)) + ")"
+}
+
+// Value is a metadata value. Scalars are stored as the Go type with the same size, such as
+// uint32 for [TypeUint32], and arrays as a slice of that type, or []Value for arrays of arrays.
+type Value struct {
+	Type ValueType
+
+	// ArrayType is the type of the elements when Type is [TypeArray].
+	ArrayType ValueType
+
+	data any
+}
+
+// Any returns the Go value, such as a uint32 or a []string.
+func (v Value) Any() any {
+	return v.data
+}
+
+// Uint returns the value as a uint64 if it is an integer that is not negative.
+func (v Value) Uint() (uint64, bool) {
+	switch x := v.data.(type) {
+	case uint8:
+		return uint64(x), true
+	case uint16:
+		return uint64(x), true
+	case uint32:
+		return uint64(x), true
+	case uint64:
+		return x, true
+	}
+
+	if i, ok := v.Int(); ok && i >= 0 {
+		return uint64(i), true
+	}
+
+	return 0, false
+}
+
+// Int returns the value as an int64 if it is an integer that fits.
+func (v Value) Int() (int64, bool) {
+	switch x := v.data.(type) {
+	case int8:
+		return int64(x), true
+	case int16:
+		return int64(x), true
+	case int32:
+		return int64(x), true
+	case int64:
+		return x, true
+	case uint8:
+		return int64(x), true
+	case uint16:
+		return int64(x), true
+	case uint32:
+		return int64(x), true
+	case uint64:
+		if x <= math.MaxInt64 {
+			return int64(x), true
+		}
+	}
+
+	return 0, false
+}
+
+// Float returns the value as a float64 if it is a number.
+func (v Value) Float() (float64, bool) {
+	switch x := v.data.(type) {
+	case float32:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+
+	if i, ok := v.Int(); ok {
+		return float64(i), true
+	}
+
+	if u, ok := v.Uint(); ok {
+		return float64(u), true
+	}
+
+	return 0, false
+}
+
+// Str returns the value if it is a string.
+func (v Value) Str() (string, bool) {
+	s, ok := v.data.(string)
+	return s, ok
+}
+
+// Bool returns the value if it is a bool.
+func (v Value) Bool() (bool, bool) {
+	b, ok := v.data.(bool)
+	return b, ok
+}
+
+// Strings returns the value if it is an array of strings.
+func (v Value) Strings() ([]string, bool) {
+	s, ok := v.data.([]string)
+	return s, ok
+}
+
+// Len returns the number of elements in an array, or 0 if the value is not an array.
+func (v Value) Len() int {
+	if v.Type != TypeArray {
+		return 0
+	}
+
+	switch x := v.data.(type) {
+	case []uint8:
+		return len(x)
+	case []int8:
+		return len(x)
+	case []uint16:
+		return len(x)
+	case []int16:
+		return len(x)
+	case []uint32:
+		return len(x)
+	case []int32:
+		return len(x)
+	case []float32:
+		return len(x)
+	case []bool:
+		return len(x)
+	case []string:
+		return len(x)
+	case []uint64:
+		return len(x)
+	case []int64:
+		return len(x)
+	case []float64:
+		return len(x)
+	case []Value:
+		return len(x)
+	}
+
+	return 0
+}
+
+// String formats the value the same way as llama.cpp does for the metadata of a model.
+// Strings are returned as is, and arrays have each element separated by commas.
+func (v Value) String() string {
+	switch x := v.data.(type) {
+	case string:
+		return x
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', 6, 32)
+	case float64:
+		return strconv.FormatFloat(x, 'f', 6, 64)
+	case []string:
+		quoted := make([]string, len(x))
+		for i, s := range x {
+			quoted[i] = strconv.Quote(s)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	case []Value:
+		elems := make([]string, len(x))
+		for i, e := range x {
+			elems[i] = e.String()
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case []float32, []float64:
+		s := fmt.Sprintf("%v", x)
+		return "[" + strings.ReplaceAll(s[1:len(s)-1], " ", ", ") + "]"
+	}
+
+	if v.Type == TypeArray {
+		s := fmt.Sprint(v.data)
+		return "[" + strings.ReplaceAll(s[1:len(s)-1], " ", ", ") + "]"
+	}
+
+	return fmt.Sprint(v.data)
+}
+
+// NewValue returns a Value for a Go value. v must be one of the Go types that matches a [ValueType],
+// such as uint32 or string, a slice of one of them for an array, or a []Value of arrays.
+func NewValue(v any) (Value, error) {
+	var t ValueType
+	switch v.(type) {
+	case uint8, []uint8:
+		t = TypeUint8
+	case int8, []int8:
+		t = TypeInt8
+	case uint16, []uint16:
+		t = TypeUint16
+	case int16, []int16:
+		t = TypeInt16
+	case uint32, []uint32:
+		t = TypeUint32
+	case int32, []int32:
+		t = TypeInt32
+	case float32, []float32:
+		t = TypeFloat32
+	case bool, []bool:
+		t = TypeBool
+	case string, []string:
+		t = TypeString
+	case uint64, []uint64:
+		t = TypeUint64
+	case int64, []int64:
+		t = TypeInt64
+	case float64, []float64:
+		t = TypeFloat64
+	case []Value:
+		for _, e := range v.([]Value) {
+			if e.Type != TypeArray {
+				return Value{}, fmt.Errorf("gguf: array of %s values, only arrays of arrays are supported", e.Type)
+			}
+		}
+		return Value{Type: TypeArray, ArrayType: TypeArray, data: v}, nil
+	default:
+		return Value{}, fmt.Errorf("gguf: unsupported value type %T", v)
+	}
+
+	if reflect.ValueOf(v).Kind() == reflect.Slice {
+		return Value{Type: TypeArray, ArrayType: t, data: v}, nil
+	}
+
+	return Value{Type: t, data: v}, nil
+}
+
+// ParseValue parses s as a value of type t, for example from a command line.
+// Arrays are not supported.
+func ParseValue(t ValueType, s string) (Value, error) {
+	var (
+		v   any
+		err error
+	)
+
+	switch t {
+	case TypeString:
+		v = s
+	case TypeBool:
+		v, err = strconv.ParseBool(s)
+	case TypeFloat32:
+		var f float64
+		f, err = strconv.ParseFloat(s, 32)
+		v = float32(f)
+	case TypeFloat64:
+		v, err = strconv.ParseFloat(s, 64)
+	case TypeUint8, TypeUint16, TypeUint32, TypeUint64:
+		var u uint64
+		u, err = strconv.ParseUint(s, 0, typeBits[t])
+		v = map[ValueType]any{TypeUint8: uint8(u), TypeUint16: uint16(u), TypeUint32: uint32(u), TypeUint64: u}[t]
+	case TypeInt8, TypeInt16, TypeInt32, TypeInt64:
+		var i int64
+		i, err = strconv.ParseInt(s, 0, typeBits[t])
+		v = map[ValueType]any{TypeInt8: int8(i), TypeInt16: int16(i), TypeInt32: int32(i), TypeInt64: i}[t]
+	default:
+		return Value{}, fmt.Errorf("gguf: unable to parse a value of type %s", t)
+	}
+
+	if err != nil {
+		return Value{}, fmt.Errorf("gguf: invalid %s value: %w", t, err)
+	}
+
+	return Value{Type: t, data: v}, nil
+}
+
+// ParseValueType returns the ValueType with a name such as "str" or "u32", as returned by [ValueType.String].
+func ParseValueType(name string) (ValueType, error) {
+	for i, n := range valueTypeNames {
+		if n == name {
+			return ValueType(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("gguf: unknown value type %q", name)
+}
+
+var typeBits = map[ValueType]int{
+	TypeUint8: 8, TypeInt8: 8, TypeUint16: 16, TypeInt16: 16,
+	TypeUint32: 32, TypeInt32: 32, TypeUint64: 64, TypeInt64: 64,
+}
@@ -0,0 +1,129 @@
+package gguf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Set sets the value of a metadata key, adding the key at the end if the file does not have it.
+func (f *File) Set(key string, v Value) {
+	for i := range f.Metadata {
+		if f.Metadata[i].Key == key {
+			f.Metadata[i].Value = v
+			return
+		}
+	}
+
+	f.Metadata = append(f.Metadata, KV{Key: key, Value: v})
+}
+
+// Delete removes a metadata key, and reports whether the file had it.
+func (f *File) Delete(key string) bool {
+	n := len(f.Metadata)
+	f.Metadata = slices.DeleteFunc(f.Metadata, func(kv KV) bool { return kv.Key == key })
+
+	return len(f.Metadata) != n
+}
+
+// WriteTo writes the file to w, with the current metadata. The tensor data is copied unchanged
+// from the file that it was opened from, and stays aligned to [File.Alignment]. If the alignment
+// is not [DefaultAlignment], general.alignment is written even if it has been deleted, as the
+// tensor data could not be read without it.
+func (f *File) WriteTo(w io.Writer) (int64, error) {
+	metadata := f.Metadata
+	if v, ok := f.Get(KeyAlignment); ok {
+		if align, _ := v.Uint(); align != f.Alignment {
+			return 0, fmt.Errorf("gguf: unable to change %s from %d", KeyAlignment, f.Alignment)
+		}
+	} else if f.Alignment != DefaultAlignment {
+		if f.Alignment > math.MaxUint32 {
+			return 0, fmt.Errorf("%w: alignment %d", ErrInvalid, f.Alignment)
+		}
+
+		metadata = append(slices.Clip(metadata), KV{Key: KeyAlignment, Value: Value{Type: TypeUint32, data: uint32(f.Alignment)}})
+	}
+
+	if len(f.Tensors) > 0 && f.r == nil {
+		return 0, errors.New("gguf: tensor data is only available for files opened with Open")
+	}
+
+	if len(f.Tensors) > 0 && f.size < f.DataOffset {
+		return 0, fmt.Errorf("%w: missing tensor data", ErrInvalid)
+	}
+
+	e := newEncoder(w)
+	e.uint32(Magic)
+	e.uint32(f.Version)
+	e.uint64(uint64(len(f.Tensors)))
+	e.uint64(uint64(len(metadata)))
+
+	for _, kv := range metadata {
+		e.string(kv.Key)
+		e.uint32(uint32(kv.Value.Type))
+		e.value(kv.Value)
+	}
+
+	for _, t := range f.Tensors {
+		e.tensorInfo(t)
+	}
+
+	if len(f.Tensors) > 0 {
+		e.pad(f.Alignment)
+
+		if e.err == nil {
+			var n int64
+			n, e.err = io.Copy(e.w, io.NewSectionReader(f.r, f.DataOffset, f.size-f.DataOffset))
+			e.off += n
+		}
+	}
+
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+
+	return e.off, e.err
+}
+
+// Rewrite copies the GGUF file at src to dst, after calling edit to change its metadata.
+// The tensor data is copied unchanged. dst can be the same as src, as the new file is written to
+// a temporary file that replaces dst once it is complete.
+func Rewrite(src, dst string, edit func(f *File) error) error {
+	f, err := Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := edit(f); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	_, err = f.WriteTo(tmp)
+	err = errors.Join(err, tmp.Close(), f.Close())
+	if err != nil {
+		return fmt.Errorf("%s: %w", dst, err)
+	}
+
+	return os.Rename(tmp.Name(), dst)
+}
@@ -0,0 +1,204 @@
+package gguf
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.gguf")
+	if err := os.WriteFile(path, testModel(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Open(path)
+	if err != nil {
+		t.Fatal("unable to open file", err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal("unable to write file", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), testModel()) {
+		t.Fatal("file without changes is not identical")
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	dir := t.TempDir()
+	src, dst := filepath.Join(dir, "test.gguf"), filepath.Join(dir, "fixed.gguf")
+	if err := os.WriteFile(src, testModel(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	template := "{% for message in messages %}{{ message['content'] }}{% endfor %}"
+	err := Rewrite(src, dst, func(f *File) error {
+		v, err := NewValue(template)
+		if err != nil {
+			return err
+		}
+		f.Set(KeyChatTemplate, v)
+
+		eos, err := ParseValue(TypeUint32, "2")
+		if err != nil {
+			return err
+		}
+		f.Set("tokenizer.ggml.eos_token_id", eos)
+
+		if !f.Delete("test.u8") {
+			t.Error("key not deleted")
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal("unable to rewrite file", err)
+	}
+
+	f, err := Open(dst)
+	if err != nil {
+		t.Fatal("unable to open rewritten file", err)
+	}
+	defer f.Close()
+
+	if s, _ := f.GetString(KeyChatTemplate); s != template {
+		t.Fatal("chat template not set", s)
+	}
+
+	if v, _ := f.Get("tokenizer.ggml.eos_token_id"); v.Type != TypeUint32 || v.String() != "2" {
+		t.Fatal("eos token not set", v)
+	}
+
+	if _, ok := f.Get("test.u8"); ok {
+		t.Fatal("deleted key still in file")
+	}
+
+	if f.DataOffset%int64(f.Alignment) != 0 {
+		t.Fatal("tensor data not aligned", f.DataOffset)
+	}
+
+	orig, err := Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer orig.Close()
+
+	for i, ti := range f.Tensors {
+		if ti.String() != orig.Tensors[i].String() || ti.Offset != orig.Tensors[i].Offset {
+			t.Fatal("tensor info changed", ti, orig.Tensors[i])
+		}
+
+		want, _ := orig.TensorReader(orig.Tensors[i])
+		got, _ := f.TensorReader(ti)
+		a, _ := io.ReadAll(want)
+		b, _ := io.ReadAll(got)
+		if !bytes.Equal(a, b) {
+			t.Fatal("tensor data changed", ti.Name)
+		}
+	}
+}
+
+func TestRewriteInPlace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.gguf")
+	if err := os.WriteFile(path, testModel(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Rewrite(path, path, func(f *File) error {
+		f.Set(KeyName, Value{Type: TypeString, data: "Renamed"})
+		return nil
+	})
+	if err != nil {
+		t.Fatal("unable to rewrite file", err)
+	}
+
+	f, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if f.Name() != "Renamed" || len(f.Tensors) != 2 {
+		t.Fatal("file not rewritten", f.Name())
+	}
+
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Fatal("temporary file left behind", entries)
+	}
+}
+
+func TestRewriteAlignment(t *testing.T) {
+	b := &testFile{}
+	b.put(uint32(Magic), uint32(3), uint64(1), uint64(2))
+	b.put(KeyAlignment, TypeUint32, uint32(64))
+	b.put(KeyName, TypeString, "Aligned")
+	b.put("output_norm.weight", uint32(1), uint64(64), TensorTypeF32, uint64(0))
+	for b.Len()%64 != 0 {
+		b.WriteByte(0)
+	}
+	b.Write(bytes.Repeat([]byte{3}, 256))
+
+	path := filepath.Join(t.TempDir(), "aligned.gguf")
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Rewrite(path, path, func(f *File) error {
+		if !f.Delete(KeyAlignment) {
+			t.Error("alignment not deleted")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal("unable to rewrite file", err)
+	}
+
+	f, err := Open(path)
+	if err != nil {
+		t.Fatal("unable to open rewritten file", err)
+	}
+	defer f.Close()
+
+	v, _ := f.Get(KeyAlignment)
+	if align, _ := v.Uint(); f.Alignment != 64 || align != 64 {
+		t.Fatal("alignment should be kept", f.Alignment, align)
+	}
+
+	if f.DataOffset%64 != 0 || f.Name() != "Aligned" {
+		t.Fatal("tensor data not aligned", f.DataOffset)
+	}
+
+	r, err := f.TensorReader(f.Tensors[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := io.ReadAll(r); !bytes.Equal(data, bytes.Repeat([]byte{3}, 256)) {
+		t.Fatal("tensor data changed", data)
+	}
+}
+
+func TestNewValue(t *testing.T) {
+	v, err := NewValue([]string{"a", "b"})
+	if err != nil || v.Type != TypeArray || v.ArrayType != TypeString || v.Len() != 2 {
+		t.Fatal("unexpected array value", v, err)
+	}
+
+	if _, err := NewValue(42); err == nil {
+		t.Fatal("expected error for int")
+	}
+
+	if _, err := ParseValue(TypeUint8, "300"); err == nil {
+		t.Fatal("expected error for out of range value")
+	}
+
+	if typ, err := ParseValueType("i32"); err != nil || typ != TypeInt32 {
+		t.Fatal("unexpected value type", typ, err)
+	}
+}
@@ -0,0 +1,166 @@
+package llama
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/hybridgroup/yzma/pkg/loader"
+)
+
+// ABIError is returned by [Load] when the structs returned by the llama.cpp library do not match
+// the layout of the Go structs, which usually means that the library is a newer or older version
+// than the one that yzma was written for.
+type ABIError struct {
+	// Struct is the name of the C struct that does not match.
+	Struct string
+
+	// Problems describes each of the fields with an unexpected value.
+	Problems []string
+
+	// Version is the version of the library, if it reports one.
+	Version string
+}
+
+func (e *ABIError) Error() string {
+	msg := fmt.Sprintf("llama: %s does not match the layout that yzma expects: %s",
+		e.Struct, strings.Join(e.Problems, "; "))
+	if e.Version != "" {
+		msg += " (library version " + e.Version + ")"
+	}
+
+	return msg + ". Install a version of llama.cpp that is supported by this version of yzma"
+}
+
+// checkABI calls the default params functions of the library and checks that the structs they
+// return have the expected size and sensible values in every field.
+func checkABI() error {
+	mp, err := loader.CallStruct[ModelParams]("llama_model_params", func(p unsafe.Pointer) {
+		modelDefaultParamsFunc.Call(p)
+	})
+	if err != nil {
+		return abiError("llama_model_params", []string{err.Error()})
+	}
+
+	if problems := checkModelParams(mp); len(problems) > 0 {
+		return abiError("llama_model_params", problems)
+	}
+
+	cp, err := loader.CallStruct[ContextParams]("llama_context_params", func(p unsafe.Pointer) {
+		contextDefaultParamsFunc.Call(p)
+	})
+	if err != nil {
+		return abiError("llama_context_params", []string{err.Error()})
+	}
+
+	if problems := checkContextParams(cp); len(problems) > 0 {
+		return abiError("llama_context_params", problems)
+	}
+
+	return nil
+}
+
+// checkQuantizeABI calls llama_model_quantize_default_params and checks that the struct it returns
+// has the expected size and values. It is only checked before a model is first quantized, so that
+// a mismatch does not stop the library from being used for anything else.
+func checkQuantizeABI() error {
+	qp, err := loader.CallStruct[ModelQuantizeParams]("llama_model_quantize_params", func(p unsafe.Pointer) {
+		modelQuantizeDefaultParamsFunc.Call(p)
+	})
+	if err != nil {
+		return abiError("llama_model_quantize_params", []string{err.Error()})
+	}
+
+	if problems := checkModelQuantizeParams(qp); len(problems) > 0 {
+		return abiError("llama_model_quantize_params", problems)
+	}
+
+	return nil
+}
+
+func abiError(name string, problems []string) *ABIError {
+	version, _ := Version()
+	return &ABIError{Struct: name, Problems: problems, Version: version}
+}
+
+// checkModelParams returns a description of every field in the default model params that does not
+// have the value that it has in llama.cpp.
+func checkModelParams(p ModelParams) []string {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(p.Devices == 0, "devices is %#x, expected NULL", p.Devices)
+	check(p.ProgressCallback == 0, "progress_callback is %#x, expected NULL", p.ProgressCallback)
+	check(p.KvOverrides == 0, "kv_overrides is %#x, expected NULL", p.KvOverrides)
+	check(p.SplitMode >= SPLIT_MODE_NONE && p.SplitMode <= SPLIT_MODE_ROW, "split_mode is %d", p.SplitMode)
+	check(p.VocabOnly == 0, "vocab_only is %d, expected false", p.VocabOnly)
+	check(p.UseMmap == 1, "use_mmap is %d, expected true", p.UseMmap)
+
+	check(p.UseMlock <= 1, "use_mlock is %d, expected a bool", p.UseMlock)
+	check(p.CheckTensors <= 1, "check_tensors is %d, expected a bool", p.CheckTensors)
+	check(p.UseExtraBufts <= 1, "use_extra_bufts is %d, expected a bool", p.UseExtraBufts)
+
+	return problems
+}
+
+// checkContextParams returns a description of every field in the default context params that does not
+// have the value that it has in llama.cpp.
+func checkContextParams(p ContextParams) []string {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(p.NSeqMax == 1, "n_seq_max is %d, expected 1", p.NSeqMax)
+	check(p.NUbatch > 0 && p.NBatch >= p.NUbatch, "n_batch is %d and n_ubatch is %d", p.NBatch, p.NUbatch)
+	check(p.RopeScalingType >= ROPE_SCALING_TYPE_UNSPECIFIED && p.RopeScalingType <= ROPE_SCALING_TYPE_LONGROPE,
+		"rope_scaling_type is %d", p.RopeScalingType)
+	check(p.PoolingType >= POOLING_TYPE_UNSPECIFIED && p.PoolingType <= POOLING_TYPE_RANK, "pooling_type is %d", p.PoolingType)
+	check(p.AttentionType >= ATTENTION_TYPE_UNSPECIFIED && p.AttentionType <= ATTENTION_TYPE_NON_CAUSAL,
+		"attention_type is %d", p.AttentionType)
+	check(p.FlashAttentionType >= LLAMA_FLASH_ATTN_TYPE_AUTO && p.FlashAttentionType <= LLAMA_FLASH_ATTN_TYPE_ENABLED,
+		"flash_attn_type is %d", p.FlashAttentionType)
+	check(p.CbEval == 0, "cb_eval is %#x, expected NULL", p.CbEval)
+	check(p.AbortCallback == 0, "abort_callback is %#x, expected NULL", p.AbortCallback)
+	check(p.TypeK >= 0 && p.TypeK < 64, "type_k is %d", p.TypeK)
+	check(p.TypeV >= 0 && p.TypeV < 64, "type_v is %d", p.TypeV)
+
+	check(p.Embeddings <= 1, "embeddings is %d, expected a bool", p.Embeddings)
+	check(p.Offload_kqv <= 1, "offload_kqv is %d, expected a bool", p.Offload_kqv)
+	check(p.NoPerf <= 1, "no_perf is %d, expected a bool", p.NoPerf)
+	check(p.OpOffload <= 1, "op_offload is %d, expected a bool", p.OpOffload)
+	check(p.SwaFull <= 1, "swa_full is %d, expected a bool", p.SwaFull)
+	check(p.KVUnified <= 1, "kv_unified is %d, expected a bool", p.KVUnified)
+
+	return problems
+}
+
+// checkModelQuantizeParams returns a description of every field in the default quantize params that does not
+// have the value that it has in llama.cpp.
+func checkModelQuantizeParams(p ModelQuantizeParams) []string {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(p.Ftype == FTYPE_MOSTLY_Q5_1, "ftype is %d, expected %d", p.Ftype, FTYPE_MOSTLY_Q5_1)
+	check(p.OutputTensorType > 0 && p.OutputTensorType < 64, "output_tensor_type is %d", p.OutputTensorType)
+	check(p.TokenEmbeddingType == p.OutputTensorType, "token_embedding_type is %d", p.TokenEmbeddingType)
+	check(p.QuantizeOutputTensor == 1, "quantize_output_tensor is %d, expected true", p.QuantizeOutputTensor)
+	check(p.AllowRequantize <= 1, "allow_requantize is %d, expected a bool", p.AllowRequantize)
+	check(p.OnlyCopy <= 1, "only_copy is %d, expected a bool", p.OnlyCopy)
+	check(p.Pure <= 1, "pure is %d, expected a bool", p.Pure)
+	check(p.KeepSplit <= 1, "keep_split is %d, expected a bool", p.KeepSplit)
+	check(p.IMatrix == 0 && p.KvOverrides == 0 && p.TensorTypes == 0 && p.PruneLayers == 0,
+		"imatrix, kv_overrides, tensor_types or prune_layers are not NULL")
+
+	return problems
+}
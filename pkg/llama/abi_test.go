@@ -0,0 +1,80 @@
+package llama
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hybridgroup/yzma/pkg/fake"
+)
+
+func TestCheckABI(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	if err := checkABI(); err != nil {
+		t.Fatal("default params do not match", err)
+	}
+
+	if err := checkQuantizeABI(); err != nil {
+		t.Fatal("default quantize params do not match", err)
+	}
+
+	if PrintSystemInfo() == "" {
+		t.Fatal("empty system info")
+	}
+}
+
+func TestVersion(t *testing.T) {
+	if err := Load(fake.New()); err != nil {
+		t.Fatal("unable to load library", err)
+	}
+
+	version, err := Version()
+	if err != nil {
+		t.Fatal("unable to get version", err)
+	}
+
+	if version != fake.Version+" (fake)" {
+		t.Fatal("unexpected version", version)
+	}
+
+	if err := Load(fake.New().Omit("ggml_version", "ggml_commit")); err != nil {
+		t.Fatal("unable to load library", err)
+	}
+
+	if _, err := Version(); !errors.Is(err, ErrNotSupported) {
+		t.Fatal("expected ErrNotSupported", err)
+	}
+}
+
+func TestCheckModelParams(t *testing.T) {
+	p := ModelParams{UseMmap: 1, SplitMode: SPLIT_MODE_LAYER}
+	if problems := checkModelParams(p); len(problems) > 0 {
+		t.Fatal("unexpected problems", problems)
+	}
+
+	// a new field shifts the bools along by one
+	p.UseMmap, p.UseMlock, p.SplitMode = 0, 1, 7
+	problems := checkModelParams(p)
+	if len(problems) != 2 {
+		t.Fatal("expected 2 problems", problems)
+	}
+
+	err := &ABIError{Struct: "llama_model_params", Problems: problems, Version: "0.9.4"}
+	if !strings.Contains(err.Error(), "split_mode is 7") || !strings.Contains(err.Error(), "0.9.4") {
+		t.Fatal("error does not describe the problems", err)
+	}
+}
+
+func TestCheckContextParams(t *testing.T) {
+	p := ContextParams{NBatch: 2048, NUbatch: 512, NSeqMax: 1, TypeK: 1, TypeV: 1, NoPerf: 1}
+	if problems := checkContextParams(p); len(problems) > 0 {
+		t.Fatal("unexpected problems", problems)
+	}
+
+	p.NUbatch, p.NoPerf, p.AbortCallback = 4096, 2, 0x1234
+	if problems := checkContextParams(p); len(problems) != 3 {
+		t.Fatal("expected 3 problems", problems)
+	}
+}
@@ -0,0 +1,77 @@
+package llama
+
+import "fmt"
+
+// LoadModel loads a Model from a GGUF file. Unlike [ModelLoadFromFile], it returns an error
+// wrapping [ErrModelLoad] if the model cannot be loaded. Call [Model.Close] when done with it.
+func LoadModel(path string, params ModelParams) (Model, error) {
+	model := ModelLoadFromFile(path, params)
+	if model == 0 {
+		return 0, fmt.Errorf("%w %q", ErrModelLoad, path)
+	}
+
+	return model, nil
+}
+
+// Close frees the Model and sets it to 0, so that closing it again does nothing. Copies of
+// the Model must not be used or closed after it is closed.
+func (m *Model) Close() error {
+	if *m == 0 {
+		return nil
+	}
+
+	ModelFree(*m)
+	*m = 0
+	return nil
+}
+
+// NewContext creates a new Context for the Model. Unlike [InitFromModel], it returns an error
+// wrapping [ErrContextInit] if the context cannot be created. Call [Context.Close] when done with it.
+func NewContext(model Model, params ContextParams) (Context, error) {
+	if model == 0 {
+		return 0, fmt.Errorf("%w: model is not loaded", ErrContextInit)
+	}
+
+	ctx := InitFromModel(model, params)
+	if ctx == 0 {
+		return 0, fmt.Errorf("%w with n_ctx %d", ErrContextInit, params.NCtx)
+	}
+
+	return ctx, nil
+}
+
+// Close frees the Context and sets it to 0, so that closing it again does nothing. Copies of
+// the Context must not be used or closed after it is closed.
+func (c *Context) Close() error {
+	if *c == 0 {
+		return nil
+	}
+
+	Free(*c)
+	*c = 0
+	return nil
+}
+
+// Decode decodes a batch of Token. It returns a [*DecodeError] that wraps [ErrNoKVSlot],
+// [ErrAborted], [ErrInvalidBatch], [ErrAlloc] or [ErrCompute] if the batch cannot be decoded.
+func (c Context) Decode(batch Batch) error {
+	return decodeError("decode", Decode(c, batch))
+}
+
+// Encode encodes a batch of Token. It returns a [*DecodeError] if the batch cannot be encoded.
+func (c Context) Encode(batch Batch) error {
+	return decodeError("encode", Encode(c, batch))
+}
+
+// Close frees the Sampler, along with all of the samplers in it if it is a chain.
+// Samplers that have been added to a chain are owned by it, and must not be closed. It sets
+// the Sampler to 0, so that closing it again does nothing.
+func (s *Sampler) Close() error {
+	if *s == 0 {
+		return nil
+	}
+
+	SamplerFree(*s)
+	*s = 0
+	return nil
+}
@@ -0,0 +1,118 @@
+package llama
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hybridgroup/yzma/pkg/fake"
+)
+
+func TestLoadModel(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	if _, err := LoadModel("", ModelDefaultParams()); !errors.Is(err, ErrModelLoad) {
+		t.Fatal("expected ErrModelLoad", err)
+	}
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal("unable to load model", err)
+	}
+	defer model.Close()
+
+	lctx, err := NewContext(model, ContextDefaultParams())
+	if err != nil {
+		t.Fatal("unable to create context", err)
+	}
+	defer lctx.Close()
+
+	if _, err := NewContext(0, ContextDefaultParams()); !errors.Is(err, ErrContextInit) {
+		t.Fatal("expected ErrContextInit", err)
+	}
+
+	if err := lctx.Decode(BatchGetOne([]Token{VocabBOS(ModelGetVocab(model))})); err != nil {
+		t.Fatal("unable to decode batch", err)
+	}
+
+	if err := lctx.Decode(BatchGetOne(nil)); !errors.Is(err, ErrInvalidBatch) {
+		t.Fatal("expected ErrInvalidBatch", err)
+	}
+}
+
+func TestDecodeNoKVSlot(t *testing.T) {
+	if err := Load(fake.New()); err != nil {
+		t.Fatal("unable to load library", err)
+	}
+
+	model, err := LoadModel("fake.gguf", ModelDefaultParams())
+	if err != nil {
+		t.Fatal("unable to load model", err)
+	}
+	defer model.Close()
+
+	params := ContextDefaultParams()
+	params.NCtx = 4
+
+	lctx, err := NewContext(model, params)
+	if err != nil {
+		t.Fatal("unable to create context", err)
+	}
+	defer lctx.Close()
+
+	err = lctx.Decode(BatchGetOne(make([]Token, 8)))
+	if !errors.Is(err, ErrNoKVSlot) {
+		t.Fatal("expected ErrNoKVSlot", err)
+	}
+
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) || decodeErr.Code != 1 {
+		t.Fatal("expected DecodeError with code 1", err)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	if err := decodeError("decode", 0); err != nil {
+		t.Fatal("expected nil error for 0", err)
+	}
+
+	err := decodeError("encode", -7)
+	if err.Error() != "llama: encode returned -7" {
+		t.Fatal("unexpected error message", err)
+	}
+
+	if !errors.Is(decodeError("decode", 2), ErrAborted) {
+		t.Fatal("expected ErrAborted")
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal("unable to load model", err)
+	}
+
+	lctx, err := NewContext(model, ContextDefaultParams())
+	if err != nil {
+		t.Fatal("unable to create context", err)
+	}
+
+	sampler := SamplerChainInit(SamplerChainDefaultParams())
+
+	for range 2 {
+		if err := sampler.Close(); err != nil || sampler != 0 {
+			t.Fatal("sampler not closed", sampler, err)
+		}
+
+		if err := lctx.Close(); err != nil || lctx != 0 {
+			t.Fatal("context not closed", lctx, err)
+		}
+
+		if err := model.Close(); err != nil || model != 0 {
+			t.Fatal("model not closed", model, err)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package llama
 
 import (
+	"errors"
 	"unsafe"
 
+	"github.com/hybridgroup/yzma/pkg/loader"
 	"github.com/jupiterrider/ffi"
 )
 
@@ -27,22 +29,25 @@ var (
 	batchGetOneFunc ffi.Fun
 )
 
-func loadBatchFuncs(lib ffi.Lib) error {
-	var err error
+func loadBatchFuncs(lib loader.Library) error {
+	var (
+		err  error
+		errs []error
+	)
 
 	if batchInitFunc, err = lib.Prep("llama_batch_init", &FFITypeBatch, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypeSint32); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if batchFreeFunc, err = lib.Prep("llama_batch_free", &ffi.TypeVoid, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if batchGetOneFunc, err = lib.Prep("llama_batch_get_one", &FFITypeBatch, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 // BatchInit allocates a batch of tokens on the heap that can hold a maximum of nTokens.
@@ -0,0 +1,30 @@
+package llama
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNotSupported is returned when calling a function that is not in the loaded llama.cpp library.
+var ErrNotSupported = errors.New("not supported by the loaded llama.cpp library")
+
+// Capabilities reports which optional features are supported by the loaded llama.cpp library.
+type Capabilities struct {
+	Grammar       bool // grammar constrained sampling
+	Warmup        bool // model warmup mode
+	LoRA          bool // LoRA adapters
+	ControlVector bool // control vectors
+	StateSave     bool // saving and restoring Context state, which Load requires so it is always true once loaded
+	Version       bool // reporting the library version
+}
+
+var capabilities Capabilities
+
+// GetCapabilities returns the optional features supported by the library that was passed to [Load].
+func GetCapabilities() Capabilities {
+	return capabilities
+}
+
+func notSupported(name string) error {
+	return fmt.Errorf("llama: %s: %w", name, ErrNotSupported)
+}
@@ -0,0 +1,51 @@
+package llama
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hybridgroup/yzma/pkg/fake"
+)
+
+func TestCapabilities(t *testing.T) {
+	if err := Load(fake.New().Omit("llama_sampler_init_grammar", "llama_set_warmup")); err != nil {
+		t.Fatal("missing optional functions should not be an error", err)
+	}
+
+	caps := GetCapabilities()
+	if caps.Grammar || caps.Warmup {
+		t.Fatal("missing optional functions reported as supported", caps)
+	}
+
+	if _, err := NewGrammarSampler(0, `root ::= "yes"`, "root"); !errors.Is(err, ErrNotSupported) {
+		t.Fatal("expected ErrNotSupported", err)
+	}
+
+	if SamplerInitGrammar(0, `root ::= "yes"`, "root") != 0 {
+		t.Fatal("expected no sampler when grammars are not supported")
+	}
+
+	// does nothing instead of calling a missing function
+	SetWarmup(0, true)
+
+	if err := Load(fake.New()); err != nil {
+		t.Fatal("unable to load library", err)
+	}
+
+	caps = GetCapabilities()
+	if !caps.Grammar || !caps.Warmup || !caps.StateSave {
+		t.Fatal("optional functions reported as not supported", caps)
+	}
+}
+
+func TestLoadMissingRequired(t *testing.T) {
+	err := Load(fake.New().Omit("llama_decode", "llama_vocab_bos"))
+	if err == nil {
+		t.Fatal("missing required functions should be an error")
+	}
+
+	if !strings.Contains(err.Error(), "llama_decode") || !strings.Contains(err.Error(), "llama_vocab_bos") {
+		t.Fatal("error does not report every missing function", err)
+	}
+}
@@ -3,6 +3,7 @@ package llama
 import (
 	"unsafe"
 
+	"github.com/hybridgroup/yzma/pkg/loader"
 	"github.com/hybridgroup/yzma/pkg/utils"
 	"github.com/jupiterrider/ffi"
 )
@@ -18,7 +19,7 @@ var (
 	chatApplyTemplateFunc ffi.Fun
 )
 
-func loadChatFuncs(lib ffi.Lib) error {
+func loadChatFuncs(lib loader.Library) error {
 	var err error
 	if chatApplyTemplateFunc, err = lib.Prep("llama_chat_apply_template", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeUint32,
 		&ffi.TypeUint8, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
@@ -1,8 +1,10 @@
 package llama
 
 import (
+	"errors"
 	"unsafe"
 
+	"github.com/hybridgroup/yzma/pkg/loader"
 	"github.com/jupiterrider/ffi"
 )
 
@@ -56,53 +58,138 @@ var (
 	//              llama_pos p1);
 	memorySeqRmFunc ffi.Fun
 
+	// LLAMA_API void llama_memory_seq_cp(
+	//         		llama_memory_t mem,
+	//           	llama_seq_id seq_id_src,
+	//           	llama_seq_id seq_id_dst,
+	//              llama_pos p0,
+	//              llama_pos p1);
+	memorySeqCpFunc ffi.Fun
+
+	// LLAMA_API void llama_memory_seq_keep(
+	//         		llama_memory_t mem,
+	//           	llama_seq_id seq_id);
+	memorySeqKeepFunc ffi.Fun
+
+	// LLAMA_API void llama_memory_seq_add(
+	//         		llama_memory_t mem,
+	//           	llama_seq_id seq_id,
+	//              llama_pos p0,
+	//              llama_pos p1,
+	//              llama_pos delta);
+	memorySeqAddFunc ffi.Fun
+
+	// LLAMA_API void llama_memory_seq_div(
+	//         		llama_memory_t mem,
+	//           	llama_seq_id seq_id,
+	//              llama_pos p0,
+	//              llama_pos p1,
+	//              int d);
+	memorySeqDivFunc ffi.Fun
+
+	// LLAMA_API llama_pos llama_memory_seq_pos_min(
+	//         		llama_memory_t mem,
+	//           	llama_seq_id seq_id);
+	memorySeqPosMinFunc ffi.Fun
+
+	// LLAMA_API llama_pos llama_memory_seq_pos_max(
+	//         		llama_memory_t mem,
+	//           	llama_seq_id seq_id);
+	memorySeqPosMaxFunc ffi.Fun
+
+	// LLAMA_API bool llama_memory_can_shift(llama_memory_t mem);
+	memoryCanShiftFunc ffi.Fun
+
 	// LLAMA_API void llama_synchronize(struct llama_context * ctx);
 	synchronizeFunc ffi.Fun
+
+	// LLAMA_API uint32_t llama_n_ctx(const struct llama_context * ctx);
+	nCtxFunc ffi.Fun
+
+	// LLAMA_API uint32_t llama_n_batch(const struct llama_context * ctx);
+	nBatchFunc ffi.Fun
 )
 
-func loadContextFuncs(lib ffi.Lib) error {
-	var err error
+func loadContextFuncs(lib loader.Library) error {
+	var (
+		err  error
+		errs []error
+	)
 	if contextDefaultParamsFunc, err = lib.Prep("llama_context_default_params", &FFITypeContextParams); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if freeFunc, err = lib.Prep("llama_free", &ffi.TypeVoid, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
-	if setWarmupFunc, err = lib.Prep("llama_set_warmup", &ffi.TypeVoid, &ffi.TypePointer, &ffi.TypeUint8); err != nil {
-		return err
-	}
+	setWarmupFunc, capabilities.Warmup = loader.PrepOptional(lib, "llama_set_warmup", &ffi.TypeVoid, &ffi.TypePointer, &ffi.TypeUint8)
 
 	if encodeFunc, err = lib.Prep("llama_encode", &ffi.TypeSint32, &ffi.TypePointer, &FFITypeBatch); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if decodeFunc, err = lib.Prep("llama_decode", &ffi.TypeSint32, &ffi.TypePointer, &FFITypeBatch); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if perfContextResetFunc, err = lib.Prep("llama_perf_context_reset", &ffi.TypeVoid, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if memoryClearFunc, err = lib.Prep("llama_memory_clear", &ffi.TypeVoid, &ffi.TypePointer, &ffi.TypeUint8); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if getMemoryFunc, err = lib.Prep("llama_get_memory", &ffi.TypePointer, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if memorySeqRmFunc, err = lib.Prep("llama_memory_seq_rm", &ffi.TypeUint8, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypeSint32); err != nil {
-		return err
+		errs = append(errs, err)
+	}
+
+	if memorySeqCpFunc, err = lib.Prep("llama_memory_seq_cp", &ffi.TypeVoid, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if memorySeqKeepFunc, err = lib.Prep("llama_memory_seq_keep", &ffi.TypeVoid, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if memorySeqAddFunc, err = lib.Prep("llama_memory_seq_add", &ffi.TypeVoid, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if memorySeqDivFunc, err = lib.Prep("llama_memory_seq_div", &ffi.TypeVoid, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if memorySeqPosMinFunc, err = lib.Prep("llama_memory_seq_pos_min", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if memorySeqPosMaxFunc, err = lib.Prep("llama_memory_seq_pos_max", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if memoryCanShiftFunc, err = lib.Prep("llama_memory_can_shift", &ffi.TypeUint8, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
 	}
 
 	if synchronizeFunc, err = lib.Prep("llama_synchronize", &ffi.TypeVoid, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
-	return nil
+	if nCtxFunc, err = lib.Prep("llama_n_ctx", &ffi.TypeUint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if nBatchFunc, err = lib.Prep("llama_n_batch", &ffi.TypeUint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
 }
 
 // ContextDefaultParams returns the default params to initialize a model context.
@@ -118,7 +205,12 @@ func Free(ctx Context) {
 }
 
 // SetWarmup sets the model context warmup mode on or off.
+// It does nothing if the loaded llama.cpp library does not support warmup mode.
 func SetWarmup(ctx Context, warmup bool) {
+	if !capabilities.Warmup {
+		return
+	}
+
 	setWarmupFunc.Call(nil, unsafe.Pointer(&ctx), &warmup)
 }
 
@@ -161,6 +253,62 @@ func MemorySeqRm(mem Memory, seqID SeqId, p0, p1 Pos) bool {
 	return result.Bool()
 }
 
+// MemorySeqCp copies all tokens that belong to the specified sequence and have positions in [p0, p1)
+// to another sequence. The tokens are shared with the source sequence, so they do not use more memory.
+// p0 < 0 : [0,  p1]
+// p1 < 0 : [p0, inf)
+func MemorySeqCp(mem Memory, seqIDSrc, seqIDDst SeqId, p0, p1 Pos) {
+	memorySeqCpFunc.Call(nil, unsafe.Pointer(&mem), &seqIDSrc, &seqIDDst, &p0, &p1)
+}
+
+// MemorySeqKeep removes all tokens that do not belong to the specified sequence.
+func MemorySeqKeep(mem Memory, seqID SeqId) {
+	memorySeqKeepFunc.Call(nil, unsafe.Pointer(&mem), &seqID)
+}
+
+// MemorySeqAdd adds delta to the positions of all tokens that belong to the specified sequence
+// and have positions in [p0, p1). Tokens whose position becomes negative are removed.
+// p0 < 0 : [0,  p1]
+// p1 < 0 : [p0, inf)
+func MemorySeqAdd(mem Memory, seqID SeqId, p0, p1, delta Pos) {
+	memorySeqAddFunc.Call(nil, unsafe.Pointer(&mem), &seqID, &p0, &p1, &delta)
+}
+
+// MemorySeqDiv divides the positions of all tokens that belong to the specified sequence and have
+// positions in [p0, p1) by d, rounding down.
+// p0 < 0 : [0,  p1]
+// p1 < 0 : [p0, inf)
+func MemorySeqDiv(mem Memory, seqID SeqId, p0, p1 Pos, d int32) {
+	memorySeqDivFunc.Call(nil, unsafe.Pointer(&mem), &seqID, &p0, &p1, &d)
+}
+
+// MemorySeqPosMin returns the smallest position in the memory for the specified sequence.
+// It returns -1 if the sequence is empty.
+func MemorySeqPosMin(mem Memory, seqID SeqId) Pos {
+	var result ffi.Arg
+	memorySeqPosMinFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&mem), &seqID)
+
+	return Pos(int32(result))
+}
+
+// MemorySeqPosMax returns the largest position in the memory for the specified sequence.
+// It returns -1 if the sequence is empty.
+func MemorySeqPosMax(mem Memory, seqID SeqId) Pos {
+	var result ffi.Arg
+	memorySeqPosMaxFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&mem), &seqID)
+
+	return Pos(int32(result))
+}
+
+// MemoryCanShift returns true if the positions in the memory can be changed with [MemorySeqAdd]
+// and [MemorySeqDiv].
+func MemoryCanShift(mem Memory) bool {
+	var result ffi.Arg
+	memoryCanShiftFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&mem))
+
+	return result.Bool()
+}
+
 // GetMemory returns the current Memory for the Context.
 func GetMemory(ctx Context) Memory {
 	var mem Memory
@@ -175,3 +323,19 @@ func GetMemory(ctx Context) Memory {
 func Synchronize(ctx Context) {
 	synchronizeFunc.Call(nil, unsafe.Pointer(&ctx))
 }
+
+// NCtx returns the size of the context, which is the most tokens that its memory can hold.
+func NCtx(ctx Context) uint32 {
+	var result ffi.Arg
+	nCtxFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx))
+
+	return uint32(result)
+}
+
+// NBatch returns the most tokens that can be passed to [Decode] in a single batch.
+func NBatch(ctx Context) uint32 {
+	var result ffi.Arg
+	nBatchFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx))
+
+	return uint32(result)
+}
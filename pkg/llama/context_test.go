@@ -0,0 +1,95 @@
+package llama
+
+import (
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model := ModelLoadFromFile(testModelFile(t), ModelDefaultParams())
+	if model == 0 {
+		t.Fatal("unable to load model")
+	}
+	defer ModelFree(model)
+
+	lctx := InitFromModel(model, ContextDefaultParams())
+	if lctx == 0 {
+		t.Fatal("unable to init context")
+	}
+	defer Free(lctx)
+
+	vocab := ModelGetVocab(model)
+	count := Tokenize(vocab, "Are you ready to rock?", nil, true, false)
+	tokens := make([]Token, count)
+	Tokenize(vocab, "Are you ready to rock?", tokens, true, false)
+
+	if result := Decode(lctx, BatchGetOne(tokens)); result != 0 {
+		t.Fatal("unable to decode batch", result)
+	}
+
+	if !MemorySeqRm(GetMemory(lctx), 0, -1, -1) {
+		t.Fatal("unable to remove sequence from memory")
+	}
+}
+
+func TestMemorySeq(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model := ModelLoadFromFile(testModelFile(t), ModelDefaultParams())
+	if model == 0 {
+		t.Fatal("unable to load model")
+	}
+	defer ModelFree(model)
+
+	lctx := InitFromModel(model, ContextDefaultParams())
+	if lctx == 0 {
+		t.Fatal("unable to init context")
+	}
+	defer Free(lctx)
+
+	tokens, err := TokenizeString(ModelGetVocab(model), "Are you ready to rock?", TokenizeOptions{AddSpecial: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result := Decode(lctx, BatchGetOne(tokens)); result != 0 {
+		t.Fatal("unable to decode batch", result)
+	}
+
+	mem := GetMemory(lctx)
+	last := Pos(len(tokens) - 1)
+	if MemorySeqPosMin(mem, 0) != 0 || MemorySeqPosMax(mem, 0) != last {
+		t.Fatal("wrong positions", MemorySeqPosMin(mem, 0), MemorySeqPosMax(mem, 0))
+	}
+
+	if MemorySeqPosMax(mem, 1) != -1 {
+		t.Fatal("expected an empty sequence")
+	}
+
+	MemorySeqCp(mem, 0, 1, 2, -1)
+	if MemorySeqPosMin(mem, 1) != 2 || MemorySeqPosMax(mem, 1) != last {
+		t.Fatal("wrong positions after copy", MemorySeqPosMin(mem, 1), MemorySeqPosMax(mem, 1))
+	}
+
+	MemorySeqKeep(mem, 1)
+	if MemorySeqPosMax(mem, 0) != -1 {
+		t.Fatal("expected sequence 0 to be removed")
+	}
+
+	if !MemoryCanShift(mem) {
+		t.Skip("memory cannot be shifted")
+	}
+
+	MemorySeqAdd(mem, 1, -1, -1, -2)
+	if MemorySeqPosMin(mem, 1) != 0 || MemorySeqPosMax(mem, 1) != last-2 {
+		t.Fatal("wrong positions after add", MemorySeqPosMin(mem, 1), MemorySeqPosMax(mem, 1))
+	}
+
+	MemorySeqDiv(mem, 1, -1, -1, 2)
+	if MemorySeqPosMax(mem, 1) != (last-2)/2 {
+		t.Fatal("wrong positions after div", MemorySeqPosMax(mem, 1))
+	}
+}
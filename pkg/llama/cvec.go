@@ -0,0 +1,121 @@
+package llama
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/hybridgroup/yzma/pkg/gguf"
+	"github.com/hybridgroup/yzma/pkg/loader"
+	"github.com/jupiterrider/ffi"
+)
+
+var (
+	// LLAMA_API int32_t llama_apply_adapter_cvec(
+	//         struct llama_context * ctx,
+	//                  const float * data,
+	//                       size_t   len,
+	//                      int32_t   n_embd,
+	//                      int32_t   il_start,
+	//                      int32_t   il_end);
+	applyAdapterCvecFunc ffi.Fun
+)
+
+func loadCvecFuncs(lib loader.Library) error {
+	applyAdapterCvecFunc, capabilities.ControlVector = loader.PrepOptional(lib, "llama_apply_adapter_cvec", &ffi.TypeSint32, &ffi.TypePointer,
+		&ffi.TypePointer, &ffi.TypeUint64, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypeSint32)
+
+	return nil
+}
+
+// ControlVector is a steering vector that is added to the output of each layer of a model. It is
+// the same type as [gguf.ControlVector], so that control vectors can be read from GGUF files with
+// [gguf.ReadControlVector].
+type ControlVector = gguf.ControlVector
+
+// CombineControlVectors returns the sum of the control vectors, each multiplied by its weight.
+// The vectors must all have the same NEmbd, but can have a different number of layers.
+func CombineControlVectors(vectors []ControlVector, weights []float32) (ControlVector, error) {
+	if len(vectors) == 0 || len(vectors) != len(weights) {
+		return ControlVector{}, errors.New("llama: need one weight for each control vector")
+	}
+
+	combined := ControlVector{NEmbd: vectors[0].NEmbd}
+	for i, cv := range vectors {
+		if cv.NEmbd != combined.NEmbd {
+			return ControlVector{}, fmt.Errorf("llama: control vector %d has n_embd %d, expected %d", i, cv.NEmbd, combined.NEmbd)
+		}
+
+		if len(cv.Data) > len(combined.Data) {
+			combined.Data = append(combined.Data, make([]float32, len(cv.Data)-len(combined.Data))...)
+		}
+
+		for j, v := range cv.Data {
+			combined.Data[j] += v * weights[i]
+		}
+	}
+
+	return combined, nil
+}
+
+// ApplyAdapterCvec applies a control vector to a Context, or clears it if data is nil.
+// data has nEmbd values for every layer starting at layer 1, and is applied to layers
+// ilStart to ilEnd inclusive. It returns 0 on success.
+func ApplyAdapterCvec(ctx Context, data []float32, nEmbd, ilStart, ilEnd int32) int32 {
+	p := unsafe.SliceData(data)
+	n := uint64(len(data))
+
+	var result ffi.Arg
+	applyAdapterCvecFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx), unsafe.Pointer(&p), &n,
+		&nEmbd, &ilStart, &ilEnd)
+
+	return int32(result)
+}
+
+// ApplyControlVector applies a control vector to a Context with a strength, replacing any that was
+// applied before. The vector is multiplied by strength, so 1 applies it as it is and a negative
+// strength steers the other way. Use [CombineControlVectors] to apply several at once, with the
+// weights setting their strength relative to each other.
+// The vector is applied to layers ilStart to ilEnd inclusive, where the first layer is 1. To apply it
+// to every layer, use 1 and [ModelNLayer].
+// It returns [ErrNotSupported] if the loaded llama.cpp library does not support control vectors.
+func ApplyControlVector(ctx Context, cv ControlVector, strength float32, ilStart, ilEnd int32) error {
+	if !capabilities.ControlVector {
+		return notSupported("llama_apply_adapter_cvec")
+	}
+
+	if cv.NEmbd <= 0 || len(cv.Data) == 0 || len(cv.Data)%int(cv.NEmbd) != 0 {
+		return errors.New("llama: invalid control vector")
+	}
+
+	if ilStart < 1 || ilEnd < ilStart {
+		return fmt.Errorf("llama: invalid control vector layer range %d to %d", ilStart, ilEnd)
+	}
+
+	data := cv.Data
+	if strength != 1 {
+		data = make([]float32, len(cv.Data))
+		for i, v := range cv.Data {
+			data[i] = v * strength
+		}
+	}
+
+	if r := ApplyAdapterCvec(ctx, data, cv.NEmbd, ilStart, ilEnd); r != 0 {
+		return fmt.Errorf("llama: unable to apply control vector: %d", r)
+	}
+
+	return nil
+}
+
+// ClearControlVector removes the control vector from a Context.
+func ClearControlVector(ctx Context) error {
+	if !capabilities.ControlVector {
+		return notSupported("llama_apply_adapter_cvec")
+	}
+
+	if r := ApplyAdapterCvec(ctx, nil, 0, 0, 0); r != 0 {
+		return fmt.Errorf("llama: unable to clear control vector: %d", r)
+	}
+
+	return nil
+}
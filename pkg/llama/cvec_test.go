@@ -0,0 +1,99 @@
+package llama
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/hybridgroup/yzma/pkg/fake"
+)
+
+func TestCombineControlVectors(t *testing.T) {
+	a := ControlVector{NEmbd: 2, Data: []float32{1, 2}}
+	b := ControlVector{NEmbd: 2, Data: []float32{1, 1, 3, 3}}
+
+	cv, err := CombineControlVectors([]ControlVector{a, b}, []float32{0.5, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cv.NLayer() != 2 || !slices.Equal(cv.Data, []float32{2.5, 3, 6, 6}) {
+		t.Fatal("wrong combined control vector", cv)
+	}
+
+	// the inputs must not be changed
+	if !slices.Equal(a.Data, []float32{1, 2}) {
+		t.Fatal("control vector was modified", a)
+	}
+
+	if _, err := CombineControlVectors([]ControlVector{a, {NEmbd: 3, Data: []float32{1, 2, 3}}}, []float32{1, 1}); err == nil {
+		t.Fatal("expected error for different n_embd")
+	}
+
+	if _, err := CombineControlVectors([]ControlVector{a}, nil); err == nil {
+		t.Fatal("expected error for missing weights")
+	}
+}
+
+func TestApplyControlVector(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal("unable to load model", err)
+	}
+	defer model.Close()
+
+	lctx, err := NewContext(model, ContextDefaultParams())
+	if err != nil {
+		t.Fatal("unable to create context", err)
+	}
+	defer lctx.Close()
+
+	nEmbd, nLayer := ModelNEmbd(model), ModelNLayer(model)
+	cv := ControlVector{NEmbd: nEmbd, Data: make([]float32, nEmbd*nLayer)}
+	for i := range cv.Data {
+		cv.Data[i] = 1
+	}
+
+	if err := ApplyControlVector(lctx, cv, 1, 1, nLayer); err != nil {
+		t.Fatal("unable to apply control vector", err)
+	}
+
+	if err := ApplyControlVector(lctx, cv, -0.5, 1, nLayer); err != nil {
+		t.Fatal("unable to apply control vector with a strength", err)
+	}
+
+	if cv.Data[0] != 1 {
+		t.Fatal("the strength should not change the control vector", cv.Data[0])
+	}
+
+	if err := ClearControlVector(lctx); err != nil {
+		t.Fatal("unable to clear control vector", err)
+	}
+
+	if err := ApplyControlVector(lctx, cv, 1, 0, nLayer); err == nil {
+		t.Fatal("expected error for layer 0")
+	}
+
+	wrong := ControlVector{NEmbd: nEmbd + 1, Data: make([]float32, nEmbd+1)}
+	if err := ApplyControlVector(lctx, wrong, 1, 1, 1); err == nil {
+		t.Fatal("expected error for wrong n_embd")
+	}
+}
+
+func TestControlVectorNotSupported(t *testing.T) {
+	if err := Load(fake.New().Omit("llama_apply_adapter_cvec")); err != nil {
+		t.Fatal("missing optional functions should not be an error", err)
+	}
+	defer Load(fake.New())
+
+	if GetCapabilities().ControlVector {
+		t.Fatal("control vectors reported as supported")
+	}
+
+	if err := ClearControlVector(0); !errors.Is(err, ErrNotSupported) {
+		t.Fatal("expected ErrNotSupported", err)
+	}
+}
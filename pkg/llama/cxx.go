@@ -0,0 +1,142 @@
+package llama
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"slices"
+	"unsafe"
+)
+
+// The quantize params take pointers to C++ containers. These types mirror the layout of the
+// containers in libstdc++ with the C++11 ABI on 64-bit platforms, which is what the llama.cpp
+// releases for Linux are built with. The layout cannot be checked from Go, so it is only used when
+// the caller opts in with [QuantizeOptions].AssumeLibstdcxx. The containers are only read by
+// llama.cpp, so they are built in Go memory that is kept alive until llama.cpp is done with it.
+
+// checkCxxLayout returns an error wrapping [ErrNotSupported] if the libstdc++ container layouts
+// cannot be used on this platform, or the caller has not opted in to them.
+func checkCxxLayout(optIn bool) error {
+	if !optIn {
+		return fmt.Errorf("%w: C++ containers need QuantizeOptions.AssumeLibstdcxx to be set", ErrNotSupported)
+	}
+
+	if runtime.GOOS != "linux" || unsafe.Sizeof(uintptr(0)) != 8 {
+		return fmt.Errorf("%w: C++ containers are only supported on 64-bit Linux", ErrNotSupported)
+	}
+
+	return nil
+}
+
+// cxxString mirrors std::string. The data is always in a separate NUL-terminated buffer instead
+// of the local buffer, which is only used to store the capacity.
+type cxxString struct {
+	data     *byte
+	size     uintptr
+	capacity uintptr
+	_        uintptr
+}
+
+// cxxVector mirrors std::vector<T>. The buffer has one extra element, so that end points into the
+// buffer even when the vector is empty.
+type cxxVector[T any] struct {
+	begin *T
+	end   *T
+	cap   *T
+}
+
+// cxxHashNode mirrors a node of std::unordered_map<std::string, std::vector<float>>, which caches
+// the hash code of its key.
+type cxxHashNode struct {
+	next  *cxxHashNode
+	key   cxxString
+	value cxxVector[float32]
+	hash  uintptr
+}
+
+// cxxHashtable mirrors std::unordered_map<std::string, std::vector<float>>. Each bucket points to
+// the node before the first node of the bucket, which is beforeBegin for the first bucket.
+type cxxHashtable struct {
+	buckets       *unsafe.Pointer
+	bucketCount   uintptr
+	beforeBegin   *cxxHashNode
+	elementCount  uintptr
+	maxLoadFactor float32
+	nextResize    uintptr
+	singleBucket  unsafe.Pointer
+}
+
+// newCxxString returns a std::string with a copy of s.
+func newCxxString(s string) cxxString {
+	buf := make([]byte, len(s)+1)
+	copy(buf, s)
+
+	return cxxString{data: &buf[0], size: uintptr(len(s)), capacity: uintptr(len(s))}
+}
+
+// newCxxVector returns a std::vector with a copy of values.
+func newCxxVector[T any](values []T) cxxVector[T] {
+	buf := make([]T, len(values)+1)
+	copy(buf, values)
+
+	return cxxVector[T]{begin: &buf[0], end: &buf[len(values)], cap: &buf[len(values)]}
+}
+
+// newCxxHashtable returns a std::unordered_map<std::string, std::vector<float>> with a copy of m.
+func newCxxHashtable(m map[string][]float32) *cxxHashtable {
+	nodes := make([]cxxHashNode, len(m))
+	i := 0
+	for key, value := range m {
+		nodes[i] = cxxHashNode{key: newCxxString(key), value: newCxxVector(value), hash: uintptr(cxxHash(key))}
+		i++
+	}
+
+	t := &cxxHashtable{bucketCount: uintptr(max(len(nodes), 1)), maxLoadFactor: 1}
+	t.nextResize = t.bucketCount
+
+	// the nodes of each bucket must be next to each other in the list
+	slices.SortFunc(nodes, func(a, b cxxHashNode) int {
+		return cmp.Compare(a.hash%t.bucketCount, b.hash%t.bucketCount)
+	})
+
+	buckets := make([]unsafe.Pointer, t.bucketCount)
+	before := unsafe.Pointer(&t.beforeBegin)
+	for i := range nodes {
+		if b := nodes[i].hash % t.bucketCount; buckets[b] == nil {
+			buckets[b] = before
+		}
+
+		*(**cxxHashNode)(before) = &nodes[i]
+		before = unsafe.Pointer(&nodes[i])
+	}
+	t.elementCount = uintptr(len(nodes))
+	t.buckets = &buckets[0]
+
+	return t
+}
+
+// cxxHash returns std::hash<std::string> of s, which is std::_Hash_bytes with the seed 0xc70f6907.
+func cxxHash(s string) uint64 {
+	const mul = 0xc6a4a7935bd1e995
+	shiftMix := func(v uint64) uint64 { return v ^ (v >> 47) }
+
+	b := []byte(s)
+	hash := 0xc70f6907 ^ uint64(len(b))*mul
+	for ; len(b) >= 8; b = b[8:] {
+		hash ^= shiftMix(binary.LittleEndian.Uint64(b)*mul) * mul
+		hash *= mul
+	}
+
+	if len(b) > 0 {
+		var tail uint64
+		for i := len(b) - 1; i >= 0; i-- {
+			tail = tail<<8 | uint64(b[i])
+		}
+		hash ^= tail
+		hash *= mul
+	}
+
+	hash = shiftMix(hash) * mul
+	return shiftMix(hash)
+}
@@ -0,0 +1,139 @@
+package llama
+
+import (
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/jupiterrider/ffi"
+)
+
+// Detokenize converts tokens into text in buf, and returns the length of the text. If buf is too
+// small, it returns the negative of the length that is needed. If removeSpecial is true, the BOS and
+// EOS tokens that the model adds are removed. If unparseSpecial is true, special tokens are included
+// in the text.
+func Detokenize(vocab Vocab, tokens []Token, buf []byte, removeSpecial bool, unparseSpecial bool) int32 {
+	toks := unsafe.SliceData(tokens)
+	nTokens := int32(len(tokens))
+	b := unsafe.SliceData(buf)
+	bLen := int32(len(buf))
+
+	var result ffi.Arg
+	detokenizeFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&vocab), unsafe.Pointer(&toks), &nTokens,
+		unsafe.Pointer(&b), &bLen, &removeSpecial, &unparseSpecial)
+
+	return int32(result)
+}
+
+// tokenPiece returns the text for token, growing buf if it is too small. It returns buf so that it can be reused.
+func tokenPiece(vocab Vocab, buf []byte, token Token, lstrip int32, special bool) ([]byte, []byte) {
+	n := TokenToPiece(vocab, token, buf, lstrip, special)
+	if n < 0 {
+		buf = make([]byte, -n)
+		n = TokenToPiece(vocab, token, buf, lstrip, special)
+	}
+
+	return buf[:max(n, 0)], buf
+}
+
+// Detokenizer converts tokens into text one token at a time, such as while they are generated.
+// A single character can be split across several tokens, so the Detokenizer holds back the start of
+// a UTF-8 character until the rest of it arrives, and only returns complete text.
+//
+//	detok := llama.NewDetokenizer(vocab, 0, false)
+//	for ... {
+//		token := llama.SamplerSample(sampler, lctx, -1)
+//		fmt.Print(detok.Add(token))
+//	}
+//	fmt.Print(detok.Flush())
+type Detokenizer struct {
+	vocab   Vocab
+	table   *PieceTable
+	lstrip  int32
+	special bool
+	started bool
+
+	buf     []byte
+	pending []byte
+}
+
+// NewDetokenizer returns a Detokenizer for vocab. lstrip is the number of leading spaces to remove
+// from the first token, since some models start their text with a space. If special is true, special
+// tokens such as EOS are included in the text.
+func NewDetokenizer(vocab Vocab, lstrip int32, special bool) *Detokenizer {
+	return &Detokenizer{
+		vocab:   vocab,
+		lstrip:  lstrip,
+		special: special,
+		buf:     make([]byte, 64),
+	}
+}
+
+// NewDetokenizerFromTable returns a Detokenizer that looks up the text of tokens in table,
+// instead of calling llama.cpp for each token.
+func NewDetokenizerFromTable(table *PieceTable, lstrip int32, special bool) *Detokenizer {
+	return &Detokenizer{
+		vocab:   table.Vocab(),
+		table:   table,
+		lstrip:  lstrip,
+		special: special,
+	}
+}
+
+// Add adds a token, and returns the text that is complete so far. The text can be empty if the
+// token is only the start of a character.
+func (d *Detokenizer) Add(token Token) string {
+	var lstrip int32
+	if !d.started {
+		lstrip = d.lstrip
+	}
+
+	n := len(d.pending)
+	if d.table != nil {
+		d.pending = d.table.AppendPiece(d.pending, token, lstrip, d.special)
+	} else {
+		var piece []byte
+		piece, d.buf = tokenPiece(d.vocab, d.buf, token, lstrip, d.special)
+		d.pending = append(d.pending, piece...)
+	}
+	if len(d.pending) > n {
+		d.started = true
+	}
+
+	n = completeUTF8(d.pending)
+	text := string(d.pending[:n])
+	d.pending = append(d.pending[:0], d.pending[n:]...)
+
+	return text
+}
+
+// Flush returns any text that is still held back, even if it is not a complete character,
+// and resets the Detokenizer so that it can be used for new text.
+func (d *Detokenizer) Flush() string {
+	text := string(d.pending)
+	d.Reset()
+
+	return text
+}
+
+// Reset discards any text that is held back, so that the Detokenizer can be used for new text.
+func (d *Detokenizer) Reset() {
+	d.pending = d.pending[:0]
+	d.started = false
+}
+
+// completeUTF8 returns the length of b without an incomplete UTF-8 character at the end.
+// Invalid bytes are not held back, since more bytes cannot make them valid.
+func completeUTF8(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if !utf8.RuneStart(b[i]) {
+			continue
+		}
+
+		if !utf8.FullRune(b[i:]) {
+			return i
+		}
+		break
+	}
+
+	return len(b)
+}
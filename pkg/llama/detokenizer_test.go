@@ -0,0 +1,127 @@
+package llama
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestDetokenizer(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	tok, err := LoadTokenizer(testModelFile(t))
+	if err != nil {
+		t.Fatal("unable to load tokenizer", err)
+	}
+	defer tok.Close()
+
+	text := "Grüße, 世界 👋!"
+	tokens, err := tok.Encode(text, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	detok := NewDetokenizer(tok.Vocab(), 0, false)
+
+	var sb strings.Builder
+	for _, token := range tokens {
+		out := detok.Add(token)
+		if !utf8.ValidString(out) {
+			t.Fatalf("incomplete UTF-8 returned: %q", out)
+		}
+		sb.WriteString(out)
+	}
+	sb.WriteString(detok.Flush())
+
+	if sb.String() != text {
+		t.Fatalf("got %q, want %q", sb.String(), text)
+	}
+}
+
+func TestDetokenizerSpecial(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	tok, err := LoadTokenizer(testModelFile(t))
+	if err != nil {
+		t.Fatal("unable to load tokenizer", err)
+	}
+	defer tok.Close()
+
+	eos := tok.EOS()
+
+	if out := NewDetokenizer(tok.Vocab(), 0, false).Add(eos); out != "" {
+		t.Fatalf("special token should not be included, got %q", out)
+	}
+
+	if out := NewDetokenizer(tok.Vocab(), 0, true).Add(eos); out != tok.Piece(eos, true) || out == "" {
+		t.Fatalf("special token should be included, got %q", out)
+	}
+
+	// lstrip only applies to the first token
+	tokens, err := tok.Encode(" a b", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	detok := NewDetokenizer(tok.Vocab(), 1, false)
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteString(detok.Add(token))
+	}
+	if sb.String() != "a b" {
+		t.Fatalf("got %q, want %q", sb.String(), "a b")
+	}
+}
+
+func TestDetokenize(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	tok, err := LoadTokenizer(testModelFile(t))
+	if err != nil {
+		t.Fatal("unable to load tokenizer", err)
+	}
+	defer tok.Close()
+
+	text := "Hello, 世界"
+	tokens, err := tok.Encode(text, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := Detokenize(tok.Vocab(), tokens, nil, true, false); n >= 0 {
+		t.Fatal("expected the needed length for an empty buffer", n)
+	}
+
+	buf := make([]byte, 64)
+	n := Detokenize(tok.Vocab(), tokens, buf, true, false)
+	if string(buf[:n]) != text {
+		t.Fatalf("got %q, want %q", buf[:n], text)
+	}
+
+	if decoded := tok.Decode(tokens, false); decoded != text {
+		t.Fatalf("got %q, want %q", decoded, text)
+	}
+}
+
+func TestCompleteUTF8(t *testing.T) {
+	tests := []struct {
+		b    string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"a\xe4\xb8", 1},
+		{"a\xe4\xb8\x96", 4},
+		{"\xf0\x9f\x91", 0},
+		{"a\xff", 2},
+	}
+
+	for _, tt := range tests {
+		if n := completeUTF8([]byte(tt.b)); n != tt.want {
+			t.Fatalf("%q: got %d, want %d", tt.b, n, tt.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package llama
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrModelLoad is returned when llama.cpp is unable to load a model file.
+	ErrModelLoad = errors.New("llama: unable to load model")
+
+	// ErrContextInit is returned when llama.cpp is unable to create a Context for a model.
+	ErrContextInit = errors.New("llama: unable to create context")
+
+	// ErrNoKVSlot is returned by [Context.Decode] when there is no space in the KV cache for the batch.
+	// Try reducing the size of the batch or increasing the size of the context.
+	ErrNoKVSlot = errors.New("llama: no KV slot available for the batch")
+
+	// ErrAborted is returned when the abort callback stops the computation. The batches that have
+	// already been processed remain in the context's memory.
+	ErrAborted = errors.New("llama: computation aborted")
+
+	// ErrInvalidBatch is returned when the batch is empty or has invalid tokens, positions or sequences.
+	ErrInvalidBatch = errors.New("llama: invalid batch")
+
+	// ErrAlloc is returned when llama.cpp is unable to allocate the memory to compute a batch.
+	ErrAlloc = errors.New("llama: unable to allocate memory for the batch")
+
+	// ErrCompute is returned when llama.cpp fails to compute a batch.
+	ErrCompute = errors.New("llama: unable to compute the batch")
+
+	// ErrInvalidUTF8 is returned by [TokenizeString] when the text is not valid UTF-8.
+	ErrInvalidUTF8 = errors.New("llama: text is not valid UTF-8")
+
+	// ErrTokenOverflow is returned by [TokenizeString] when the text has more tokens than llama.cpp can return.
+	ErrTokenOverflow = errors.New("llama: too many tokens")
+
+	// ErrInvalidToken is returned by [PieceString] for a token that is not in the vocabulary.
+	ErrInvalidToken = errors.New("llama: invalid token")
+)
+
+// DecodeError is returned when [Decode] or [Encode] return a non-zero code.
+// Use [errors.Is] to check which of the sentinel errors it is, for example:
+//
+//	if errors.Is(err, llama.ErrNoKVSlot) {
+//		// make room in the KV cache and try again
+//	}
+type DecodeError struct {
+	// Op is the function that failed, either "decode" or "encode".
+	Op string
+
+	// Code is the code returned by llama.cpp.
+	Code int32
+}
+
+func (e *DecodeError) Error() string {
+	if err := e.Unwrap(); err != nil {
+		return fmt.Sprintf("%s (%s returned %d)", err, e.Op, e.Code)
+	}
+
+	return fmt.Sprintf("llama: %s returned %d", e.Op, e.Code)
+}
+
+// Unwrap returns the sentinel error for the code, or nil if llama.cpp does not document it.
+func (e *DecodeError) Unwrap() error {
+	switch e.Code {
+	case 1:
+		return ErrNoKVSlot
+	case 2:
+		return ErrAborted
+	case -1:
+		return ErrInvalidBatch
+	case -2:
+		return ErrAlloc
+	case -3:
+		return ErrCompute
+	default:
+		return nil
+	}
+}
+
+// decodeError returns nil for a code of 0, and a [*DecodeError] for anything else.
+func decodeError(op string, code int32) error {
+	if code == 0 {
+		return nil
+	}
+
+	return &DecodeError{Op: op, Code: code}
+}
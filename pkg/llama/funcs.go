@@ -1,8 +1,11 @@
 package llama
 
 import (
+	"errors"
 	"unsafe"
 
+	"github.com/hybridgroup/yzma/pkg/loader"
+	"github.com/hybridgroup/yzma/pkg/utils"
 	"github.com/jupiterrider/ffi"
 )
 
@@ -16,27 +19,46 @@ var (
 
 	// GGML_API void ggml_backend_load_all(void);
 	ggmlBackendLoadAllFromPath ffi.Fun
+
+	// LLAMA_API const char * llama_print_system_info(void);
+	printSystemInfoFunc ffi.Fun
+
+	// GGML_API const char * ggml_version(void);
+	ggmlVersionFunc ffi.Fun
+
+	// GGML_API const char * ggml_commit(void);
+	ggmlCommitFunc ffi.Fun
 )
 
-func loadFuncs(lib ffi.Lib) error {
-	var err error
+func loadFuncs(lib loader.Library) error {
+	var (
+		err  error
+		errs []error
+	)
 	if backendInitFunc, err = lib.Prep("llama_backend_init", &ffi.TypeVoid); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if backendFreeFunc, err = lib.Prep("llama_backend_free", &ffi.TypeVoid); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if ggmlBackendLoadAllFunc, err = lib.Prep("ggml_backend_load_all", &ffi.TypeVoid); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if ggmlBackendLoadAllFromPath, err = lib.Prep("ggml_backend_load_all_from_path", &ffi.TypeVoid, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
+	}
+
+	if printSystemInfoFunc, err = lib.Prep("llama_print_system_info", &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
 	}
 
-	return nil
+	ggmlVersionFunc, capabilities.Version = loader.PrepOptional(lib, "ggml_version", &ffi.TypePointer)
+	ggmlCommitFunc, _ = loader.PrepOptional(lib, "ggml_commit", &ffi.TypePointer)
+
+	return errors.Join(errs...)
 }
 
 // BackendInit initializes the llama.cpp back-end.
@@ -59,3 +81,31 @@ func GGMLBackendLoadAllFromPath(path string) {
 	p := &[]byte(path + "\x00")[0]
 	ggmlBackendLoadAllFromPath.Call(nil, unsafe.Pointer(&p))
 }
+
+// PrintSystemInfo returns information about the features of the system that llama.cpp is using.
+func PrintSystemInfo() string {
+	var info *byte
+	printSystemInfoFunc.Call(unsafe.Pointer(&info))
+
+	return utils.BytePtrToString(info)
+}
+
+// Version returns the version and commit of the ggml library used by llama.cpp, for example "0.9.4 (e4c4cd1)".
+// It returns [ErrNotSupported] for builds of llama.cpp that do not report their version.
+func Version() (string, error) {
+	if !capabilities.Version {
+		return "", notSupported("ggml_version")
+	}
+
+	var version, commit *byte
+	ggmlVersionFunc.Call(unsafe.Pointer(&version))
+	if ggmlCommitFunc.Cif != nil {
+		ggmlCommitFunc.Call(unsafe.Pointer(&commit))
+	}
+
+	if commit == nil {
+		return utils.BytePtrToString(version), nil
+	}
+
+	return utils.BytePtrToString(version) + " (" + utils.BytePtrToString(commit) + ")", nil
+}
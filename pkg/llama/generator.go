@@ -0,0 +1,137 @@
+package llama
+
+import (
+	"fmt"
+	"io"
+)
+
+// Generator decodes tokens and samples new ones in sequence 0 of a Context, and keeps going after
+// the context is full. When there is no room for more tokens, it keeps the first Keep tokens, such
+// as a system prompt, and discards the oldest half of the rest. If the memory of the context can be
+// shifted, the remaining tokens are moved into place with [MemorySeqRm] and [MemorySeqAdd].
+// Otherwise the remaining tokens are decoded again.
+//
+//	gen := llama.NewGenerator(lctx, vocab, sampler)
+//	gen.Keep = len(system)
+//	if err := gen.Decode(prompt); err != nil {
+//		return err
+//	}
+//	for {
+//		token, err := gen.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//	}
+type Generator struct {
+	// Keep is the number of tokens at the start of the sequence that are never discarded.
+	Keep int
+
+	ctx     Context
+	vocab   Vocab
+	sampler Sampler
+	nCtx    int
+	nBatch  int
+	shift   bool
+
+	// tokens are the tokens in the memory of the sequence, in order.
+	tokens []Token
+}
+
+// NewGenerator returns a Generator for ctx, that samples tokens with sampler. The memory of
+// sequence 0 of ctx should be empty.
+func NewGenerator(ctx Context, vocab Vocab, sampler Sampler) *Generator {
+	return &Generator{
+		ctx:     ctx,
+		vocab:   vocab,
+		sampler: sampler,
+		nCtx:    int(NCtx(ctx)),
+		nBatch:  max(int(NBatch(ctx)), 1),
+		shift:   MemoryCanShift(GetMemory(ctx)),
+	}
+}
+
+// Tokens returns the tokens that are in the memory of the context, after any that were discarded.
+// The returned slice must not be modified.
+func (g *Generator) Tokens() []Token {
+	return g.tokens
+}
+
+// Decode decodes tokens after the tokens that have already been decoded, in batches of at most
+// [NBatch] tokens, and makes room for them first if the context is full. If it returns an error,
+// [Generator.Tokens] are still the tokens that are in the memory of the context, which does not
+// include the batch that failed.
+func (g *Generator) Decode(tokens []Token) error {
+	for len(tokens) > 0 {
+		n := min(len(tokens), g.nBatch)
+		if len(g.tokens)+n > g.nCtx {
+			if err := g.makeRoom(n); err != nil {
+				return err
+			}
+		}
+
+		if err := g.ctx.Decode(BatchGetOne(tokens[:n])); err != nil {
+			return err
+		}
+
+		g.tokens = append(g.tokens, tokens[:n]...)
+		tokens = tokens[n:]
+	}
+
+	return nil
+}
+
+// Next samples the next token from the last token that was decoded, and decodes it so that the
+// token after it can be sampled. It returns [io.EOF] with the token when it is an end of generation
+// token, such as EOS, which is not decoded.
+func (g *Generator) Next() (Token, error) {
+	token := SamplerSample(g.sampler, g.ctx, -1)
+	if VocabIsEOG(g.vocab, token) {
+		return token, io.EOF
+	}
+
+	return token, g.Decode([]Token{token})
+}
+
+// Reset clears the memory of sequence 0 and the tokens that have been decoded, and resets the
+// sampler with [SamplerReset], so that the Generator can be used for a new conversation.
+func (g *Generator) Reset() {
+	MemorySeqRm(GetMemory(g.ctx), 0, -1, -1)
+	g.tokens = g.tokens[:0]
+
+	if g.sampler != 0 {
+		SamplerReset(g.sampler)
+	}
+}
+
+// makeRoom discards tokens after the first Keep tokens, so that n more tokens fit in the context.
+func (g *Generator) makeRoom(n int) error {
+	keep := min(g.Keep, len(g.tokens))
+	left := len(g.tokens) - keep
+	discard := max(left/2, len(g.tokens)+n-g.nCtx)
+	if discard > left {
+		return fmt.Errorf("%w: cannot keep %d tokens and add %d in a context of %d", ErrNoKVSlot, keep, n, g.nCtx)
+	}
+
+	mem := GetMemory(g.ctx)
+	remaining := append(g.tokens[:keep:keep], g.tokens[keep+discard:]...)
+
+	if g.shift {
+		MemorySeqRm(mem, 0, Pos(keep), Pos(keep+discard))
+		MemorySeqAdd(mem, 0, Pos(keep+discard), -1, Pos(-discard))
+		g.tokens = remaining
+
+		return nil
+	}
+
+	// the memory cannot be shifted, so decode the remaining tokens again
+	MemorySeqRm(mem, 0, -1, -1)
+	g.tokens = g.tokens[:0]
+
+	if err := g.Decode(remaining); err != nil {
+		return fmt.Errorf("llama: the context was cleared to make room, and only %d of the %d tokens that were kept could be decoded again, so the rest of the history was dropped: %w",
+			len(g.tokens), len(remaining), err)
+	}
+
+	return nil
+}
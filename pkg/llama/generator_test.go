@@ -0,0 +1,182 @@
+package llama
+
+import (
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGenerator(t *testing.T) {
+	tests := []struct {
+		name  string
+		shift bool
+	}{
+		{"shift", true},
+		{"decode", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testSetup(t)
+			defer testCleanup(t)
+
+			model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer model.Close()
+
+			params := ContextDefaultParams()
+			params.NCtx = 32
+			params.NBatch = 8
+			lctx, err := NewContext(model, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer lctx.Close()
+
+			vocab := ModelGetVocab(model)
+			sampler := SamplerChainInit(SamplerChainDefaultParams())
+			SamplerChainAdd(sampler, SamplerInitGreedy())
+			defer sampler.Close()
+
+			system, err := TokenizeString(vocab, "sys:", TokenizeOptions{AddSpecial: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			prompt, err := TokenizeString(vocab, strings.Repeat("Are you ready to rock? ", 4)+"abc", TokenizeOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gen := NewGenerator(lctx, vocab, sampler)
+			gen.Keep = len(system)
+			if !tt.shift {
+				gen.shift = false
+			}
+
+			if err := gen.Decode(append(system, prompt...)); err != nil {
+				t.Fatal(err)
+			}
+
+			tokens := gen.Tokens()
+			if len(tokens) > int(NCtx(lctx)) || !slices.Equal(tokens[:len(system)], system) ||
+				!slices.Equal(tokens[len(tokens)-3:], prompt[len(prompt)-3:]) {
+				t.Fatal("wrong tokens after making room", tokens)
+			}
+
+			mem := GetMemory(lctx)
+			if MemorySeqPosMin(mem, 0) != 0 || MemorySeqPosMax(mem, 0) != Pos(len(tokens)-1) {
+				t.Fatal("wrong positions after making room", MemorySeqPosMin(mem, 0), MemorySeqPosMax(mem, 0))
+			}
+
+			var out []Token
+			for range 64 {
+				token, err := gen.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				out = append(out, token)
+			}
+
+			// the toy model continues the alphabet
+			if os.Getenv("YZMA_LIB") != "" {
+				return
+			}
+
+			if text := NewPieceTable(vocab).Decode(out, false); text != "defghijklmnopqrstuvwxyz." {
+				t.Fatalf("got %q", text)
+			}
+
+			if MemorySeqPosMax(mem, 0) != Pos(len(gen.Tokens())-1) {
+				t.Fatal("wrong positions after generating", MemorySeqPosMax(mem, 0))
+			}
+
+			gen.Reset()
+			if len(gen.Tokens()) != 0 || MemorySeqPosMax(mem, 0) != -1 {
+				t.Fatal("memory not cleared by Reset", gen.Tokens(), MemorySeqPosMax(mem, 0))
+			}
+		})
+	}
+}
+
+func TestGeneratorDecodeAgainError(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer model.Close()
+
+	params := ContextDefaultParams()
+	params.NCtx = 16
+	lctx, err := NewContext(model, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lctx.Close()
+
+	vocab := ModelGetVocab(model)
+	tokens, err := TokenizeString(vocab, strings.Repeat("a", 12), TokenizeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen := NewGenerator(lctx, vocab, 0)
+	gen.shift = false
+	if err := gen.Decode(tokens); err != nil {
+		t.Fatal(err)
+	}
+
+	// a token that cannot be decoded again when making room
+	gen.tokens[len(gen.tokens)-1] = -1
+
+	err = gen.Decode(tokens[:8])
+	if err == nil || !strings.Contains(err.Error(), "history was dropped") {
+		t.Fatal("expected an error for the dropped history", err)
+	}
+
+	if mem := GetMemory(lctx); MemorySeqPosMax(mem, 0) != Pos(len(gen.Tokens())-1) {
+		t.Fatal("tokens do not match the memory", len(gen.Tokens()), MemorySeqPosMax(mem, 0))
+	}
+}
+
+func TestGeneratorKeepTooLarge(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer model.Close()
+
+	params := ContextDefaultParams()
+	params.NCtx = 16
+	lctx, err := NewContext(model, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lctx.Close()
+
+	vocab := ModelGetVocab(model)
+	tokens, err := TokenizeString(vocab, strings.Repeat("a", 20), TokenizeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gen := NewGenerator(lctx, vocab, 0)
+	gen.Keep = 16
+	if err := gen.Decode(tokens); !errors.Is(err, ErrNoKVSlot) {
+		t.Fatal("expected ErrNoKVSlot", err)
+	}
+}
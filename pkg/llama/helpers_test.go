@@ -1,18 +1,15 @@
 package llama
 
 import (
+	"os"
 	"testing"
 
+	"github.com/hybridgroup/yzma/pkg/fake"
 	"github.com/hybridgroup/yzma/pkg/loader"
 )
 
-func testSetup(t *testing.T) {
-	testPath := "."
-	lib, err := loader.LoadLibrary(testPath)
-	if err != nil {
-		t.Fatal("unable to load library", err.Error())
-	}
-	if err := Load(lib); err != nil {
+func testSetup(t testing.TB) {
+	if err := Load(testLibrary(t)); err != nil {
 		t.Fatal("unable to load library", err.Error())
 	}
 
@@ -20,6 +17,34 @@ func testSetup(t *testing.T) {
 	GGMLBackendLoadAll()
 }
 
-func testCleanup(t *testing.T) {
+func testCleanup(t testing.TB) {
 	BackendFree()
 }
+
+// testLibrary returns the llama.cpp library in YZMA_LIB, or the fake backend if YZMA_LIB is not set.
+func testLibrary(t testing.TB) loader.Library {
+	if os.Getenv("YZMA_LIB") == "" {
+		return fake.New()
+	}
+
+	lib, err := loader.OpenLibraries(os.Getenv("YZMA_LIB"))
+	if err != nil {
+		t.Fatal("unable to load library", err.Error())
+	}
+
+	return lib
+}
+
+// testModelFile returns the model to use for tests. When using llama.cpp, the tests that need
+// a model are skipped unless YZMA_TEST_MODEL is set.
+func testModelFile(t testing.TB) string {
+	if os.Getenv("YZMA_LIB") == "" {
+		return "fake.gguf"
+	}
+
+	if os.Getenv("YZMA_TEST_MODEL") == "" {
+		t.Skip("YZMA_TEST_MODEL not set")
+	}
+
+	return os.Getenv("YZMA_TEST_MODEL")
+}
@@ -1,5 +1,12 @@
 package llama
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hybridgroup/yzma/pkg/gguf"
+)
+
 // Common types matching llama.cpp
 type (
 	Token  int32
@@ -39,8 +46,28 @@ const (
 	VOCAB_TYPE_WPM
 	VOCAB_TYPE_UGM
 	VOCAB_TYPE_RWKV
+	VOCAB_TYPE_PLAMO2
 )
 
+var vocabTypeNames = map[VocabType]string{
+	VOCAB_TYPE_NONE:   "no vocab",
+	VOCAB_TYPE_SPM:    "SPM",
+	VOCAB_TYPE_BPE:    "BPE",
+	VOCAB_TYPE_WPM:    "WPM",
+	VOCAB_TYPE_UGM:    "UGM",
+	VOCAB_TYPE_RWKV:   "RWKV",
+	VOCAB_TYPE_PLAMO2: "PLaMo2",
+}
+
+// String returns the name of the vocabulary type in the same way as llama.cpp, such as "BPE".
+func (t VocabType) String() string {
+	if name, ok := vocabTypeNames[t]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
 type TokenType int32
 
 const (
@@ -68,29 +95,77 @@ const (
 	TOKEN_ATTR_SINGLE_WORD TokenAttr = 1 << 8
 )
 
-type Ftype int32
+var tokenAttrNames = []string{"unknown", "unused", "normal", "control", "user_defined", "byte", "lstrip", "rstrip", "single_word"}
+
+// String returns the names of the attributes joined with "|", such as "control|rstrip".
+func (a TokenAttr) String() string {
+	if a == TOKEN_ATTR_UNDEFINED {
+		return "undefined"
+	}
+
+	var names []string
+	for i, name := range tokenAttrNames {
+		if a&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+
+	if rest := a &^ (1<<len(tokenAttrNames) - 1); rest != 0 {
+		names = append(names, fmt.Sprintf("%#x", int32(rest)))
+	}
+
+	return strings.Join(names, "|")
+}
+
+// Ftype is the type that a model is quantized to. It is the same type as [gguf.Ftype], so that it can
+// be compared with the general.file_type of a GGUF file without importing this package.
+type Ftype = gguf.Ftype
+
+const (
+	FTYPE_ALL_F32        = gguf.FtypeAllF32
+	FTYPE_MOSTLY_F16     = gguf.FtypeMostlyF16
+	FTYPE_MOSTLY_Q4_0    = gguf.FtypeMostlyQ4_0
+	FTYPE_MOSTLY_Q4_1    = gguf.FtypeMostlyQ4_1
+	FTYPE_MOSTLY_Q8_0    = gguf.FtypeMostlyQ8_0
+	FTYPE_MOSTLY_Q5_0    = gguf.FtypeMostlyQ5_0
+	FTYPE_MOSTLY_Q5_1    = gguf.FtypeMostlyQ5_1
+	FTYPE_MOSTLY_Q2_K    = gguf.FtypeMostlyQ2_K
+	FTYPE_MOSTLY_Q3_K_S  = gguf.FtypeMostlyQ3_K_S
+	FTYPE_MOSTLY_Q3_K_M  = gguf.FtypeMostlyQ3_K_M
+	FTYPE_MOSTLY_Q3_K_L  = gguf.FtypeMostlyQ3_K_L
+	FTYPE_MOSTLY_Q4_K_S  = gguf.FtypeMostlyQ4_K_S
+	FTYPE_MOSTLY_Q4_K_M  = gguf.FtypeMostlyQ4_K_M
+	FTYPE_MOSTLY_Q5_K_S  = gguf.FtypeMostlyQ5_K_S
+	FTYPE_MOSTLY_Q5_K_M  = gguf.FtypeMostlyQ5_K_M
+	FTYPE_MOSTLY_Q6_K    = gguf.FtypeMostlyQ6_K
+	FTYPE_MOSTLY_IQ2_XXS = gguf.FtypeMostlyIQ2_XXS
+	FTYPE_MOSTLY_IQ2_XS  = gguf.FtypeMostlyIQ2_XS
+	FTYPE_MOSTLY_Q2_K_S  = gguf.FtypeMostlyQ2_K_S
+	FTYPE_MOSTLY_IQ3_XS  = gguf.FtypeMostlyIQ3_XS
+	FTYPE_MOSTLY_IQ3_XXS = gguf.FtypeMostlyIQ3_XXS
+	FTYPE_MOSTLY_IQ1_S   = gguf.FtypeMostlyIQ1_S
+	FTYPE_MOSTLY_IQ4_NL  = gguf.FtypeMostlyIQ4_NL
+	FTYPE_MOSTLY_IQ3_S   = gguf.FtypeMostlyIQ3_S
+	FTYPE_MOSTLY_IQ3_M   = gguf.FtypeMostlyIQ3_M
+	FTYPE_MOSTLY_IQ2_S   = gguf.FtypeMostlyIQ2_S
+	FTYPE_MOSTLY_IQ2_M   = gguf.FtypeMostlyIQ2_M
+	FTYPE_MOSTLY_IQ4_XS  = gguf.FtypeMostlyIQ4_XS
+	FTYPE_MOSTLY_IQ1_M   = gguf.FtypeMostlyIQ1_M
+	FTYPE_MOSTLY_BF16    = gguf.FtypeMostlyBF16
+	FTYPE_MOSTLY_TQ1_0   = gguf.FtypeMostlyTQ1_0
+	FTYPE_MOSTLY_TQ2_0   = gguf.FtypeMostlyTQ2_0
+	FTYPE_MOSTLY_MXFP4   = gguf.FtypeMostlyMXFP4
+	FTYPE_GUESSED        = gguf.FtypeGuessed
+)
+
+type RopeType int32
 
 const (
-	FTYPE_ALL_F32        Ftype = 0
-	FTYPE_MOSTLY_F16     Ftype = 1
-	FTYPE_MOSTLY_Q4_0    Ftype = 2
-	FTYPE_MOSTLY_Q4_1    Ftype = 3
-	FTYPE_MOSTLY_Q8_0    Ftype = 7
-	FTYPE_MOSTLY_Q5_0    Ftype = 8
-	FTYPE_MOSTLY_Q5_1    Ftype = 9
-	FTYPE_MOSTLY_Q2_K    Ftype = 10
-	FTYPE_MOSTLY_Q3_K_S  Ftype = 11
-	FTYPE_MOSTLY_Q3_K_M  Ftype = 12
-	FTYPE_MOSTLY_Q3_K_L  Ftype = 13
-	FTYPE_MOSTLY_Q4_K_S  Ftype = 14
-	FTYPE_MOSTLY_Q4_K_M  Ftype = 15
-	FTYPE_MOSTLY_Q5_K_S  Ftype = 16
-	FTYPE_MOSTLY_Q5_K_M  Ftype = 17
-	FTYPE_MOSTLY_Q6_K    Ftype = 18
-	FTYPE_MOSTLY_IQ2_XXS Ftype = 19
-	FTYPE_MOSTLY_IQ2_XS  Ftype = 20
-	FTYPE_MOSTLY_Q2_K_S  Ftype = 21
-	FTYPE_MOSTLY_IQ3_XS  Ftype = 22
+	ROPE_TYPE_NONE   RopeType = -1
+	ROPE_TYPE_NORM   RopeType = 0
+	ROPE_TYPE_NEOX   RopeType = 2
+	ROPE_TYPE_MROPE  RopeType = 8
+	ROPE_TYPE_VISION RopeType = 24
 )
 
 type RopeScalingType int32
@@ -100,6 +175,7 @@ const (
 	ROPE_SCALING_TYPE_NONE        RopeScalingType = 0
 	ROPE_SCALING_TYPE_LINEAR      RopeScalingType = 1
 	ROPE_SCALING_TYPE_YARN        RopeScalingType = 2
+	ROPE_SCALING_TYPE_LONGROPE    RopeScalingType = 3
 )
 
 type PoolingType int32
@@ -116,8 +192,9 @@ const (
 type AttentionType int32
 
 const (
-	ATTENTION_TYPE_CAUSAL     AttentionType = 0
-	ATTENTION_TYPE_NON_CAUSAL AttentionType = 1
+	ATTENTION_TYPE_UNSPECIFIED AttentionType = -1
+	ATTENTION_TYPE_CAUSAL      AttentionType = 0
+	ATTENTION_TYPE_NON_CAUSAL  AttentionType = 1
 )
 
 type FlashAttentionType int32
@@ -268,17 +345,19 @@ type ContextParams struct {
 
 // Model quantize parameters
 type ModelQuantizeParams struct {
-	NThread              int32 // number of threads to use for quantizing
-	Ftype                Ftype // quantize to this llama_ftype
-	OutputTensorType     int32 // output tensor type
-	TokenEmbeddingType   int32 // itoken embeddings tensor type
-	AllowRequantize      uint8 // allow quantizing non-f32/f16 tensors (bool as uint8)
-	QuantizeOutputTensor uint8 // quantize output.weight (bool as uint8)
-	OnlyF32              uint8 // quantize only f32 tensors (bool as uint8)
-	PureF16              uint8 // disable k-quant mixtures and quantize all tensors to the same type (bool as uint8)
-	KeepSplit            uint8 // keep split tensors (bool as uint8)
-	IMatrix              *byte // importance matrix data
-	KqsWarning           uint8 // warning for quantization quality loss (bool as uint8)
+	NThread              int32   // number of threads to use for quantizing, if <=0 will use std::thread::hardware_concurrency()
+	Ftype                Ftype   // quantize to this llama_ftype
+	OutputTensorType     int32   // output tensor ggml_type
+	TokenEmbeddingType   int32   // token embeddings tensor ggml_type
+	AllowRequantize      uint8   // allow quantizing non-f32/f16 tensors (bool as uint8)
+	QuantizeOutputTensor uint8   // quantize output.weight (bool as uint8)
+	OnlyCopy             uint8   // only copy tensors - ftype, allow_requantize and quantize_output_tensor are ignored (bool as uint8)
+	Pure                 uint8   // quantize all tensors to the default type (bool as uint8)
+	KeepSplit            uint8   // quantize to the same number of shards (bool as uint8)
+	IMatrix              uintptr // pointer to importance matrix data, a C++ std::unordered_map
+	KvOverrides          uintptr // pointer to a C++ std::vector of kv overrides
+	TensorTypes          uintptr // pointer to a C++ std::vector of tensor types
+	PruneLayers          uintptr // pointer to a C++ std::vector of layer indices to prune
 }
 
 // Chat message
@@ -1,45 +1,40 @@
 package llama
 
 import (
+	"errors"
 	"os"
 
-	"github.com/jupiterrider/ffi"
+	"github.com/hybridgroup/yzma/pkg/loader"
 )
 
-func Load(lib ffi.Lib) error {
-	if err := loadFuncs(lib); err != nil {
-		return err
-	}
-
-	if err := loadModelFuncs(lib); err != nil {
-		return err
-	}
-
-	if err := loadBatchFuncs(lib); err != nil {
-		return err
-	}
-
-	if err := loadVocabFuncs(lib); err != nil {
-		return err
-	}
-
-	if err := loadSamplingFuncs(lib); err != nil {
-		return err
-	}
-
-	if err := loadChatFuncs(lib); err != nil {
-		return err
-	}
-
-	if err := loadContextFuncs(lib); err != nil {
-		return err
-	}
-
-	if err := loadLogFuncs(lib); err != nil {
-		return err
-	}
-
-	return nil
+// Load loads the llama.cpp functions from lib. If any of the required functions cannot be found,
+// the returned error reports every one of them. Optional functions that are missing are not an
+// error, see [GetCapabilities] for which of them are available.
+//
+// Once the functions are loaded, Load checks that the structs returned by the library match the
+// layout of [ModelParams] and [ContextParams], and returns an [*ABIError] if they do not. The layout
+// of [ModelQuantizeParams] is checked by [Quantize] the first time that it is called.
+func Load(lib loader.Library) error {
+	if err := errors.Join(
+		loadFuncs(lib),
+		loadModelFuncs(lib),
+		loadBatchFuncs(lib),
+		loadVocabFuncs(lib),
+		loadSamplingFuncs(lib),
+		loadChatFuncs(lib),
+		loadContextFuncs(lib),
+		loadLogFuncs(lib),
+		loadQuantizeFuncs(lib),
+		loadSplitFuncs(lib),
+		loadLoraFuncs(lib),
+		loadCvecFuncs(lib),
+		loadStateFuncs(lib),
+		loadOutputFuncs(lib),
+	); err != nil {
+		return err
+	}
+
+	return checkABI()
 }
 
 // Init is a convenience function to handle initialization of llama.cpp.
@@ -3,6 +3,7 @@ package llama
 import (
 	"unsafe"
 
+	"github.com/hybridgroup/yzma/pkg/loader"
 	"github.com/jupiterrider/ffi"
 )
 
@@ -17,7 +18,7 @@ var (
 	logSilent *ffi.Closure
 )
 
-func loadLogFuncs(lib ffi.Lib) error {
+func loadLogFuncs(lib loader.Library) error {
 	var err error
 
 	if logSetFunc, err = lib.Prep("llama_log_set", &ffi.TypeVoid, &ffi.TypePointer, &ffi.TypePointer); err != nil {
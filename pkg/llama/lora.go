@@ -0,0 +1,203 @@
+package llama
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/hybridgroup/yzma/pkg/loader"
+	"github.com/jupiterrider/ffi"
+)
+
+var (
+	// LLAMA_API struct llama_adapter_lora * llama_adapter_lora_init(
+	//         struct llama_model * model,
+	//         const char * path_lora);
+	adapterLoraInitFunc ffi.Fun
+
+	// LLAMA_API void llama_adapter_lora_free(struct llama_adapter_lora * adapter);
+	adapterLoraFreeFunc ffi.Fun
+
+	// LLAMA_API int32_t llama_set_adapter_lora(
+	//         struct llama_context * ctx,
+	//         struct llama_adapter_lora * adapter,
+	//         float scale);
+	setAdapterLoraFunc ffi.Fun
+
+	// LLAMA_API int32_t llama_rm_adapter_lora(
+	//         struct llama_context * ctx,
+	//         struct llama_adapter_lora * adapter);
+	rmAdapterLoraFunc ffi.Fun
+
+	// LLAMA_API void llama_clear_adapter_lora(struct llama_context * ctx);
+	clearAdapterLoraFunc ffi.Fun
+
+	// LLAMA_API int32_t llama_adapter_meta_count(const struct llama_adapter_lora * adapter);
+	adapterMetaCountFunc ffi.Fun
+
+	// LLAMA_API int32_t llama_adapter_meta_key_by_index(const struct llama_adapter_lora * adapter, int32_t i, char * buf, size_t buf_size);
+	adapterMetaKeyByIndexFunc ffi.Fun
+
+	// LLAMA_API int32_t llama_adapter_meta_val_str(const struct llama_adapter_lora * adapter, const char * key, char * buf, size_t buf_size);
+	adapterMetaValStrFunc ffi.Fun
+
+	// adapterMeta is true when the loaded library can return the metadata of an adapter.
+	adapterMeta bool
+)
+
+// ErrAdapterLoad is returned when a LoRA adapter cannot be loaded.
+var ErrAdapterLoad = errors.New("llama: unable to load LoRA adapter")
+
+func loadLoraFuncs(lib loader.Library) error {
+	var ok [5]bool
+
+	adapterLoraInitFunc, ok[0] = loader.PrepOptional(lib, "llama_adapter_lora_init", &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer)
+	adapterLoraFreeFunc, ok[1] = loader.PrepOptional(lib, "llama_adapter_lora_free", &ffi.TypeVoid, &ffi.TypePointer)
+	setAdapterLoraFunc, ok[2] = loader.PrepOptional(lib, "llama_set_adapter_lora", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeFloat)
+	rmAdapterLoraFunc, ok[3] = loader.PrepOptional(lib, "llama_rm_adapter_lora", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypePointer)
+	clearAdapterLoraFunc, ok[4] = loader.PrepOptional(lib, "llama_clear_adapter_lora", &ffi.TypeVoid, &ffi.TypePointer)
+	capabilities.LoRA = ok == [5]bool{true, true, true, true, true}
+
+	var meta [3]bool
+	adapterMetaCountFunc, meta[0] = loader.PrepOptional(lib, "llama_adapter_meta_count", &ffi.TypeSint32, &ffi.TypePointer)
+	adapterMetaKeyByIndexFunc, meta[1] = loader.PrepOptional(lib, "llama_adapter_meta_key_by_index", &ffi.TypeSint32, &ffi.TypePointer,
+		&ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeUint64)
+	adapterMetaValStrFunc, meta[2] = loader.PrepOptional(lib, "llama_adapter_meta_val_str", &ffi.TypeSint32, &ffi.TypePointer,
+		&ffi.TypePointer, &ffi.TypePointer, &ffi.TypeUint64)
+	adapterMeta = capabilities.LoRA && meta == [3]bool{true, true, true}
+
+	return nil
+}
+
+// AdapterLoraInit loads a LoRA adapter for model from a GGUF file. The adapter can then be applied to
+// any Context of the model with [SetAdapterLora].
+// It returns [ErrNotSupported] if the loaded llama.cpp library does not support LoRA adapters.
+func AdapterLoraInit(model Model, path string) (AdapterLora, error) {
+	if !capabilities.LoRA {
+		return 0, notSupported("llama_adapter_lora_init")
+	}
+
+	var adapter AdapterLora
+	file := &[]byte(path + "\x00")[0]
+	adapterLoraInitFunc.Call(unsafe.Pointer(&adapter), unsafe.Pointer(&model), unsafe.Pointer(&file))
+	if adapter == 0 {
+		return 0, fmt.Errorf("%w %q", ErrAdapterLoad, path)
+	}
+
+	return adapter, nil
+}
+
+// AdapterLoraFree frees a LoRA adapter. Adapters are also freed along with their Model, so
+// this is only needed to unload an adapter before the Model is freed.
+func AdapterLoraFree(adapter AdapterLora) {
+	if !capabilities.LoRA {
+		return
+	}
+
+	adapterLoraFreeFunc.Call(nil, unsafe.Pointer(&adapter))
+}
+
+// SetAdapterLora applies a LoRA adapter to a Context with the given scale, or changes its scale
+// if it is already applied. Each Context of a Model can use a different set of adapters.
+func SetAdapterLora(ctx Context, adapter AdapterLora, scale float32) error {
+	if !capabilities.LoRA {
+		return notSupported("llama_set_adapter_lora")
+	}
+
+	var result ffi.Arg
+	setAdapterLoraFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx), unsafe.Pointer(&adapter), &scale)
+	if int32(result) != 0 {
+		return fmt.Errorf("llama: unable to set LoRA adapter: %d", int32(result))
+	}
+
+	return nil
+}
+
+// RmAdapterLora removes a LoRA adapter from a Context. It returns an error if the adapter was not applied to it.
+func RmAdapterLora(ctx Context, adapter AdapterLora) error {
+	if !capabilities.LoRA {
+		return notSupported("llama_rm_adapter_lora")
+	}
+
+	var result ffi.Arg
+	rmAdapterLoraFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx), unsafe.Pointer(&adapter))
+	if int32(result) != 0 {
+		return errors.New("llama: LoRA adapter is not applied to the context")
+	}
+
+	return nil
+}
+
+// ClearAdapterLora removes every LoRA adapter from a Context.
+func ClearAdapterLora(ctx Context) error {
+	if !capabilities.LoRA {
+		return notSupported("llama_clear_adapter_lora")
+	}
+
+	clearAdapterLoraFunc.Call(nil, unsafe.Pointer(&ctx))
+
+	return nil
+}
+
+// AdapterMetaCount returns the number of metadata key/value pairs of a LoRA adapter, or -1
+// if the loaded llama.cpp library cannot return the metadata of adapters.
+func AdapterMetaCount(adapter AdapterLora) int32 {
+	if !adapterMeta {
+		return -1
+	}
+
+	var result ffi.Arg
+	adapterMetaCountFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&adapter))
+
+	return int32(result)
+}
+
+// AdapterMetaKeyByIndex returns the key of the metadata of a LoRA adapter at index i, or false if there is none.
+func AdapterMetaKeyByIndex(adapter AdapterLora, i int32) (string, bool) {
+	if !adapterMeta {
+		return "", false
+	}
+
+	return metaString(func(buf *byte, size uint64) int32 {
+		var result ffi.Arg
+		adapterMetaKeyByIndexFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&adapter), &i, unsafe.Pointer(&buf), &size)
+
+		return int32(result)
+	})
+}
+
+// AdapterMetaValStr returns the value of a metadata key of a LoRA adapter as a string, such as
+// "adapter.lora.alpha", or false if the adapter does not have the key.
+func AdapterMetaValStr(adapter AdapterLora, key string) (string, bool) {
+	if !adapterMeta {
+		return "", false
+	}
+
+	k := &[]byte(key + "\x00")[0]
+
+	return metaString(func(buf *byte, size uint64) int32 {
+		var result ffi.Arg
+		adapterMetaValStrFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&adapter), unsafe.Pointer(&k), unsafe.Pointer(&buf), &size)
+
+		return int32(result)
+	})
+}
+
+// AdapterMetadata returns all of the metadata of a LoRA adapter as strings.
+func AdapterMetadata(adapter AdapterLora) map[string]string {
+	n := AdapterMetaCount(adapter)
+
+	meta := make(map[string]string, max(n, 0))
+	for i := range n {
+		key, ok := AdapterMetaKeyByIndex(adapter, i)
+		if !ok {
+			continue
+		}
+
+		if val, ok := AdapterMetaValStr(adapter, key); ok {
+			meta[key] = val
+		}
+	}
+
+	return meta
+}
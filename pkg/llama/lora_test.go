@@ -0,0 +1,121 @@
+package llama
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/hybridgroup/yzma/pkg/fake"
+)
+
+func testLoraFile(t *testing.T) string {
+	if os.Getenv("YZMA_LIB") == "" {
+		return "fake-lora.gguf"
+	}
+
+	if os.Getenv("YZMA_TEST_LORA") == "" {
+		t.Skip("YZMA_TEST_LORA not set")
+	}
+
+	return os.Getenv("YZMA_TEST_LORA")
+}
+
+func TestAdapterLora(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal("unable to load model", err)
+	}
+	defer model.Close()
+
+	adapter, err := AdapterLoraInit(model, testLoraFile(t))
+	if err != nil {
+		t.Fatal("unable to load adapter", err)
+	}
+	defer AdapterLoraFree(adapter)
+
+	lctx, err := NewContext(model, ContextDefaultParams())
+	if err != nil {
+		t.Fatal("unable to create context", err)
+	}
+	defer lctx.Close()
+
+	if err := SetAdapterLora(lctx, adapter, 0.5); err != nil {
+		t.Fatal("unable to set adapter", err)
+	}
+
+	if err := RmAdapterLora(lctx, adapter); err != nil {
+		t.Fatal("unable to remove adapter", err)
+	}
+
+	if err := RmAdapterLora(lctx, adapter); err == nil {
+		t.Fatal("expected error removing an adapter that is not applied")
+	}
+
+	if err := SetAdapterLora(lctx, adapter, 1); err != nil {
+		t.Fatal("unable to set adapter", err)
+	}
+
+	if err := ClearAdapterLora(lctx); err != nil {
+		t.Fatal("unable to clear adapters", err)
+	}
+
+	if _, err := AdapterLoraInit(model, ""); !errors.Is(err, ErrAdapterLoad) {
+		t.Fatal("expected ErrAdapterLoad", err)
+	}
+}
+
+func TestAdapterMetadata(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal("unable to load model", err)
+	}
+	defer model.Close()
+
+	adapter, err := AdapterLoraInit(model, testLoraFile(t))
+	if err != nil {
+		t.Fatal("unable to load adapter", err)
+	}
+	defer AdapterLoraFree(adapter)
+
+	meta := AdapterMetadata(adapter)
+	if len(meta) != int(AdapterMetaCount(adapter)) {
+		t.Fatal("wrong number of metadata keys", meta)
+	}
+
+	if typ, ok := AdapterMetaValStr(adapter, "adapter.type"); !ok || typ != "lora" {
+		t.Fatal("wrong adapter.type", typ, ok)
+	}
+
+	if _, ok := AdapterMetaValStr(adapter, "no.such.key"); ok {
+		t.Fatal("expected missing key")
+	}
+}
+
+func TestAdapterLoraNotSupported(t *testing.T) {
+	if err := Load(fake.New().Omit("llama_adapter_lora_init")); err != nil {
+		t.Fatal("missing optional functions should not be an error", err)
+	}
+	defer Load(fake.New())
+
+	if GetCapabilities().LoRA {
+		t.Fatal("LoRA reported as supported")
+	}
+
+	if _, err := AdapterLoraInit(0, "lora.gguf"); !errors.Is(err, ErrNotSupported) {
+		t.Fatal("expected ErrNotSupported", err)
+	}
+
+	if err := SetAdapterLora(0, 0, 1); !errors.Is(err, ErrNotSupported) {
+		t.Fatal("expected ErrNotSupported", err)
+	}
+
+	if n := AdapterMetaCount(0); n != -1 {
+		t.Fatal("expected -1 metadata keys", n)
+	}
+}
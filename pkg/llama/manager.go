@@ -0,0 +1,207 @@
+package llama
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"unsafe"
+)
+
+// ModelManager loads each combination of model path and [ModelParams] once, and shares it between
+// everyone that needs it. Each user of a model holds a [ModelRef], and the model is only freed once
+// every ModelRef for it has been released.
+//
+// Models that are no longer in use are kept loaded while the total size of the loaded models, as
+// reported by [ModelSize], is within the memory budget, so that they can be used again without being
+// reloaded. When the budget is exceeded, the least recently used idle models are freed. Models that
+// are in use are never freed, so the budget can be exceeded while they are.
+//
+// Models are shared by their path, the value fields of their ModelParams and their kv overrides.
+// The pointer fields of ModelParams, such as ProgressCallback and TensorSplit, cannot be compared,
+// so they must be 0 or nil.
+//
+// A ModelManager is safe for concurrent use.
+type ModelManager struct {
+	budget uint64
+
+	mu     sync.Mutex
+	models map[modelKey]*managedModel
+	idle   list.List // of *managedModel, most recently used first
+	size   uint64
+}
+
+// modelKey identifies a model by its path, ModelParams with the pointer fields set to 0, and the
+// contents of its kv overrides.
+type modelKey struct {
+	path      string
+	params    ModelParams
+	overrides string
+}
+
+type managedModel struct {
+	key   modelKey
+	model Model
+	size  uint64
+	refs  int
+	idle  *list.Element
+
+	// ready is closed once the model has been loaded, or has failed to load with err.
+	ready chan struct{}
+	err   error
+}
+
+// ModelRef is a reference to a Model that is loaded by a [ModelManager].
+type ModelRef struct {
+	manager *ModelManager
+	entry   *managedModel
+	once    sync.Once
+}
+
+// NewModelManager returns a ModelManager that keeps idle models loaded while their total size is
+// at most budget bytes. If budget is 0, models are freed as soon as they are no longer in use.
+func NewModelManager(budget uint64) *ModelManager {
+	return &ModelManager{
+		budget: budget,
+		models: make(map[modelKey]*managedModel),
+	}
+}
+
+// Acquire returns a reference to the model at path loaded with params, loading it if needed.
+// If the model is being loaded by another caller, Acquire waits for it. Call [ModelRef.Release]
+// once the model is no longer needed.
+func (m *ModelManager) Acquire(path string, params ModelParams) (*ModelRef, error) {
+	return m.AcquireWithOverrides(path, params, nil)
+}
+
+// AcquireWithOverrides is like [ModelManager.Acquire], but loads the model with the kv overrides
+// like [LoadModelWithOverrides]. Models loaded with different overrides are not shared.
+func (m *ModelManager) AcquireWithOverrides(path string, params ModelParams, overrides []KvOverride) (*ModelRef, error) {
+	if params.Devices != 0 || params.TensorBuftOverrides != 0 || params.TensorSplit != nil ||
+		params.ProgressCallback != 0 || params.ProgressCallbackUserData != 0 || params.KvOverrides != 0 {
+		return nil, fmt.Errorf("%w %q: the ModelManager does not support pointer fields in ModelParams", ErrModelLoad, path)
+	}
+
+	data, err := marshalKvOverrides(overrides)
+	if err != nil {
+		return nil, err
+	}
+
+	size := len(data) * int(unsafe.Sizeof(kvOverride{}))
+	key := modelKey{path: path, params: params, overrides: string(unsafe.Slice((*byte)(unsafe.Pointer(&data[0])), size))}
+
+	m.mu.Lock()
+	if e, ok := m.models[key]; ok {
+		e.refs++
+		if e.idle != nil {
+			m.idle.Remove(e.idle)
+			e.idle = nil
+		}
+		m.mu.Unlock()
+
+		<-e.ready
+		if e.err != nil {
+			return nil, e.err
+		}
+
+		return &ModelRef{manager: m, entry: e}, nil
+	}
+
+	e := &managedModel{key: key, refs: 1, ready: make(chan struct{})}
+	m.models[key] = e
+	m.mu.Unlock()
+
+	model, err := loadModelWithOverrides(path, params, data)
+
+	m.mu.Lock()
+	if err != nil {
+		e.err = err
+		delete(m.models, key)
+	} else {
+		e.model = model
+		e.size = ModelSize(model)
+		m.size += e.size
+		m.evict()
+	}
+	close(e.ready)
+	m.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ModelRef{manager: m, entry: e}, nil
+}
+
+// Len returns the number of models that are loaded, including idle models.
+func (m *ModelManager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for _, e := range m.models {
+		if e.model != 0 {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Size returns the total size in bytes of the models that are loaded, including idle models.
+func (m *ModelManager) Size() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.size
+}
+
+// Purge frees every model that is not in use.
+func (m *ModelManager) Purge() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for m.idle.Len() > 0 {
+		m.free(m.idle.Remove(m.idle.Back()).(*managedModel))
+	}
+}
+
+// release drops a reference to a model, and frees it or makes it idle if it was the last one.
+func (m *ModelManager) release(e *managedModel) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e.refs--
+	if e.refs > 0 {
+		return
+	}
+
+	e.idle = m.idle.PushFront(e)
+	m.evict()
+}
+
+// evict frees the least recently used idle models until the loaded models fit in the budget.
+func (m *ModelManager) evict() {
+	for m.idle.Len() > 0 && (m.budget == 0 || m.size > m.budget) {
+		m.free(m.idle.Remove(m.idle.Back()).(*managedModel))
+	}
+}
+
+func (m *ModelManager) free(e *managedModel) {
+	e.idle = nil
+	delete(m.models, e.key)
+	m.size -= e.size
+	ModelFree(e.model)
+}
+
+// Model returns the Model. It must not be used after the ModelRef is released.
+func (r *ModelRef) Model() Model {
+	return r.entry.model
+}
+
+// Release releases the reference to the Model. Every Context created from the Model with this
+// reference must be freed first. Calling Release more than once has no effect.
+func (r *ModelRef) Release() {
+	r.once.Do(func() {
+		r.manager.release(r.entry)
+	})
+}
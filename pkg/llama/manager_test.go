@@ -0,0 +1,205 @@
+package llama
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// testManagerParams returns different ModelParams for each i, so that the same model file is loaded more than once.
+func testManagerParams(i int32) ModelParams {
+	params := ModelDefaultParams()
+	params.NGpuLayers = i
+
+	return params
+}
+
+func TestModelManagerShared(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	m := NewModelManager(0)
+
+	a, err := m.Acquire(testModelFile(t), testManagerParams(0))
+	if err != nil {
+		t.Fatal("unable to acquire model", err)
+	}
+
+	b, err := m.Acquire(testModelFile(t), testManagerParams(0))
+	if err != nil {
+		t.Fatal("unable to acquire model", err)
+	}
+
+	if a.Model() != b.Model() || m.Len() != 1 {
+		t.Fatal("model should be loaded once")
+	}
+
+	if m.Size() != ModelSize(a.Model()) {
+		t.Fatal("wrong size", m.Size())
+	}
+
+	a.Release()
+	a.Release()
+	if m.Len() != 1 {
+		t.Fatal("model freed while still in use")
+	}
+
+	lctx, err := NewContext(b.Model(), ContextDefaultParams())
+	if err != nil {
+		t.Fatal("unable to create context", err)
+	}
+	lctx.Close()
+
+	b.Release()
+	if m.Len() != 0 || m.Size() != 0 {
+		t.Fatal("model should be freed with a budget of 0", m.Len(), m.Size())
+	}
+}
+
+func TestModelManagerEvict(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	refs := make([]*ModelRef, 3)
+	size := uint64(0)
+
+	m := NewModelManager(0)
+	for i := range refs {
+		ref, err := m.Acquire(testModelFile(t), testManagerParams(int32(i)))
+		if err != nil {
+			t.Fatal("unable to acquire model", err)
+		}
+		size = max(size, ModelSize(ref.Model()))
+		ref.Release()
+	}
+
+	// room for two idle models
+	m = NewModelManager(2 * size)
+	for i := range refs {
+		ref, err := m.Acquire(testModelFile(t), testManagerParams(int32(i)))
+		if err != nil {
+			t.Fatal("unable to acquire model", err)
+		}
+		refs[i] = ref
+	}
+
+	if m.Len() != 3 {
+		t.Fatal("models in use should not be evicted", m.Len())
+	}
+
+	for _, ref := range refs {
+		ref.Release()
+	}
+
+	if m.Len() != 2 {
+		t.Fatal("least recently used model should be evicted", m.Len())
+	}
+
+	// model 0 was the least recently used, so model 1 is still loaded
+	ref, err := m.Acquire(testModelFile(t), testManagerParams(1))
+	if err != nil {
+		t.Fatal("unable to acquire model", err)
+	}
+
+	if ref.Model() != refs[1].Model() {
+		t.Fatal("idle model should be reused")
+	}
+	ref.Release()
+
+	m.Purge()
+	if m.Len() != 0 || m.Size() != 0 {
+		t.Fatal("idle models should be purged", m.Len(), m.Size())
+	}
+}
+
+func TestModelManagerConcurrent(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	m := NewModelManager(0)
+
+	var wg sync.WaitGroup
+	refs := make([]*ModelRef, 8)
+	for i := range refs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ref, err := m.Acquire(testModelFile(t), testManagerParams(0))
+			if err != nil {
+				t.Error("unable to acquire model", err)
+				return
+			}
+			refs[i] = ref
+		}()
+	}
+	wg.Wait()
+
+	for _, ref := range refs {
+		if ref == nil || ref.Model() != refs[0].Model() {
+			t.Fatal("model should be loaded once")
+		}
+	}
+
+	for _, ref := range refs {
+		ref.Release()
+	}
+
+	if m.Len() != 0 {
+		t.Fatal("model should be freed", m.Len())
+	}
+}
+
+func TestModelManagerLoadError(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	m := NewModelManager(0)
+	if _, err := m.Acquire("", ModelDefaultParams()); !errors.Is(err, ErrModelLoad) {
+		t.Fatal("expected ErrModelLoad", err)
+	}
+
+	if m.Len() != 0 {
+		t.Fatal("failed model should not be kept", m.Len())
+	}
+}
+
+func TestModelManagerOverrides(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	m := NewModelManager(0)
+
+	// the same overrides in separate slices share the model
+	a, err := m.AcquireWithOverrides(testModelFile(t), testManagerParams(0), []KvOverride{KvOverrideStr("general.name", "A")})
+	if err != nil {
+		t.Fatal("unable to acquire model", err)
+	}
+	defer a.Release()
+
+	b, err := m.AcquireWithOverrides(testModelFile(t), testManagerParams(0), []KvOverride{KvOverrideStr("general.name", "A")})
+	if err != nil {
+		t.Fatal("unable to acquire model", err)
+	}
+	defer b.Release()
+
+	c, err := m.AcquireWithOverrides(testModelFile(t), testManagerParams(0), []KvOverride{KvOverrideStr("general.name", "C")})
+	if err != nil {
+		t.Fatal("unable to acquire model", err)
+	}
+	defer c.Release()
+
+	if a.Model() != b.Model() || a.Model() == c.Model() || m.Len() != 2 {
+		t.Fatal("models should be shared by their overrides", m.Len())
+	}
+
+	if name, _ := ModelMetaValStr(c.Model(), "general.name"); name != "C" {
+		t.Fatal("general.name should be overridden, got", name)
+	}
+
+	params := testManagerParams(0)
+	params.ProgressCallbackUserData = 1
+	if _, err := m.Acquire(testModelFile(t), params); !errors.Is(err, ErrModelLoad) {
+		t.Fatal("expected ErrModelLoad for a pointer field", err)
+	}
+}
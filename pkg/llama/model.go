@@ -1,8 +1,10 @@
 package llama
 
 import (
+	"errors"
 	"unsafe"
 
+	"github.com/hybridgroup/yzma/pkg/loader"
 	"github.com/hybridgroup/yzma/pkg/utils"
 	"github.com/jupiterrider/ffi"
 )
@@ -45,48 +47,137 @@ var (
 
 	// LLAMA_API int32_t llama_model_n_ctx_train(const struct llama_model * model);
 	modelNCtxTrainFunc ffi.Fun
+
+	// LLAMA_API int32_t llama_model_n_embd     (const struct llama_model * model);
+	modelNEmbdFunc ffi.Fun
+
+	// LLAMA_API int32_t llama_model_n_layer    (const struct llama_model * model);
+	modelNLayerFunc ffi.Fun
+
+	// LLAMA_API int32_t llama_model_n_head     (const struct llama_model * model);
+	modelNHeadFunc ffi.Fun
+
+	// LLAMA_API int32_t llama_model_n_head_kv  (const struct llama_model * model);
+	modelNHeadKVFunc ffi.Fun
+
+	// LLAMA_API enum llama_rope_type llama_model_rope_type(const struct llama_model * model);
+	modelRopeTypeFunc ffi.Fun
+
+	// LLAMA_API float llama_model_rope_freq_scale_train(const struct llama_model * model);
+	modelRopeFreqScaleTrainFunc ffi.Fun
+
+	// LLAMA_API int32_t llama_model_meta_count(const struct llama_model * model);
+	modelMetaCountFunc ffi.Fun
+
+	// LLAMA_API int32_t llama_model_meta_key_by_index(const struct llama_model * model, int32_t i, char * buf, size_t buf_size);
+	modelMetaKeyByIndexFunc ffi.Fun
+
+	// LLAMA_API int32_t llama_model_meta_val_str(const struct llama_model * model, const char * key, char * buf, size_t buf_size);
+	modelMetaValStrFunc ffi.Fun
+
+	// LLAMA_API int32_t llama_model_desc(const struct llama_model * model, char * buf, size_t buf_size);
+	modelDescFunc ffi.Fun
+
+	// LLAMA_API uint64_t llama_model_size(const struct llama_model * model);
+	modelSizeFunc ffi.Fun
+
+	// LLAMA_API uint64_t llama_model_n_params(const struct llama_model * model);
+	modelNParamsFunc ffi.Fun
 )
 
-func loadModelFuncs(lib ffi.Lib) error {
-	var err error
+func loadModelFuncs(lib loader.Library) error {
+	var (
+		err  error
+		errs []error
+	)
 
 	if modelDefaultParamsFunc, err = lib.Prep("llama_model_default_params", &FFITypeModelParams); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if modelLoadFromFileFunc, err = lib.Prep("llama_model_load_from_file", &ffi.TypePointer, &ffi.TypePointer, &FFITypeModelParams); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if modelFreeFunc, err = lib.Prep("llama_model_free", &ffi.TypeVoid, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if initFromModelFunc, err = lib.Prep("llama_init_from_model", &ffi.TypePointer, &ffi.TypePointer, &FFITypeContextParams); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if modelChatTemplateFunc, err = lib.Prep("llama_model_chat_template", &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if modelHasEncoderFunc, err = lib.Prep("llama_model_has_encoder", &ffi.TypeUint8, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if modelHasDecoderFunc, err = lib.Prep("llama_model_has_decoder", &ffi.TypeUint8, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if modelDecoderStartTokenFunc, err = lib.Prep("llama_model_decoder_start_token", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if modelNCtxTrainFunc, err = lib.Prep("llama_model_n_ctx_train", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
+	}
+
+	if modelNEmbdFunc, err = lib.Prep("llama_model_n_embd", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if modelNLayerFunc, err = lib.Prep("llama_model_n_layer", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if modelNHeadFunc, err = lib.Prep("llama_model_n_head", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if modelNHeadKVFunc, err = lib.Prep("llama_model_n_head_kv", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if modelRopeTypeFunc, err = lib.Prep("llama_model_rope_type", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if modelRopeFreqScaleTrainFunc, err = lib.Prep("llama_model_rope_freq_scale_train", &ffi.TypeFloat, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
 	}
 
-	return nil
+	if modelMetaCountFunc, err = lib.Prep("llama_model_meta_count", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if modelMetaKeyByIndexFunc, err = lib.Prep("llama_model_meta_key_by_index", &ffi.TypeSint32, &ffi.TypePointer,
+		&ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeUint64); err != nil {
+		errs = append(errs, err)
+	}
+
+	if modelMetaValStrFunc, err = lib.Prep("llama_model_meta_val_str", &ffi.TypeSint32, &ffi.TypePointer,
+		&ffi.TypePointer, &ffi.TypePointer, &ffi.TypeUint64); err != nil {
+		errs = append(errs, err)
+	}
+
+	if modelDescFunc, err = lib.Prep("llama_model_desc", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeUint64); err != nil {
+		errs = append(errs, err)
+	}
+
+	if modelSizeFunc, err = lib.Prep("llama_model_size", &ffi.TypeUint64, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if modelNParamsFunc, err = lib.Prep("llama_model_n_params", &ffi.TypeUint64, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
 }
 
 // ModelDefaultParams returns default parameters for loading a Model.
@@ -153,6 +244,7 @@ func ModelDecoderStartToken(model Model) Token {
 	return Token(result)
 }
 
+// ModelNCtxTrain returns the size of the context that the Model was trained with.
 func ModelNCtxTrain(model Model) int32 {
 	var result ffi.Arg
 	modelNCtxTrainFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&model))
@@ -160,6 +252,130 @@ func ModelNCtxTrain(model Model) int32 {
 	return int32(result)
 }
 
+// ModelNEmbd returns the size of the Model's embeddings.
+func ModelNEmbd(model Model) int32 {
+	var result ffi.Arg
+	modelNEmbdFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&model))
+
+	return int32(result)
+}
+
+// ModelNLayer returns the number of layers in the Model.
+func ModelNLayer(model Model) int32 {
+	var result ffi.Arg
+	modelNLayerFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&model))
+
+	return int32(result)
+}
+
+// ModelNHead returns the number of attention heads in the Model.
+func ModelNHead(model Model) int32 {
+	var result ffi.Arg
+	modelNHeadFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&model))
+
+	return int32(result)
+}
+
+// ModelNHeadKV returns the number of key/value heads in the Model.
+func ModelNHeadKV(model Model) int32 {
+	var result ffi.Arg
+	modelNHeadKVFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&model))
+
+	return int32(result)
+}
+
+// ModelRopeType returns the type of RoPE used by the Model.
+func ModelRopeType(model Model) RopeType {
+	var result ffi.Arg
+	modelRopeTypeFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&model))
+
+	return RopeType(int32(result))
+}
+
+// ModelRopeFreqScaleTrain returns the RoPE frequency scaling factor that the Model was trained with.
+func ModelRopeFreqScaleTrain(model Model) float32 {
+	var result float32
+	modelRopeFreqScaleTrainFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&model))
+
+	return result
+}
+
+// ModelMetaCount returns the number of metadata key/value pairs in the Model.
+func ModelMetaCount(model Model) int32 {
+	var result ffi.Arg
+	modelMetaCountFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&model))
+
+	return int32(result)
+}
+
+// ModelMetaKeyByIndex returns the metadata key at index i, or false if there is none.
+func ModelMetaKeyByIndex(model Model, i int32) (string, bool) {
+	return metaString(func(buf *byte, size uint64) int32 {
+		var result ffi.Arg
+		modelMetaKeyByIndexFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&model), &i, unsafe.Pointer(&buf), &size)
+
+		return int32(result)
+	})
+}
+
+// ModelMetaValStr returns the value of a metadata key as a string, or false if the Model does not have the key.
+// Array values are not returned in full, only their type and length.
+func ModelMetaValStr(model Model, key string) (string, bool) {
+	k := &[]byte(key + "\x00")[0]
+
+	return metaString(func(buf *byte, size uint64) int32 {
+		var result ffi.Arg
+		modelMetaValStrFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&model), unsafe.Pointer(&k), unsafe.Pointer(&buf), &size)
+
+		return int32(result)
+	})
+}
+
+// ModelDesc returns a short description of the Model, such as "llama 135M Q2_K - Medium".
+func ModelDesc(model Model) string {
+	desc, _ := metaString(func(buf *byte, size uint64) int32 {
+		var result ffi.Arg
+		modelDescFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&model), unsafe.Pointer(&buf), &size)
+
+		return int32(result)
+	})
+
+	return desc
+}
+
+// ModelSize returns the total size of all of the tensors in the Model in bytes.
+func ModelSize(model Model) uint64 {
+	var result ffi.Arg
+	modelSizeFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&model))
+
+	return uint64(result)
+}
+
+// ModelNParams returns the total number of parameters in the Model.
+func ModelNParams(model Model) uint64 {
+	var result ffi.Arg
+	modelNParamsFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&model))
+
+	return uint64(result)
+}
+
+// metaString calls a function that formats a string into buf like snprintf, and returns the string.
+// If buf is too small, fn is called again with a buffer of the size that it needs.
+func metaString(fn func(buf *byte, size uint64) int32) (string, bool) {
+	buf := make([]byte, 256)
+	n := fn(unsafe.SliceData(buf), uint64(len(buf)))
+	if n < 0 {
+		return "", false
+	}
+
+	if int(n) >= len(buf) {
+		buf = make([]byte, n+1)
+		n = fn(unsafe.SliceData(buf), uint64(len(buf)))
+	}
+
+	return string(buf[:n]), true
+}
+
 // Warmup is to warm-up a model.
 func Warmup(lctx Context, model Model) {
 	vocab := ModelGetVocab(model)
@@ -0,0 +1,76 @@
+package llama
+
+import "strconv"
+
+// ModelInfo describes a loaded Model.
+type ModelInfo struct {
+	Description  string // short description, such as "llama 135M Q2_K - Medium"
+	Name         string // general.name from the metadata
+	Architecture string // general.architecture from the metadata, such as "llama"
+	Ftype        Ftype  // general.file_type from the metadata, or -1 if it is not set
+
+	Size    uint64 // total size of the tensors in bytes
+	NParams uint64 // total number of parameters
+
+	NCtxTrain int32 // context size that the model was trained with
+	NEmbd     int32 // embedding size
+	NLayer    int32 // number of layers
+	NHead     int32 // number of attention heads
+	NHeadKV   int32 // number of key/value heads
+
+	RopeType           RopeType
+	RopeFreqScaleTrain float32
+
+	HasEncoder bool
+	HasDecoder bool
+}
+
+// ModelGetInfo returns information about a Model, such as its architecture, size and quantization.
+func ModelGetInfo(model Model) ModelInfo {
+	info := ModelInfo{
+		Description:        ModelDesc(model),
+		Ftype:              -1,
+		Size:               ModelSize(model),
+		NParams:            ModelNParams(model),
+		NCtxTrain:          ModelNCtxTrain(model),
+		NEmbd:              ModelNEmbd(model),
+		NLayer:             ModelNLayer(model),
+		NHead:              ModelNHead(model),
+		NHeadKV:            ModelNHeadKV(model),
+		RopeType:           ModelRopeType(model),
+		RopeFreqScaleTrain: ModelRopeFreqScaleTrain(model),
+		HasEncoder:         ModelHasEncoder(model),
+		HasDecoder:         ModelHasDecoder(model),
+	}
+
+	info.Name, _ = ModelMetaValStr(model, "general.name")
+	info.Architecture, _ = ModelMetaValStr(model, "general.architecture")
+
+	if v, ok := ModelMetaValStr(model, "general.file_type"); ok {
+		if ftype, err := strconv.ParseInt(v, 10, 32); err == nil {
+			info.Ftype = Ftype(ftype)
+		}
+	}
+
+	return info
+}
+
+// ModelMetadata returns all of the GGUF metadata of a Model as strings.
+// Array values only have their type and length, as reported by llama.cpp.
+func ModelMetadata(model Model) map[string]string {
+	n := ModelMetaCount(model)
+
+	meta := make(map[string]string, n)
+	for i := range n {
+		key, ok := ModelMetaKeyByIndex(model, i)
+		if !ok {
+			continue
+		}
+
+		if val, ok := ModelMetaValStr(model, key); ok {
+			meta[key] = val
+		}
+	}
+
+	return meta
+}
@@ -0,0 +1,94 @@
+package llama
+
+import (
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestModelGetInfo(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal("unable to load model", err)
+	}
+	defer model.Close()
+
+	info := ModelGetInfo(model)
+	if info.Description == "" || info.Architecture == "" {
+		t.Fatal("missing description or architecture", info)
+	}
+
+	if info.NLayer <= 0 || info.NEmbd <= 0 || info.NHead <= 0 || info.NParams == 0 || info.Size == 0 {
+		t.Fatal("missing model dimensions", info)
+	}
+
+	if info.RopeFreqScaleTrain <= 0 {
+		t.Fatal("invalid rope freq scale", info.RopeFreqScaleTrain)
+	}
+}
+
+func TestModelMetadata(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal("unable to load model", err)
+	}
+	defer model.Close()
+
+	meta := ModelMetadata(model)
+	if len(meta) != int(ModelMetaCount(model)) {
+		t.Fatal("expected every metadata key", len(meta), ModelMetaCount(model))
+	}
+
+	if meta["general.architecture"] != ModelGetInfo(model).Architecture {
+		t.Fatal("unexpected architecture", meta["general.architecture"])
+	}
+
+	// values longer than the initial buffer are returned in full
+	if template, ok := meta["tokenizer.chat_template"]; ok && template != ModelChatTemplate(model, "") {
+		t.Fatal("chat template does not match", template)
+	}
+
+	if _, ok := ModelMetaValStr(model, "no.such.key"); ok {
+		t.Fatal("expected missing key")
+	}
+
+	if _, ok := ModelMetaKeyByIndex(model, ModelMetaCount(model)); ok {
+		t.Fatal("expected missing index")
+	}
+}
+
+func TestMetaString(t *testing.T) {
+	long := strings.Repeat("x", 1000)
+
+	calls := 0
+	s, ok := metaString(func(buf *byte, size uint64) int32 {
+		calls++
+		out := unsafe.Slice(buf, size)
+		out[copy(out[:size-1], long)] = 0
+
+		return int32(len(long))
+	})
+
+	if !ok || s != long || calls != 2 {
+		t.Fatal("long string not returned in full", len(s), calls)
+	}
+}
+
+func TestFtypeString(t *testing.T) {
+	for ftype, want := range map[Ftype]string{
+		FTYPE_MOSTLY_Q4_K_M:               "Q4_K - Medium",
+		FTYPE_MOSTLY_F16:                  "F16",
+		FTYPE_MOSTLY_Q8_0 | FTYPE_GUESSED: "Q8_0 (guessed)",
+		Ftype(4):                          "unknown, may not work",
+	} {
+		if ftype.String() != want {
+			t.Errorf("%d is %q, expected %q", int32(ftype), ftype.String(), want)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package llama
+
+import (
+	"errors"
+	"unsafe"
+
+	"github.com/hybridgroup/yzma/pkg/loader"
+	"github.com/jupiterrider/ffi"
+)
+
+var (
+	// LLAMA_API const struct llama_model * llama_get_model(const struct llama_context * ctx);
+	getModelFunc ffi.Fun
+
+	// LLAMA_API enum llama_pooling_type llama_pooling_type(const struct llama_context * ctx);
+	poolingTypeFunc ffi.Fun
+
+	// LLAMA_API uint32_t llama_model_n_cls_out(const struct llama_model * model);
+	modelNClsOutFunc ffi.Fun
+
+	// LLAMA_API float * llama_get_logits(struct llama_context * ctx);
+	getLogitsFunc ffi.Fun
+
+	// LLAMA_API float * llama_get_logits_ith(struct llama_context * ctx, int32_t i);
+	getLogitsIthFunc ffi.Fun
+
+	// LLAMA_API float * llama_get_embeddings(struct llama_context * ctx);
+	getEmbeddingsFunc ffi.Fun
+
+	// LLAMA_API float * llama_get_embeddings_ith(struct llama_context * ctx, int32_t i);
+	getEmbeddingsIthFunc ffi.Fun
+
+	// LLAMA_API float * llama_get_embeddings_seq(struct llama_context * ctx, llama_seq_id seq_id);
+	getEmbeddingsSeqFunc ffi.Fun
+
+	// nClsOut is true when the loaded library can return the number of outputs of a classifier model.
+	nClsOut bool
+)
+
+func loadOutputFuncs(lib loader.Library) error {
+	var (
+		err  error
+		errs []error
+	)
+	if getModelFunc, err = lib.Prep("llama_get_model", &ffi.TypePointer, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if poolingTypeFunc, err = lib.Prep("llama_pooling_type", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	modelNClsOutFunc, nClsOut = loader.PrepOptional(lib, "llama_model_n_cls_out", &ffi.TypeUint32, &ffi.TypePointer)
+
+	if getLogitsFunc, err = lib.Prep("llama_get_logits", &ffi.TypePointer, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if getLogitsIthFunc, err = lib.Prep("llama_get_logits_ith", &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if getEmbeddingsFunc, err = lib.Prep("llama_get_embeddings", &ffi.TypePointer, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if getEmbeddingsIthFunc, err = lib.Prep("llama_get_embeddings_ith", &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if getEmbeddingsSeqFunc, err = lib.Prep("llama_get_embeddings_seq", &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// GetModel returns the Model that a Context was created for.
+func GetModel(ctx Context) Model {
+	var model Model
+	getModelFunc.Call(unsafe.Pointer(&model), unsafe.Pointer(&ctx))
+
+	return model
+}
+
+// GetPoolingType returns how the embeddings of a sequence are pooled by a Context.
+func GetPoolingType(ctx Context) PoolingType {
+	var result ffi.Arg
+	poolingTypeFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx))
+
+	return PoolingType(int32(result))
+}
+
+// ModelNClsOut returns the number of outputs of a classifier model, such as a reranker.
+// It returns 1 if the loaded llama.cpp library cannot report it.
+func ModelNClsOut(model Model) uint32 {
+	if !nClsOut {
+		return 1
+	}
+
+	var result ffi.Arg
+	modelNClsOutFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&model))
+
+	return uint32(result)
+}
+
+// floats returns a slice that views n floats at p, or nil if p is NULL.
+func floats(p *float32, n int) []float32 {
+	if p == nil || n <= 0 {
+		return nil
+	}
+
+	return unsafe.Slice(p, n)
+}
+
+// GetLogits returns the logits of every output of the last batch that was decoded, with
+// [VocabNTokens] logits for each output in the order of the tokens in the batch. nOutputs is the
+// number of tokens in the batch that had their Logits set, which is 1 for [BatchGetOne].
+//
+// The slice views memory owned by the Context, so it is only valid until the next Decode or Encode
+// for the Context, or until the Context is freed. Copy the values to keep them for longer.
+func GetLogits(ctx Context, nOutputs int) []float32 {
+	var p *float32
+	getLogitsFunc.Call(unsafe.Pointer(&p), unsafe.Pointer(&ctx))
+
+	return floats(p, nOutputs*int(VocabNTokens(ModelGetVocab(GetModel(ctx)))))
+}
+
+// GetLogitsIth returns the logits for the i-th token of the last batch that was decoded, which
+// is [VocabNTokens] values. Negative values of i count back from the last output, so -1 is the last
+// output. It returns nil if the token did not have its Logits set.
+//
+// The slice views memory owned by the Context, so it is only valid until the next Decode or Encode
+// for the Context, or until the Context is freed. Copy the values to keep them for longer.
+func GetLogitsIth(ctx Context, i int32) []float32 {
+	var p *float32
+	getLogitsIthFunc.Call(unsafe.Pointer(&p), unsafe.Pointer(&ctx), &i)
+
+	return floats(p, int(VocabNTokens(ModelGetVocab(GetModel(ctx)))))
+}
+
+// GetEmbeddings returns the embeddings of every output of the last batch that was decoded, with
+// [ModelNEmbd] values for each output. The Context must have been created with Embeddings set, and
+// with POOLING_TYPE_NONE, otherwise use [GetEmbeddingsSeq].
+//
+// The slice views memory owned by the Context, so it is only valid until the next Decode or Encode
+// for the Context, or until the Context is freed. Copy the values to keep them for longer.
+func GetEmbeddings(ctx Context, nOutputs int) []float32 {
+	var p *float32
+	getEmbeddingsFunc.Call(unsafe.Pointer(&p), unsafe.Pointer(&ctx))
+
+	return floats(p, nOutputs*int(ModelNEmbd(GetModel(ctx))))
+}
+
+// GetEmbeddingsIth returns the embeddings for the i-th token of the last batch that was decoded,
+// which is [ModelNEmbd] values. Negative values of i count back from the last output. It returns
+// nil if there are no embeddings for the token.
+//
+// The slice views memory owned by the Context, so it is only valid until the next Decode or Encode
+// for the Context, or until the Context is freed. Copy the values to keep them for longer.
+func GetEmbeddingsIth(ctx Context, i int32) []float32 {
+	var p *float32
+	getEmbeddingsIthFunc.Call(unsafe.Pointer(&p), unsafe.Pointer(&ctx), &i)
+
+	return floats(p, int(ModelNEmbd(GetModel(ctx))))
+}
+
+// GetEmbeddingsSeq returns the pooled embeddings of a sequence in the last batch that was decoded,
+// which is [ModelNEmbd] values. When the pooling type is POOLING_TYPE_RANK, it returns the scores of
+// the sequence instead, which is [ModelNClsOut] values. It returns nil when the pooling type is
+// POOLING_TYPE_NONE, or the sequence was not in the batch.
+//
+// The slice views memory owned by the Context, so it is only valid until the next Decode or Encode
+// for the Context, or until the Context is freed. Copy the values to keep them for longer.
+func GetEmbeddingsSeq(ctx Context, seqID SeqId) []float32 {
+	var p *float32
+	getEmbeddingsSeqFunc.Call(unsafe.Pointer(&p), unsafe.Pointer(&ctx), &seqID)
+
+	model := GetModel(ctx)
+	if GetPoolingType(ctx) == POOLING_TYPE_RANK {
+		return floats(p, int(ModelNClsOut(model)))
+	}
+
+	return floats(p, int(ModelNEmbd(model)))
+}
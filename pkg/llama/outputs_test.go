@@ -0,0 +1,113 @@
+package llama
+
+import (
+	"slices"
+	"testing"
+)
+
+// testOutputsContext returns a context with params that has decoded a prompt.
+func testOutputsContext(t *testing.T, model Model, params ContextParams) Context {
+	lctx, err := NewContext(model, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := TokenizeString(ModelGetVocab(model), "Are you ready to rock?", TokenizeOptions{AddSpecial: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lctx.Decode(BatchGetOne(tokens)); err != nil {
+		t.Fatal(err)
+	}
+
+	return lctx
+}
+
+func TestGetLogits(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer model.Close()
+
+	lctx := testOutputsContext(t, model, ContextDefaultParams())
+	defer lctx.Close()
+
+	if GetModel(lctx) != model {
+		t.Fatal("wrong model for context")
+	}
+
+	nVocab := int(VocabNTokens(ModelGetVocab(model)))
+	logits := GetLogits(lctx, 1)
+	if len(logits) != nVocab {
+		t.Fatal("wrong number of logits", len(logits))
+	}
+
+	last := GetLogitsIth(lctx, -1)
+	if !slices.Equal(logits, last) {
+		t.Fatal("logits of the last output do not match")
+	}
+
+	sampler := SamplerChainInit(SamplerChainDefaultParams())
+	SamplerChainAdd(sampler, SamplerInitGreedy())
+	defer sampler.Close()
+
+	best := Token(0)
+	for i, v := range last {
+		if v > last[best] {
+			best = Token(i)
+		}
+	}
+
+	if token := SamplerSample(sampler, lctx, -1); token != best {
+		t.Fatal("greedy sampler does not pick the largest logit", token, best)
+	}
+}
+
+func TestGetEmbeddings(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer model.Close()
+
+	nEmbd := int(ModelNEmbd(model))
+
+	params := ContextDefaultParams()
+	params.Embeddings = 1
+	params.PoolingType = POOLING_TYPE_NONE
+	lctx := testOutputsContext(t, model, params)
+	defer lctx.Close()
+
+	if GetPoolingType(lctx) != POOLING_TYPE_NONE {
+		t.Fatal("wrong pooling type", GetPoolingType(lctx))
+	}
+
+	embd := GetEmbeddings(lctx, 1)
+	if len(embd) != nEmbd || !slices.Equal(embd, GetEmbeddingsIth(lctx, -1)) {
+		t.Fatal("wrong embeddings", embd)
+	}
+
+	if GetEmbeddingsSeq(lctx, 0) != nil {
+		t.Fatal("expected no pooled embeddings")
+	}
+
+	params.PoolingType = POOLING_TYPE_MEAN
+	pooled := testOutputsContext(t, model, params)
+	defer pooled.Close()
+
+	if embd := GetEmbeddingsSeq(pooled, 0); len(embd) != nEmbd {
+		t.Fatal("wrong pooled embeddings", embd)
+	}
+
+	if GetEmbeddingsSeq(pooled, 1) != nil {
+		t.Fatal("expected no embeddings for a sequence that was not decoded")
+	}
+}
@@ -0,0 +1,174 @@
+package llama
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+// enum llama_model_kv_override_type
+type KvOverrideType int32
+
+const (
+	KV_OVERRIDE_TYPE_INT KvOverrideType = iota
+	KV_OVERRIDE_TYPE_FLOAT
+	KV_OVERRIDE_TYPE_BOOL
+	KV_OVERRIDE_TYPE_STR
+)
+
+// kvOverrideMaxLen is the size of the key and the string value of a llama_model_kv_override, including the NUL.
+const kvOverrideMaxLen = 128
+
+// kvOverride mirrors the memory layout of llama_model_kv_override.
+//
+//	struct llama_model_kv_override {
+//	    enum llama_model_kv_override_type tag;
+//	    char key[128];
+//	    union {
+//	        int64_t val_i64;
+//	        double  val_f64;
+//	        bool    val_bool;
+//	        char    val_str[128];
+//	    };
+//	};
+type kvOverride struct {
+	Tag   KvOverrideType
+	Key   [kvOverrideMaxLen]byte
+	_     [4]byte
+	Value [kvOverrideMaxLen]byte
+}
+
+// KvOverride overrides the value of a metadata key of a model when it is loaded.
+// Use [KvOverrideInt], [KvOverrideFloat], [KvOverrideBool] or [KvOverrideStr] to create one.
+type KvOverride struct {
+	Key   string
+	Type  KvOverrideType
+	Int   int64
+	Float float64
+	Bool  bool
+	Str   string
+}
+
+// KvOverrideInt returns a KvOverride that sets key to an integer.
+func KvOverrideInt(key string, v int64) KvOverride {
+	return KvOverride{Key: key, Type: KV_OVERRIDE_TYPE_INT, Int: v}
+}
+
+// KvOverrideFloat returns a KvOverride that sets key to a float.
+func KvOverrideFloat(key string, v float64) KvOverride {
+	return KvOverride{Key: key, Type: KV_OVERRIDE_TYPE_FLOAT, Float: v}
+}
+
+// KvOverrideBool returns a KvOverride that sets key to a bool.
+func KvOverrideBool(key string, v bool) KvOverride {
+	return KvOverride{Key: key, Type: KV_OVERRIDE_TYPE_BOOL, Bool: v}
+}
+
+// KvOverrideStr returns a KvOverride that sets key to a string of at most 127 bytes.
+func KvOverrideStr(key string, v string) KvOverride {
+	return KvOverride{Key: key, Type: KV_OVERRIDE_TYPE_STR, Str: v}
+}
+
+// ParseKvOverride parses an override in the same format as the --override-kv flag of llama-cli,
+// KEY=TYPE:VALUE where TYPE is int, float, bool or str. For example "tokenizer.ggml.add_bos_token=bool:false".
+func ParseKvOverride(s string) (KvOverride, error) {
+	key, typedValue, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return KvOverride{}, fmt.Errorf("llama: invalid kv override %q, expected KEY=TYPE:VALUE", s)
+	}
+
+	typ, value, ok := strings.Cut(typedValue, ":")
+	if !ok {
+		return KvOverride{}, fmt.Errorf("llama: invalid kv override %q, expected KEY=TYPE:VALUE", s)
+	}
+
+	switch typ {
+	case "int":
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return KvOverride{}, fmt.Errorf("llama: invalid int in kv override %q: %w", s, err)
+		}
+		return KvOverrideInt(key, v), nil
+	case "float":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return KvOverride{}, fmt.Errorf("llama: invalid float in kv override %q: %w", s, err)
+		}
+		return KvOverrideFloat(key, v), nil
+	case "bool":
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return KvOverride{}, fmt.Errorf("llama: invalid bool in kv override %q: %w", s, err)
+		}
+		return KvOverrideBool(key, v), nil
+	case "str":
+		return KvOverrideStr(key, value), nil
+	default:
+		return KvOverride{}, fmt.Errorf("llama: invalid type %q in kv override %q, expected int, float, bool or str", typ, s)
+	}
+}
+
+// LoadModelWithOverrides loads a model like [LoadModel], overriding the values of metadata keys:
+//
+//	model, err := llama.LoadModelWithOverrides(path, llama.ModelDefaultParams(), []llama.KvOverride{
+//		llama.KvOverrideBool("tokenizer.ggml.add_bos_token", false),
+//	})
+//
+// It sets params.KvOverrides to a NULL-terminated array of llama_model_kv_override that is only
+// used while the model is loaded. It returns an error if a key or a string value is too long.
+func LoadModelWithOverrides(path string, params ModelParams, overrides []KvOverride) (Model, error) {
+	data, err := marshalKvOverrides(overrides)
+	if err != nil {
+		return 0, err
+	}
+
+	return loadModelWithOverrides(path, params, data)
+}
+
+// loadModelWithOverrides loads a model with a NULL-terminated array of llama_model_kv_override.
+func loadModelWithOverrides(path string, params ModelParams, data []kvOverride) (Model, error) {
+	params.KvOverrides = uintptr(unsafe.Pointer(&data[0]))
+	model, err := LoadModel(path, params)
+
+	// the array is only referenced by params.KvOverrides while llama.cpp reads it
+	runtime.KeepAlive(data)
+
+	return model, err
+}
+
+// marshalKvOverrides returns a NULL-terminated array of llama_model_kv_override for overrides.
+func marshalKvOverrides(overrides []KvOverride) ([]kvOverride, error) {
+	data := make([]kvOverride, len(overrides)+1)
+	for i, o := range overrides {
+		if o.Key == "" || len(o.Key) >= kvOverrideMaxLen {
+			return nil, fmt.Errorf("llama: kv override key %q must be 1 to %d bytes", o.Key, kvOverrideMaxLen-1)
+		}
+
+		kv := &data[i]
+		kv.Tag = o.Type
+		copy(kv.Key[:], o.Key)
+
+		switch o.Type {
+		case KV_OVERRIDE_TYPE_INT:
+			*(*int64)(unsafe.Pointer(&kv.Value)) = o.Int
+		case KV_OVERRIDE_TYPE_FLOAT:
+			*(*uint64)(unsafe.Pointer(&kv.Value)) = math.Float64bits(o.Float)
+		case KV_OVERRIDE_TYPE_BOOL:
+			if o.Bool {
+				kv.Value[0] = 1
+			}
+		case KV_OVERRIDE_TYPE_STR:
+			if len(o.Str) >= kvOverrideMaxLen {
+				return nil, fmt.Errorf("llama: kv override %s must be at most %d bytes", o.Key, kvOverrideMaxLen-1)
+			}
+			copy(kv.Value[:], o.Str)
+		default:
+			return nil, fmt.Errorf("llama: kv override %s has invalid type %d", o.Key, o.Type)
+		}
+	}
+
+	return data, nil
+}
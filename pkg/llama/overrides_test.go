@@ -0,0 +1,111 @@
+package llama
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestKvOverridesLayout(t *testing.T) {
+	if size := unsafe.Sizeof(kvOverride{}); size != 264 {
+		t.Fatal("llama_model_kv_override should be 264 bytes, got", size)
+	}
+
+	if off := unsafe.Offsetof(kvOverride{}.Value); off != 136 {
+		t.Fatal("llama_model_kv_override value should be at offset 136, got", off)
+	}
+}
+
+func TestMarshalKvOverrides(t *testing.T) {
+	data, err := marshalKvOverrides([]KvOverride{KvOverrideInt("a", -2), KvOverrideStr("b", "hello")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data) != 3 || data[2].Key[0] != 0 {
+		t.Fatal("overrides should be NULL-terminated")
+	}
+
+	if v := *(*int64)(unsafe.Pointer(&data[0].Value)); v != -2 {
+		t.Fatal("wrong int value", v)
+	}
+
+	if _, err := marshalKvOverrides([]KvOverride{KvOverrideStr("b", strings.Repeat("x", 128))}); err == nil {
+		t.Fatal("expected error for a long string value")
+	}
+
+	if _, err := marshalKvOverrides([]KvOverride{KvOverrideInt(strings.Repeat("k", 128), 1)}); err == nil {
+		t.Fatal("expected error for a long key")
+	}
+}
+
+func TestParseKvOverride(t *testing.T) {
+	tests := []struct {
+		s    string
+		want KvOverride
+	}{
+		{"fake.context_length=int:512", KvOverrideInt("fake.context_length", 512)},
+		{"a.b=float:0.5", KvOverrideFloat("a.b", 0.5)},
+		{"tokenizer.ggml.add_bos_token=bool:false", KvOverrideBool("tokenizer.ggml.add_bos_token", false)},
+		{"general.name=str:a=b:c", KvOverrideStr("general.name", "a=b:c")},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseKvOverride(tt.s)
+		if err != nil {
+			t.Fatal(tt.s, err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: got %+v, want %+v", tt.s, got, tt.want)
+		}
+	}
+
+	for _, s := range []string{"", "key", "=int:1", "key=int", "key=int:x", "key=bool:maybe", "key=u32:1"} {
+		if _, err := ParseKvOverride(s); err == nil {
+			t.Fatal("expected error for", s)
+		}
+	}
+}
+
+func TestLoadModelKvOverrides(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModelWithOverrides(testModelFile(t), ModelDefaultParams(), []KvOverride{
+		KvOverrideInt("fake.context_length", 512),
+		KvOverrideStr("general.name", "Overridden"),
+		KvOverrideBool("tokenizer.ggml.add_bos_token", false),
+	})
+	if err != nil {
+		t.Fatal("unable to load model", err)
+	}
+	defer model.Close()
+
+	if n := ModelNCtxTrain(model); n != 512 {
+		t.Fatal("n_ctx_train should be overridden, got", n)
+	}
+
+	if name, _ := ModelMetaValStr(model, "general.name"); name != "Overridden" {
+		t.Fatal("general.name should be overridden, got", name)
+	}
+
+	if v, _ := ModelMetaValStr(model, "tokenizer.ggml.add_bos_token"); v != "false" {
+		t.Fatal("tokenizer.ggml.add_bos_token should be overridden, got", v)
+	}
+}
+
+func TestLoadModelKvOverridesWrongType(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	overrides := []KvOverride{KvOverrideStr("fake.context_length", "long")}
+	if _, err := LoadModelWithOverrides(testModelFile(t), ModelDefaultParams(), overrides); !errors.Is(err, ErrModelLoad) {
+		t.Fatal("expected load to fail", err)
+	}
+
+	overrides = []KvOverride{KvOverrideStr(strings.Repeat("k", 128), "long")}
+	if _, err := LoadModelWithOverrides(testModelFile(t), ModelDefaultParams(), overrides); err == nil {
+		t.Fatal("expected error for a long key")
+	}
+}
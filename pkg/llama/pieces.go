@@ -0,0 +1,94 @@
+package llama
+
+// PieceTable has the text and attributes of every token in a vocabulary, so that tokens can be
+// converted into text without calling llama.cpp. Building a table calls [TokenToPiece] once for
+// each token, so it is worth it when a model generates many tokens, such as in a server.
+// A PieceTable is read-only after it has been built, and can be used by many goroutines.
+type PieceTable struct {
+	vocab   Vocab
+	data    []byte
+	offsets []uint32
+	attrs   []TokenAttr
+}
+
+// pieceSpecial are the attributes of tokens that only have text when special tokens are included,
+// like in llama_token_to_piece.
+const pieceSpecial = TOKEN_ATTR_UNKNOWN | TOKEN_ATTR_CONTROL
+
+// NewPieceTable builds a PieceTable for vocab.
+func NewPieceTable(vocab Vocab) *PieceTable {
+	n := max(VocabNTokens(vocab), 0)
+
+	t := &PieceTable{
+		vocab:   vocab,
+		offsets: make([]uint32, n+1),
+		attrs:   make([]TokenAttr, n),
+	}
+
+	buf := make([]byte, 64)
+	for i := range n {
+		var piece []byte
+		piece, buf = tokenPiece(vocab, buf, Token(i), 0, true)
+
+		t.data = append(t.data, piece...)
+		t.offsets[i+1] = uint32(len(t.data))
+		t.attrs[i] = VocabGetAttr(vocab, Token(i))
+	}
+
+	return t
+}
+
+// Vocab returns the vocabulary that the table was built from.
+func (t *PieceTable) Vocab() Vocab {
+	return t.vocab
+}
+
+// Len returns the number of tokens in the table.
+func (t *PieceTable) Len() int {
+	return len(t.attrs)
+}
+
+// Attr returns the attributes of token, or TOKEN_ATTR_UNDEFINED if it is not in the table.
+func (t *PieceTable) Attr(token Token) TokenAttr {
+	if token < 0 || int(token) >= len(t.attrs) {
+		return TOKEN_ATTR_UNDEFINED
+	}
+
+	return t.attrs[token]
+}
+
+// Piece returns the text for token, like [TokenToPiece]. If special is true, the text of special
+// tokens such as EOS is returned. The returned slice is shared by every caller, and must not be
+// modified. It returns nil if the token is not in the table.
+func (t *PieceTable) Piece(token Token, special bool) []byte {
+	if token < 0 || int(token) >= len(t.attrs) {
+		return nil
+	}
+
+	if !special && t.attrs[token]&pieceSpecial != 0 {
+		return nil
+	}
+
+	return t.data[t.offsets[token]:t.offsets[token+1]:t.offsets[token+1]]
+}
+
+// AppendPiece appends the text for token to dst, after removing up to lstrip leading spaces.
+func (t *PieceTable) AppendPiece(dst []byte, token Token, lstrip int32, special bool) []byte {
+	piece := t.Piece(token, special)
+	for ; lstrip > 0 && len(piece) > 0 && piece[0] == ' '; lstrip-- {
+		piece = piece[1:]
+	}
+
+	return append(dst, piece...)
+}
+
+// Decode converts tokens into text. Unlike [Detokenize], it does not remove the BOS and EOS tokens
+// or apply the model's text cleanup, it only joins the text of each token.
+func (t *PieceTable) Decode(tokens []Token, special bool) string {
+	var buf []byte
+	for _, token := range tokens {
+		buf = t.AppendPiece(buf, token, 0, special)
+	}
+
+	return string(buf)
+}
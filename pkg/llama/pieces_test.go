@@ -0,0 +1,153 @@
+package llama
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPieceTable(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	tok, err := LoadTokenizer(testModelFile(t))
+	if err != nil {
+		t.Fatal("unable to load tokenizer", err)
+	}
+	defer tok.Close()
+
+	vocab := tok.Vocab()
+	table := NewPieceTable(vocab)
+	if table.Len() != int(tok.NTokens()) {
+		t.Fatal("wrong number of tokens", table.Len())
+	}
+
+	buf := make([]byte, 256)
+	for i := range table.Len() {
+		token := Token(i)
+		for _, special := range []bool{false, true} {
+			n := TokenToPiece(vocab, token, buf, 0, special)
+			if !bytes.Equal(table.Piece(token, special), buf[:n]) {
+				t.Fatalf("token %d: got %q, want %q", token, table.Piece(token, special), buf[:n])
+			}
+		}
+
+		if table.Attr(token) != VocabGetAttr(vocab, token) {
+			t.Fatal("wrong attributes for token", token)
+		}
+	}
+
+	if table.Piece(-1, true) != nil || table.Attr(Token(table.Len())) != TOKEN_ATTR_UNDEFINED {
+		t.Fatal("expected nothing for tokens that are not in the table")
+	}
+
+	text := "Grüße, 世界 👋!"
+	tokens, err := tok.Encode(text, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded := table.Decode(tokens, false); decoded != text {
+		t.Fatalf("got %q, want %q", decoded, text)
+	}
+
+	detok := NewDetokenizerFromTable(table, 1, false)
+	var sb strings.Builder
+	for _, token := range append([]Token{tok.BOS()}, tokens...) {
+		sb.WriteString(detok.Add(token))
+	}
+	sb.WriteString(detok.Flush())
+
+	if sb.String() != text {
+		t.Fatalf("got %q, want %q", sb.String(), text)
+	}
+}
+
+// benchmarkTokens loads a tokenizer, and returns it along with some tokens to convert into text.
+func benchmarkTokens(b *testing.B) (*Tokenizer, []Token) {
+	tok, err := LoadTokenizer(testModelFile(b))
+	if err != nil {
+		b.Fatal("unable to load tokenizer", err)
+	}
+
+	tokens, err := tok.Encode(strings.Repeat("Are you ready to rock? ", 20), false, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return tok, tokens
+}
+
+func BenchmarkTokenToPiece(b *testing.B) {
+	testSetup(b)
+	defer testCleanup(b)
+
+	tok, tokens := benchmarkTokens(b)
+	defer tok.Close()
+	vocab := tok.Vocab()
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		buf := make([]byte, 64)
+		TokenToPiece(vocab, tokens[i%len(tokens)], buf, 0, false)
+	}
+}
+
+func BenchmarkPieceTable(b *testing.B) {
+	testSetup(b)
+	defer testCleanup(b)
+
+	tok, tokens := benchmarkTokens(b)
+	defer tok.Close()
+	vocab := tok.Vocab()
+	table := NewPieceTable(vocab)
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		table.Piece(tokens[i%len(tokens)], false)
+	}
+}
+
+func BenchmarkNewPieceTable(b *testing.B) {
+	testSetup(b)
+	defer testCleanup(b)
+
+	tok, _ := benchmarkTokens(b)
+	defer tok.Close()
+	vocab := tok.Vocab()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		NewPieceTable(vocab)
+	}
+}
+
+func BenchmarkDetokenizer(b *testing.B) {
+	testSetup(b)
+	defer testCleanup(b)
+
+	tok, tokens := benchmarkTokens(b)
+	defer tok.Close()
+	vocab := tok.Vocab()
+	detok := NewDetokenizer(vocab, 0, false)
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		detok.Add(tokens[i%len(tokens)])
+	}
+}
+
+func BenchmarkDetokenizerFromTable(b *testing.B) {
+	testSetup(b)
+	defer testCleanup(b)
+
+	tok, tokens := benchmarkTokens(b)
+	defer tok.Close()
+	vocab := tok.Vocab()
+	detok := NewDetokenizerFromTable(NewPieceTable(vocab), 0, false)
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		detok.Add(tokens[i%len(tokens)])
+	}
+}
@@ -0,0 +1,123 @@
+package llama
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/jupiterrider/ffi"
+)
+
+// ProgressFunc is called with the progress of loading a model, from 0 to 1.
+type ProgressFunc func(progress float32)
+
+type progressState struct {
+	ctx context.Context
+	fn  ProgressFunc
+}
+
+var (
+	// typedef bool (*llama_progress_callback)(float progress, void * user_data);
+	progressCallbackOnce sync.Once
+	progressCallback     unsafe.Pointer
+	progressCallbackErr  error
+
+	// progressCif and progressArgTypes are used by the closure for as long as the program runs, so
+	// they are kept here where the garbage collector cannot free them.
+	progressCif      ffi.Cif
+	progressArgTypes = []*ffi.Type{&ffi.TypeFloat, &ffi.TypePointer}
+
+	// progressStates has the state of every load in progress, by the id passed as the user data.
+	progressMu     sync.Mutex
+	progressNext   uintptr
+	progressStates = make(map[uintptr]*progressState)
+)
+
+// initProgressCallback creates the single C function that is used as the progress callback for
+// every load. The id of the load is passed to it as the user data.
+func initProgressCallback() error {
+	progressCallbackOnce.Do(func() {
+		if status := ffi.PrepCif(&progressCif, ffi.DefaultAbi, uint32(len(progressArgTypes)), &ffi.TypeUint8, progressArgTypes...); status != ffi.OK {
+			progressCallbackErr = fmt.Errorf("llama: unable to prepare progress callback: %s", status)
+			return
+		}
+
+		closure := ffi.ClosureAlloc(unsafe.Sizeof(ffi.Closure{}), &progressCallback)
+		if closure == nil {
+			progressCallbackErr = fmt.Errorf("llama: unable to allocate progress callback")
+			return
+		}
+
+		fn := ffi.NewCallback(func(cif *ffi.Cif, ret unsafe.Pointer, args *unsafe.Pointer, userData unsafe.Pointer) uintptr {
+			arg := unsafe.Slice(args, cif.NArgs)
+			progress := *(*float32)(arg[0])
+			id := *(*uintptr)(arg[1])
+
+			progressMu.Lock()
+			state := progressStates[id]
+			progressMu.Unlock()
+
+			keepGoing := true
+			if state != nil {
+				if state.fn != nil {
+					state.fn(progress)
+				}
+				keepGoing = state.ctx.Err() == nil
+			}
+
+			if keepGoing {
+				*(*ffi.Arg)(ret) = 1
+			} else {
+				*(*ffi.Arg)(ret) = 0
+			}
+
+			return 0
+		})
+
+		if status := ffi.PrepClosureLoc(closure, &progressCif, fn, nil, progressCallback); status != ffi.OK {
+			progressCallbackErr = fmt.Errorf("llama: unable to prepare progress callback: %s", status)
+		}
+	})
+
+	return progressCallbackErr
+}
+
+// ModelLoadFromFileContext loads a Model from a GGUF file like [LoadModel], calling progress with the
+// progress of the load. progress can be nil. If ctx is cancelled, the load is aborted and the returned
+// error wraps both [ErrModelLoad] and the error from ctx.
+func ModelLoadFromFileContext(ctx context.Context, path string, params ModelParams, progress ProgressFunc) (Model, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("%w %q: %w", ErrModelLoad, path, err)
+	}
+
+	if err := initProgressCallback(); err != nil {
+		return 0, err
+	}
+
+	progressMu.Lock()
+	progressNext++
+	id := progressNext
+	progressStates[id] = &progressState{ctx: ctx, fn: progress}
+	progressMu.Unlock()
+
+	defer func() {
+		progressMu.Lock()
+		delete(progressStates, id)
+		progressMu.Unlock()
+	}()
+
+	params.ProgressCallback = uintptr(progressCallback)
+	params.ProgressCallbackUserData = id
+
+	model := ModelLoadFromFile(path, params)
+	if model == 0 {
+		if err := ctx.Err(); err != nil {
+			return 0, fmt.Errorf("%w %q: %w", ErrModelLoad, path, err)
+		}
+
+		return 0, fmt.Errorf("%w %q", ErrModelLoad, path)
+	}
+
+	return model, nil
+}
@@ -0,0 +1,73 @@
+package llama
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestModelLoadFromFileContext(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	var calls []float32
+	model, err := ModelLoadFromFileContext(context.Background(), testModelFile(t), ModelDefaultParams(), func(progress float32) {
+		calls = append(calls, progress)
+	})
+	if err != nil {
+		t.Fatal("unable to load model", err)
+	}
+	defer model.Close()
+
+	if len(calls) == 0 || calls[len(calls)-1] != 1 {
+		t.Fatal("progress not reported", calls)
+	}
+}
+
+func TestModelLoadFromFileContextCancel(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := ModelLoadFromFileContext(ctx, testModelFile(t), ModelDefaultParams(), func(progress float32) {
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, ErrModelLoad) {
+		t.Fatal("expected load to be cancelled", err)
+	}
+
+	if _, err := ModelLoadFromFileContext(ctx, testModelFile(t), ModelDefaultParams(), nil); !errors.Is(err, context.Canceled) {
+		t.Fatal("expected load with a cancelled context to fail", err)
+	}
+}
+
+func TestProgressCallbackGC(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	if err := initProgressCallback(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the callback must still work after everything that is not kept alive has been collected
+	runtime.GC()
+	runtime.GC()
+
+	var calls int
+	model, err := ModelLoadFromFileContext(context.Background(), testModelFile(t), ModelDefaultParams(), func(progress float32) {
+		runtime.GC()
+		calls++
+
+		// the callback can call into the library while the model is loading
+		ModelDefaultParams()
+	})
+	if err != nil {
+		t.Fatal("unable to load model", err)
+	}
+	defer model.Close()
+
+	if calls == 0 {
+		t.Fatal("progress not reported")
+	}
+}
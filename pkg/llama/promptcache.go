@@ -0,0 +1,314 @@
+package llama
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PromptCache saves the state of prompts that have been decoded in a directory, so that a later
+// prompt that starts with the same tokens, such as the same system prompt and examples, only needs
+// to decode the tokens after them. The cache lasts across restarts of the program.
+//
+// Each entry is the state of a sequence saved with [StateSeqSaveFile], named by the number of tokens
+// and a hash of them. The entries are kept in a subdirectory for the identity of the model file, so
+// state is never restored from a different model, or from a model file that has changed.
+//
+// When the total size of the entries is more than the limit, the least recently used entries are
+// removed. A PromptCache is safe for concurrent use, but only one PromptCache should use a directory.
+type PromptCache struct {
+	dir   string
+	limit int64
+
+	mu      sync.Mutex
+	entries map[string]*promptEntry
+	size    int64
+}
+
+type promptEntry struct {
+	name string
+	n    int
+	size int64
+	used time.Time
+}
+
+// promptCacheExt is the file extension of the entries of a PromptCache.
+const promptCacheExt = ".state"
+
+// NewPromptCache returns a PromptCache for the model file at modelPath, that stores its entries
+// under dir. If limit is more than 0, the entries are kept within limit bytes.
+func NewPromptCache(dir string, modelPath string, limit int64) (*PromptCache, error) {
+	id, err := modelIdentity(modelPath)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &PromptCache{
+		dir:     filepath.Join(dir, id),
+		limit:   limit,
+		entries: make(map[string]*promptEntry),
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		n, ok := promptEntryTokens(f.Name())
+		if !ok || !f.Type().IsRegular() {
+			continue
+		}
+
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+
+		c.entries[f.Name()] = &promptEntry{name: f.Name(), n: n, size: info.Size(), used: info.ModTime()}
+		c.size += info.Size()
+	}
+
+	return c, nil
+}
+
+// Dir returns the directory with the entries for the model.
+func (c *PromptCache) Dir() string {
+	return c.dir
+}
+
+// Len returns the number of entries in the cache.
+func (c *PromptCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// Size returns the total size in bytes of the entries in the cache.
+func (c *PromptCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.size
+}
+
+// Restore restores the longest prefix of tokens that is in the cache into seq, replacing what was in
+// it, and returns the number of tokens that were restored. It returns 0 if no prefix is in the cache.
+func (c *PromptCache) Restore(ctx Context, seq SeqId, tokens []Token) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lengths := make([]int, 0, len(c.entries))
+	for _, e := range c.entries {
+		if e.n <= len(tokens) {
+			lengths = append(lengths, e.n)
+		}
+	}
+	slices.Sort(lengths)
+	lengths = slices.Compact(lengths)
+
+	for _, n := range slices.Backward(lengths) {
+		e, ok := c.entries[promptEntryName(tokens[:n])]
+		if !ok {
+			continue
+		}
+
+		loaded, err := StateSeqLoadFile(ctx, filepath.Join(c.dir, e.name), seq, n)
+		if err != nil || !slices.Equal(loaded, tokens[:n]) {
+			// the entry is damaged, or is for different tokens with the same hash
+			MemorySeqRm(GetMemory(ctx), seq, -1, -1)
+			c.remove(e)
+			continue
+		}
+
+		e.used = time.Now()
+		os.Chtimes(filepath.Join(c.dir, e.name), e.used, e.used)
+
+		return n, nil
+	}
+
+	return 0, nil
+}
+
+// Save saves the state of seq, which has decoded tokens, to the cache.
+func (c *PromptCache) Save(ctx Context, seq SeqId, tokens []Token) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := promptEntryName(tokens)
+	if e, ok := c.entries[name]; ok {
+		e.used = time.Now()
+		os.Chtimes(filepath.Join(c.dir, name), e.used, e.used)
+
+		return nil
+	}
+
+	// save to a temporary file, so that an entry is never read before it is complete
+	tmp := filepath.Join(c.dir, name+".tmp")
+	if err := StateSeqSaveFile(ctx, tmp, seq, tokens); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	info, err := os.Stat(tmp)
+	if err == nil {
+		err = os.Rename(tmp, filepath.Join(c.dir, name))
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	c.entries[name] = &promptEntry{name: name, n: len(tokens), size: info.Size(), used: time.Now()}
+	c.size += info.Size()
+	c.evict()
+
+	return nil
+}
+
+// Decode decodes tokens in sequence 0 of ctx, which is cleared first. The longest prefix of tokens
+// that is in the cache is restored, and only the rest of the tokens are decoded. The state is saved
+// to the cache after the first prefixLen tokens, such as a system prompt that is shared by many
+// prompts, and after all of the tokens. Use 0 to only save the state after all of the tokens.
+//
+// The last token is always decoded so that its logits are available for sampling, so when all of
+// the tokens are restored, the last one is removed and decoded again. It returns the number of
+// tokens that were restored and not decoded.
+func (c *PromptCache) Decode(ctx Context, tokens []Token, prefixLen int) (int, error) {
+	if len(tokens) == 0 {
+		return 0, ErrInvalidBatch
+	}
+
+	mem := GetMemory(ctx)
+	MemorySeqRm(mem, 0, -1, -1)
+
+	n, err := c.Restore(ctx, 0, tokens)
+	if err != nil {
+		return 0, err
+	}
+
+	if n == len(tokens) {
+		n--
+		MemorySeqRm(mem, 0, Pos(n), -1)
+
+		return n, ctx.Decode(BatchGetOne(tokens[n:]))
+	}
+
+	start := n
+	if prefixLen > n && prefixLen < len(tokens) {
+		if err := ctx.Decode(BatchGetOne(tokens[n:prefixLen])); err != nil {
+			return n, err
+		}
+
+		if err := c.Save(ctx, 0, tokens[:prefixLen]); err != nil {
+			return n, err
+		}
+		start = prefixLen
+	}
+
+	if err := ctx.Decode(BatchGetOne(tokens[start:])); err != nil {
+		return n, err
+	}
+
+	return n, c.Save(ctx, 0, tokens)
+}
+
+// evict removes the least recently used entries until the cache is within its limit.
+func (c *PromptCache) evict() {
+	if c.limit <= 0 || c.size <= c.limit {
+		return
+	}
+
+	entries := make([]*promptEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+	slices.SortFunc(entries, func(a, b *promptEntry) int {
+		return a.used.Compare(b.used)
+	})
+
+	for _, e := range entries {
+		if c.size <= c.limit {
+			break
+		}
+		c.remove(e)
+	}
+}
+
+func (c *PromptCache) remove(e *promptEntry) {
+	os.Remove(filepath.Join(c.dir, e.name))
+	delete(c.entries, e.name)
+	c.size -= e.size
+}
+
+// promptEntryName returns the file name of the entry for tokens.
+func promptEntryName(tokens []Token) string {
+	h := sha256.New()
+	buf := make([]byte, 0, 4*len(tokens))
+	for _, t := range tokens {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(t))
+	}
+	h.Write(buf)
+
+	return fmt.Sprintf("%d-%s%s", len(tokens), hex.EncodeToString(h.Sum(nil)[:16]), promptCacheExt)
+}
+
+// promptEntryTokens returns the number of tokens of the entry in the file name.
+func promptEntryTokens(name string) (int, bool) {
+	prefix, _, ok := strings.Cut(strings.TrimSuffix(name, promptCacheExt), "-")
+	if !ok || !strings.HasSuffix(name, promptCacheExt) {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(prefix)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// modelIdentityBytes is how much of the start of a model file is hashed to identify it. It includes
+// the GGUF metadata of most models.
+const modelIdentityBytes = 1 << 20
+
+// modelIdentity returns an identifier for the model file at path, made from its size, modification
+// time and a hash of its start, so that it changes if the file is replaced.
+func modelIdentity(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d %d\n", info.Size(), info.ModTime().UnixNano())
+	if _, err := io.CopyN(h, f, modelIdentityBytes); err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:16]), nil
+}
@@ -0,0 +1,178 @@
+package llama
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testPromptCacheModel returns the path of a file to identify the model by, which the tests can
+// change without changing the model that is used.
+func testPromptCacheModel(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "model.gguf")
+	if err := os.WriteFile(path, []byte("GGUF model"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestPromptCache(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer model.Close()
+
+	lctx, err := NewContext(model, ContextDefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lctx.Close()
+
+	vocab := ModelGetVocab(model)
+	system, err := TokenizeString(vocab, "You are a helpful assistant.", TokenizeOptions{AddSpecial: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := TokenizeString(vocab, " What is a llama?", TokenizeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := TokenizeString(vocab, " Where do alpacas live?", TokenizeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	modelPath := testPromptCacheModel(t)
+	cache, err := NewPromptCache(dir, modelPath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mem := GetMemory(lctx)
+	prompt := append(slices.Clone(system), first...)
+	if n, err := cache.Decode(lctx, prompt, len(system)); err != nil || n != 0 {
+		t.Fatal("nothing should be restored from an empty cache", n, err)
+	}
+
+	if cache.Len() != 2 || cache.Size() <= 0 {
+		t.Fatal("expected the system prompt and the whole prompt to be saved", cache.Len(), cache.Size())
+	}
+
+	// a different question after the same system prompt
+	prompt = append(slices.Clone(system), second...)
+	n, err := cache.Decode(lctx, prompt, len(system))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != len(system) || MemorySeqPosMax(mem, 0) != Pos(len(prompt)-1) {
+		t.Fatal("expected the system prompt to be restored", n, MemorySeqPosMax(mem, 0))
+	}
+
+	// the same prompt again only decodes the last token
+	n, err = cache.Decode(lctx, prompt, len(system))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != len(prompt)-1 || MemorySeqPosMax(mem, 0) != Pos(len(prompt)-1) || GetLogitsIth(lctx, -1) == nil {
+		t.Fatal("expected the whole prompt to be restored", n, MemorySeqPosMax(mem, 0))
+	}
+
+	// the entries are found again by a new cache for the same model
+	cache, err = NewPromptCache(dir, modelPath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cache.Len() != 3 {
+		t.Fatal("expected the entries to be found", cache.Len())
+	}
+
+	if n, err := cache.Restore(lctx, 1, prompt); err != nil || n != len(prompt) {
+		t.Fatal("expected the whole prompt to be restored", n, err)
+	}
+
+	// a changed model file does not use the entries
+	if err := os.WriteFile(modelPath, []byte("another model"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewPromptCache(dir, modelPath, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if other.Len() != 0 || other.Dir() == cache.Dir() {
+		t.Fatal("entries should not be shared between models", other.Len())
+	}
+}
+
+func TestPromptCacheEvict(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer model.Close()
+
+	lctx, err := NewContext(model, ContextDefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lctx.Close()
+
+	vocab := ModelGetVocab(model)
+	first, err := TokenizeString(vocab, "first prompt", TokenizeOptions{AddSpecial: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := TokenizeString(vocab, "second prompt", TokenizeOptions{AddSpecial: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := NewPromptCache(t.TempDir(), testPromptCacheModel(t), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cache.Decode(lctx, first, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if cache.Len() != 0 || cache.Size() != 0 {
+		t.Fatal("entries larger than the limit should be removed", cache.Len(), cache.Size())
+	}
+
+	cache.limit = 0
+	if _, err := cache.Decode(lctx, first, 0); err != nil {
+		t.Fatal(err)
+	}
+	size := cache.Size()
+
+	cache.limit = size + size/2
+	if _, err := cache.Decode(lctx, second, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if cache.Len() != 1 {
+		t.Fatal("expected the least recently used entry to be removed", cache.Len())
+	}
+
+	if _, err := os.Stat(filepath.Join(cache.Dir(), promptEntryName(second))); err != nil {
+		t.Fatal("expected the most recent entry to be kept", err)
+	}
+}
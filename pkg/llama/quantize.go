@@ -0,0 +1,247 @@
+package llama
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/hybridgroup/yzma/pkg/gguf"
+	"github.com/hybridgroup/yzma/pkg/loader"
+	"github.com/jupiterrider/ffi"
+)
+
+var (
+	FFITypeModelQuantizeParams = ffi.NewType(&ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypeSint32,
+		&ffi.TypeUint8, &ffi.TypeUint8, &ffi.TypeUint8, &ffi.TypeUint8, &ffi.TypeUint8,
+		&ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer)
+)
+
+var (
+	// LLAMA_API struct llama_model_quantize_params llama_model_quantize_default_params(void);
+	modelQuantizeDefaultParamsFunc ffi.Fun
+
+	// LLAMA_API uint32_t llama_model_quantize(
+	//         const char * fname_inp,
+	//         const char * fname_out,
+	//         const llama_model_quantize_params * params);
+	modelQuantizeFunc ffi.Fun
+
+	// quantizeABI checks the layout of the quantize params the first time that it is called.
+	quantizeABI = sync.OnceValue(checkQuantizeABI)
+)
+
+// ErrQuantize is returned when llama.cpp is unable to quantize a model.
+var ErrQuantize = errors.New("llama: unable to quantize model")
+
+func loadQuantizeFuncs(lib loader.Library) error {
+	var (
+		err  error
+		errs []error
+	)
+
+	if modelQuantizeDefaultParamsFunc, err = lib.Prep("llama_model_quantize_default_params", &FFITypeModelQuantizeParams); err != nil {
+		errs = append(errs, err)
+	}
+
+	if modelQuantizeFunc, err = lib.Prep("llama_model_quantize", &ffi.TypeUint32, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	quantizeABI = sync.OnceValue(checkQuantizeABI)
+
+	return errors.Join(errs...)
+}
+
+// ModelQuantizeDefaultParams returns the default parameters for [ModelQuantize].
+// The output and token embedding tensor types are set to GGML_TYPE_COUNT, which means that
+// the type is chosen by the ftype.
+func ModelQuantizeDefaultParams() ModelQuantizeParams {
+	var p ModelQuantizeParams
+	modelQuantizeDefaultParamsFunc.Call(unsafe.Pointer(&p))
+	return p
+}
+
+// ModelQuantize quantizes the model in the GGUF file fnameInp and writes it to fnameOut.
+// It returns 0 on success.
+func ModelQuantize(fnameInp, fnameOut string, params *ModelQuantizeParams) uint32 {
+	inp := &[]byte(fnameInp + "\x00")[0]
+	out := &[]byte(fnameOut + "\x00")[0]
+
+	var result ffi.Arg
+	modelQuantizeFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&inp), unsafe.Pointer(&out), unsafe.Pointer(&params))
+
+	return uint32(result)
+}
+
+// Quantize quantizes the model in the GGUF file src and writes it to dst. Start with
+// [ModelQuantizeDefaultParams] and set the Ftype, for example:
+//
+//	params := llama.ModelQuantizeDefaultParams()
+//	params.Ftype = llama.FTYPE_MOSTLY_Q4_K_M
+//	err := llama.Quantize("model-f16.gguf", "model-q4_k_m.gguf", params)
+//
+// The types of the output and token embedding tensors can be overridden with OutputTensorType
+// and TokenEmbeddingType. The IMatrix, KvOverrides, TensorTypes and PruneLayers fields are pointers
+// to C++ containers, so they must be left as 0. Use [QuantizeWithOptions] for an importance matrix
+// or tensor types.
+//
+// The first time that it is called, Quantize checks that the layout of [ModelQuantizeParams]
+// matches the loaded library, and returns an [*ABIError] if it does not.
+func Quantize(src, dst string, params ModelQuantizeParams) error {
+	if err := quantizeABI(); err != nil {
+		return err
+	}
+
+	if params.IMatrix != 0 || params.KvOverrides != 0 || params.TensorTypes != 0 || params.PruneLayers != 0 {
+		return fmt.Errorf("%w: the imatrix, kv overrides, tensor types and prune layers params are not supported", ErrQuantize)
+	}
+
+	return quantize(src, dst, &params)
+}
+
+func quantize(src, dst string, params *ModelQuantizeParams) error {
+	if result := ModelQuantize(src, dst, params); result != 0 {
+		return fmt.Errorf("%w %q to %s", ErrQuantize, src, params.Ftype)
+	}
+
+	return nil
+}
+
+// TensorQuantization sets the type of every tensor with a name that matches Pattern, which is a
+// regular expression, like the --tensor-type flag of llama-quantize. Use [gguf.ParseTensorType]
+// to get the Type from its name.
+type TensorQuantization struct {
+	Pattern string
+	Type    gguf.TensorType
+}
+
+// tensorQuantization mirrors the layout of the tensor_quantization struct in llama.cpp.
+type tensorQuantization struct {
+	name  cxxString
+	quant int32
+}
+
+// QuantizeOptions are the options for [QuantizeWithOptions] that llama.cpp takes as C++ containers.
+type QuantizeOptions struct {
+	// IMatrix is the importance matrix, with the values for each tensor by name, such as
+	// "blk.0.attn_q.weight".
+	IMatrix map[string][]float32
+
+	// TensorTypes sets the type of the tensors that match each pattern.
+	TensorTypes []TensorQuantization
+
+	// AssumeLibstdcxx must be set to use IMatrix or TensorTypes. The containers are built with the
+	// memory layout of libstdc++ with the C++11 ABI, which is what the llama.cpp releases for Linux
+	// use, but it cannot be checked. With a llama.cpp that is built with libc++, or with
+	// _GLIBCXX_USE_CXX11_ABI=0, llama.cpp would read corrupt memory.
+	AssumeLibstdcxx bool
+}
+
+// QuantizeWithOptions quantizes the model in the GGUF file src and writes it to dst like [Quantize],
+// using an importance matrix and tensor types from opts:
+//
+//	params := llama.ModelQuantizeDefaultParams()
+//	params.Ftype = llama.FTYPE_MOSTLY_IQ2_XS
+//	err := llama.QuantizeWithOptions(src, dst, params, llama.QuantizeOptions{
+//		IMatrix:         imatrix,
+//		AssumeLibstdcxx: true,
+//	})
+//
+// It returns an error wrapping [ErrNotSupported] if IMatrix or TensorTypes are set without
+// AssumeLibstdcxx, or on platforms other than 64-bit Linux.
+func QuantizeWithOptions(src, dst string, params ModelQuantizeParams, opts QuantizeOptions) error {
+	if err := quantizeABI(); err != nil {
+		return err
+	}
+
+	if params.IMatrix != 0 || params.KvOverrides != 0 || params.TensorTypes != 0 || params.PruneLayers != 0 {
+		return fmt.Errorf("%w: set the imatrix and tensor types with QuantizeOptions", ErrQuantize)
+	}
+
+	if opts.IMatrix == nil && len(opts.TensorTypes) == 0 {
+		return quantize(src, dst, &params)
+	}
+
+	if err := checkCxxLayout(opts.AssumeLibstdcxx); err != nil {
+		return err
+	}
+
+	var imatrix *cxxHashtable
+	if opts.IMatrix != nil {
+		imatrix = newCxxHashtable(opts.IMatrix)
+		params.IMatrix = uintptr(unsafe.Pointer(imatrix))
+	}
+
+	var types *cxxVector[tensorQuantization]
+	if len(opts.TensorTypes) > 0 {
+		values := make([]tensorQuantization, len(opts.TensorTypes))
+		for i, tt := range opts.TensorTypes {
+			if tt.Pattern == "" {
+				return fmt.Errorf("%w: tensor type %d has an empty pattern", ErrQuantize, i)
+			}
+
+			values[i] = tensorQuantization{name: newCxxString(tt.Pattern), quant: int32(tt.Type)}
+		}
+
+		vector := newCxxVector(values)
+		types = &vector
+		params.TensorTypes = uintptr(unsafe.Pointer(types))
+	}
+
+	err := quantize(src, dst, &params)
+
+	// the containers are only referenced by the uintptr fields of params while llama.cpp reads them
+	runtime.KeepAlive(imatrix)
+	runtime.KeepAlive(types)
+
+	return err
+}
+
+// ftypeShortNames are the names of the Ftype values used by the llama-quantize tool.
+var ftypeShortNames = map[string]Ftype{
+	"F32":     FTYPE_ALL_F32,
+	"F16":     FTYPE_MOSTLY_F16,
+	"BF16":    FTYPE_MOSTLY_BF16,
+	"Q4_0":    FTYPE_MOSTLY_Q4_0,
+	"Q4_1":    FTYPE_MOSTLY_Q4_1,
+	"Q5_0":    FTYPE_MOSTLY_Q5_0,
+	"Q5_1":    FTYPE_MOSTLY_Q5_1,
+	"Q8_0":    FTYPE_MOSTLY_Q8_0,
+	"MXFP4":   FTYPE_MOSTLY_MXFP4,
+	"Q2_K":    FTYPE_MOSTLY_Q2_K,
+	"Q2_K_S":  FTYPE_MOSTLY_Q2_K_S,
+	"Q3_K_S":  FTYPE_MOSTLY_Q3_K_S,
+	"Q3_K_M":  FTYPE_MOSTLY_Q3_K_M,
+	"Q3_K_L":  FTYPE_MOSTLY_Q3_K_L,
+	"Q4_K_S":  FTYPE_MOSTLY_Q4_K_S,
+	"Q4_K_M":  FTYPE_MOSTLY_Q4_K_M,
+	"Q5_K_S":  FTYPE_MOSTLY_Q5_K_S,
+	"Q5_K_M":  FTYPE_MOSTLY_Q5_K_M,
+	"Q6_K":    FTYPE_MOSTLY_Q6_K,
+	"TQ1_0":   FTYPE_MOSTLY_TQ1_0,
+	"TQ2_0":   FTYPE_MOSTLY_TQ2_0,
+	"IQ1_S":   FTYPE_MOSTLY_IQ1_S,
+	"IQ1_M":   FTYPE_MOSTLY_IQ1_M,
+	"IQ2_XXS": FTYPE_MOSTLY_IQ2_XXS,
+	"IQ2_XS":  FTYPE_MOSTLY_IQ2_XS,
+	"IQ2_S":   FTYPE_MOSTLY_IQ2_S,
+	"IQ2_M":   FTYPE_MOSTLY_IQ2_M,
+	"IQ3_XXS": FTYPE_MOSTLY_IQ3_XXS,
+	"IQ3_XS":  FTYPE_MOSTLY_IQ3_XS,
+	"IQ3_S":   FTYPE_MOSTLY_IQ3_S,
+	"IQ3_M":   FTYPE_MOSTLY_IQ3_M,
+	"IQ4_NL":  FTYPE_MOSTLY_IQ4_NL,
+	"IQ4_XS":  FTYPE_MOSTLY_IQ4_XS,
+}
+
+// ParseFtype returns the Ftype for a name used by the llama-quantize tool, such as "Q4_K_M" or "Q8_0".
+func ParseFtype(name string) (Ftype, error) {
+	if ftype, ok := ftypeShortNames[strings.ToUpper(name)]; ok {
+		return ftype, nil
+	}
+
+	return 0, fmt.Errorf("llama: unknown ftype %q", name)
+}
@@ -0,0 +1,134 @@
+package llama
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hybridgroup/yzma/pkg/gguf"
+)
+
+func TestQuantize(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	dir := t.TempDir()
+	src := testModelFile(t)
+	if os.Getenv("YZMA_LIB") == "" {
+		src = filepath.Join(dir, "fake.gguf")
+		if err := os.WriteFile(src, []byte("GGUF"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	params := ModelQuantizeDefaultParams()
+	params.Ftype = FTYPE_MOSTLY_Q8_0
+
+	dst := filepath.Join(dir, "q8_0.gguf")
+	if err := Quantize(src, dst, params); err != nil {
+		t.Fatal("unable to quantize model", err)
+	}
+
+	if _, err := os.Stat(dst); err != nil {
+		t.Fatal("quantized model not written", err)
+	}
+
+	if err := Quantize(filepath.Join(dir, "missing.gguf"), dst, params); !errors.Is(err, ErrQuantize) {
+		t.Fatal("expected ErrQuantize", err)
+	}
+
+	params.KvOverrides = 1
+	if err := Quantize(src, dst, params); !errors.Is(err, ErrQuantize) {
+		t.Fatal("expected ErrQuantize for kv overrides", err)
+	}
+}
+
+func TestQuantizeWithOptions(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	dir := t.TempDir()
+	src := testModelFile(t)
+	if os.Getenv("YZMA_LIB") == "" {
+		src = filepath.Join(dir, "fake.gguf")
+		if err := os.WriteFile(src, []byte("GGUF"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// more entries than libstdc++ searches without hashing, with names that do not match any of
+	// the tensors so that llama.cpp does not check their sizes
+	imatrix := make(map[string][]float32)
+	for i := range 32 {
+		imatrix[fmt.Sprintf("yzma.test.%d.weight", i)] = []float32{1, float32(i), 0.5}
+	}
+
+	opts := QuantizeOptions{
+		IMatrix: imatrix,
+		TensorTypes: []TensorQuantization{
+			{Pattern: "attn_v", Type: gguf.TensorTypeQ8_0},
+			{Pattern: `blk\.[0-9]+\.ffn_down\.weight`, Type: gguf.TensorTypeQ4_K},
+		},
+	}
+
+	params := ModelQuantizeDefaultParams()
+	params.Ftype = FTYPE_MOSTLY_Q4_K_M
+
+	dst := filepath.Join(dir, "q4_k_m.gguf")
+	if err := QuantizeWithOptions(src, dst, params, opts); !errors.Is(err, ErrNotSupported) {
+		t.Fatal("expected ErrNotSupported without AssumeLibstdcxx", err)
+	}
+
+	opts.AssumeLibstdcxx = true
+	if err := checkCxxLayout(true); err != nil {
+		t.Skip(err)
+	}
+
+	if err := QuantizeWithOptions(src, dst, params, opts); err != nil {
+		t.Fatal("unable to quantize model with an imatrix and tensor types", err)
+	}
+
+	// llama.cpp checks that every value of the imatrix is finite, which shows that it was read
+	imatrix["yzma.test.nan.weight"] = []float32{float32(math.NaN())}
+	if err := QuantizeWithOptions(src, filepath.Join(dir, "nan.gguf"), params, opts); !errors.Is(err, ErrQuantize) {
+		t.Fatal("expected ErrQuantize for an imatrix with NaN", err)
+	}
+
+	opts.TensorTypes = []TensorQuantization{{Type: gguf.TensorTypeQ8_0}}
+	if err := QuantizeWithOptions(src, dst, params, opts); !errors.Is(err, ErrQuantize) {
+		t.Fatal("expected ErrQuantize for an empty pattern", err)
+	}
+
+	params.IMatrix = 1
+	if err := QuantizeWithOptions(src, dst, params, QuantizeOptions{}); !errors.Is(err, ErrQuantize) {
+		t.Fatal("expected ErrQuantize for the imatrix param", err)
+	}
+}
+
+func TestCxxHash(t *testing.T) {
+	// the values of std::hash<std::string> with libstdc++ on 64-bit platforms
+	tests := map[string]uint64{
+		"":                    6142509188972423790,
+		"abcdefgh":            8664279048047335611,
+		"blk.0.attn_q.weight": 4068745212705744642,
+	}
+
+	for s, want := range tests {
+		if got := cxxHash(s); got != want {
+			t.Errorf("cxxHash(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestParseFtype(t *testing.T) {
+	if ftype, err := ParseFtype("q4_k_m"); err != nil || ftype != FTYPE_MOSTLY_Q4_K_M {
+		t.Fatal("unexpected ftype", ftype, err)
+	}
+
+	if _, err := ParseFtype("Q9_0"); err == nil {
+		t.Fatal("expected error for unknown ftype")
+	}
+}
@@ -1,9 +1,11 @@
 package llama
 
 import (
+	"errors"
 	"math"
 	"unsafe"
 
+	"github.com/hybridgroup/yzma/pkg/loader"
 	"github.com/hybridgroup/yzma/pkg/utils"
 	"github.com/jupiterrider/ffi"
 )
@@ -103,90 +105,98 @@ var (
 	// LLAMA_API void  llama_sampler_accept(struct llama_sampler * smpl, llama_token token);
 	samplerAcceptFunc ffi.Fun
 
+	// LLAMA_API void llama_sampler_reset (struct llama_sampler * smpl);
+	samplerResetFunc ffi.Fun
+
 	// LLAMA_API void lama_sampler_free  (struct llama_sampler * smpl);
 	samplerFreeFunc ffi.Fun
 )
 
-func loadSamplingFuncs(lib ffi.Lib) error {
-	var err error
+func loadSamplingFuncs(lib loader.Library) error {
+	var (
+		err  error
+		errs []error
+	)
 	if samplerChainDefaultParamsFunc, err = lib.Prep("llama_sampler_chain_default_params", &FFISamplerChainParams); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerChainInitFunc, err = lib.Prep("llama_sampler_chain_init", &ffi.TypePointer, &FFISamplerChainParams); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerChainAddFunc, err = lib.Prep("llama_sampler_chain_add", &ffi.TypeVoid, &ffi.TypePointer, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerInitGreedyFunc, err = lib.Prep("llama_sampler_init_greedy", &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerInitDistFunc, err = lib.Prep("llama_sampler_init_dist", &ffi.TypePointer, &ffi.TypeUint32); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerInitLogitBiasFunc, err = lib.Prep("llama_sampler_init_logit_bias", &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerInitPenaltiesFunc, err = lib.Prep("llama_sampler_init_penalties", &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeFloat, &ffi.TypeFloat, &ffi.TypeFloat); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerInitDryFunc, err = lib.Prep("llama_sampler_init_dry", &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeFloat, &ffi.TypeFloat,
 		&ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeUint32); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerInitTopNSigmaFunc, err = lib.Prep("llama_sampler_init_top_n_sigma", &ffi.TypePointer, &ffi.TypeFloat); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerInitTopKFunc, err = lib.Prep("llama_sampler_init_top_k", &ffi.TypePointer, &ffi.TypeSint32); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerInitTypicalFunc, err = lib.Prep("llama_sampler_init_typical", &ffi.TypePointer, &ffi.TypeFloat, &ffi.TypeUint32); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerInitTopPFunc, err = lib.Prep("llama_sampler_init_top_p", &ffi.TypePointer, &ffi.TypeFloat, &ffi.TypeUint32); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerInitMinPFunc, err = lib.Prep("llama_sampler_init_top_p", &ffi.TypePointer, &ffi.TypeFloat, &ffi.TypeUint32); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerInitXTCFunc, err = lib.Prep("llama_sampler_init_xtc", &ffi.TypePointer, &ffi.TypeFloat, &ffi.TypeFloat, &ffi.TypeUint32, &ffi.TypeUint32); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerInitTempExtFunc, err = lib.Prep("llama_sampler_init_temp_ext", &ffi.TypePointer, &ffi.TypeFloat, &ffi.TypeFloat, &ffi.TypeFloat); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
-	if samplerInitGrammarFunc, err = lib.Prep("llama_sampler_init_grammar", &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer); err != nil {
-		return err
-	}
+	samplerInitGrammarFunc, capabilities.Grammar = loader.PrepOptional(lib, "llama_sampler_init_grammar", &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer)
 
 	if samplerSampleFunc, err = lib.Prep("llama_sampler_sample", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if samplerAcceptFunc, err = lib.Prep("llama_sampler_accept", &ffi.TypeVoid, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
-		return err
+		errs = append(errs, err)
+	}
+
+	if samplerResetFunc, err = lib.Prep("llama_sampler_reset", &ffi.TypeVoid, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
 	}
 
-	if samplerFreeFunc, err = lib.Prep("llama_sampler_free", &ffi.TypePointer); err != nil {
-		return err
+	if samplerFreeFunc, err = lib.Prep("llama_sampler_free", &ffi.TypeVoid, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 // SamplerChainDefaultParams returns the default parameters to create a new sampling chain.
@@ -296,7 +306,14 @@ func SamplerInitTempExt(t float32, delta float32, exponent float32) Sampler {
 	return s
 }
 
+// SamplerInitGrammar creates a sampler that constrains output to a GBNF grammar, starting at the root rule.
+// It returns 0 if the grammar cannot be parsed, or if the loaded llama.cpp library does not support
+// grammars. Use [NewGrammarSampler] to find out why.
 func SamplerInitGrammar(vocab Vocab, grammar, root string) Sampler {
+	if !capabilities.Grammar {
+		return 0
+	}
+
 	grmr, _ := utils.BytePtrFromString(grammar)
 	r, _ := utils.BytePtrFromString(root)
 
@@ -306,6 +323,21 @@ func SamplerInitGrammar(vocab Vocab, grammar, root string) Sampler {
 	return s
 }
 
+// NewGrammarSampler is like [SamplerInitGrammar], but returns an error if the grammar cannot be
+// parsed, or [ErrNotSupported] if the loaded llama.cpp library does not support grammars.
+func NewGrammarSampler(vocab Vocab, grammar, root string) (Sampler, error) {
+	if !capabilities.Grammar {
+		return 0, notSupported("llama_sampler_init_grammar")
+	}
+
+	s := SamplerInitGrammar(vocab, grammar, root)
+	if s == 0 {
+		return 0, errors.New("llama: unable to parse grammar")
+	}
+
+	return s, nil
+}
+
 func SamplerSample(smpl Sampler, ctx Context, idx int32) Token {
 	var result ffi.Arg
 	samplerSampleFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&smpl), unsafe.Pointer(&ctx), unsafe.Pointer(&idx))
@@ -317,6 +349,12 @@ func SamplerAccept(smpl Sampler, token Token) {
 	samplerAcceptFunc.Call(nil, unsafe.Pointer(&smpl), unsafe.Pointer(&token))
 }
 
+// SamplerReset resets the state of a sampler, such as the tokens accepted by a grammar or the
+// penalties of the previous tokens, so that it can be used for a new sequence.
+func SamplerReset(smpl Sampler) {
+	samplerResetFunc.Call(nil, unsafe.Pointer(&smpl))
+}
+
 func SamplerFree(smpl Sampler) {
 	samplerFreeFunc.Call(nil, unsafe.Pointer(&smpl))
 }
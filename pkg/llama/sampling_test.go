@@ -0,0 +1,42 @@
+package llama
+
+import (
+	"testing"
+)
+
+func TestSamplerSample(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model := ModelLoadFromFile(testModelFile(t), ModelDefaultParams())
+	if model == 0 {
+		t.Fatal("unable to load model")
+	}
+	defer ModelFree(model)
+
+	lctx := InitFromModel(model, ContextDefaultParams())
+	defer Free(lctx)
+
+	sampler := SamplerChainInit(SamplerChainDefaultParams())
+	SamplerChainAdd(sampler, SamplerInitGreedy())
+	defer SamplerFree(sampler)
+
+	vocab := ModelGetVocab(model)
+	count := Tokenize(vocab, "Once upon a time", nil, true, false)
+	tokens := make([]Token, count)
+	Tokenize(vocab, "Once upon a time", tokens, true, false)
+
+	batch := BatchGetOne(tokens)
+	for i := 0; i < 4; i++ {
+		if result := Decode(lctx, batch); result != 0 {
+			t.Fatal("unable to decode batch", result)
+		}
+
+		token := SamplerSample(sampler, lctx, -1)
+		if token < 0 || int32(token) >= VocabNTokens(vocab) {
+			t.Fatal("invalid token sampled", token)
+		}
+
+		batch = BatchGetOne([]Token{token})
+	}
+}
@@ -0,0 +1,161 @@
+package llama
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"unsafe"
+
+	"github.com/hybridgroup/yzma/pkg/loader"
+	"github.com/hybridgroup/yzma/pkg/utils"
+	"github.com/jupiterrider/ffi"
+)
+
+var (
+	// LLAMA_API struct llama_model * llama_model_load_from_splits(
+	//                          const char ** paths,
+	//                              size_t    n_paths,
+	//           struct llama_model_params    params);
+	modelLoadFromSplitsFunc ffi.Fun
+
+	// LLAMA_API int llama_split_path(char * split_path, size_t maxlen, const char * path_prefix, int split_no, int split_count);
+	splitPathFunc ffi.Fun
+
+	// LLAMA_API int llama_split_prefix(char * split_prefix, size_t maxlen, const char * split_path, int split_no, int split_count);
+	splitPrefixFunc ffi.Fun
+
+	// LLAMA_API void llama_model_save_to_file(
+	//         const struct llama_model * model,
+	//                     const char * path_model);
+	modelSaveToFileFunc ffi.Fun
+)
+
+func loadSplitFuncs(lib loader.Library) error {
+	var (
+		err  error
+		errs []error
+	)
+
+	if modelLoadFromSplitsFunc, err = lib.Prep("llama_model_load_from_splits", &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeUint64, &FFITypeModelParams); err != nil {
+		errs = append(errs, err)
+	}
+
+	if splitPathFunc, err = lib.Prep("llama_split_path", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeUint64, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if splitPrefixFunc, err = lib.Prep("llama_split_prefix", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeUint64, &ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if modelSaveToFileFunc, err = lib.Prep("llama_model_save_to_file", &ffi.TypeVoid, &ffi.TypePointer, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// ModelLoadFromSplits loads a Model that is split across several GGUF files. The paths must be
+// in the order of the splits. It returns 0 if the model could not be loaded.
+//
+// Splits that follow the model-00001-of-00004.gguf naming convention are also loaded by
+// [ModelLoadFromFile] when it is given the first of them, so this is only needed for splits
+// that have been renamed.
+func ModelLoadFromSplits(paths []string, params ModelParams) Model {
+	var model Model
+	if len(paths) == 0 {
+		return model
+	}
+
+	ptrs := make([]*byte, len(paths))
+	for i, path := range paths {
+		ptr, err := utils.BytePtrFromString(path)
+		if err != nil {
+			return model
+		}
+		ptrs[i] = ptr
+	}
+
+	p := unsafe.SliceData(ptrs)
+	n := uint64(len(paths))
+	modelLoadFromSplitsFunc.Call(unsafe.Pointer(&model), unsafe.Pointer(&p), unsafe.Pointer(&n), unsafe.Pointer(&params))
+
+	return model
+}
+
+// ModelSaveToFile writes a Model to a GGUF file.
+func ModelSaveToFile(model Model, path string) {
+	file := &[]byte(path + "\x00")[0]
+	modelSaveToFileFunc.Call(nil, unsafe.Pointer(&model), unsafe.Pointer(&file))
+}
+
+// SplitPath returns the path of a split of a model from its prefix. splitNo starts at 0, so
+// SplitPath("/models/ggml-model-q4_0", 2, 4) returns "/models/ggml-model-q4_0-00003-of-00004.gguf".
+func SplitPath(prefix string, splitNo, splitCount int32) string {
+	buf := make([]byte, len(prefix)+64)
+	b := unsafe.SliceData(buf)
+	maxLen := uint64(len(buf))
+	p := &[]byte(prefix + "\x00")[0]
+
+	var result ffi.Arg
+	splitPathFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&b), unsafe.Pointer(&maxLen), unsafe.Pointer(&p),
+		unsafe.Pointer(&splitNo), unsafe.Pointer(&splitCount))
+
+	return string(buf[:min(int(int32(result)), len(buf)-1)])
+}
+
+// SplitPrefix returns the prefix of the path of a split of a model, or false if path is not
+// split splitNo of splitCount. For example SplitPrefix("/models/ggml-model-q4_0-00003-of-00004.gguf", 2, 4)
+// returns "/models/ggml-model-q4_0".
+func SplitPrefix(path string, splitNo, splitCount int32) (string, bool) {
+	buf := make([]byte, len(path)+1)
+	b := unsafe.SliceData(buf)
+	maxLen := uint64(len(buf))
+	p := &[]byte(path + "\x00")[0]
+
+	var result ffi.Arg
+	splitPrefixFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&b), unsafe.Pointer(&maxLen), unsafe.Pointer(&p),
+		unsafe.Pointer(&splitNo), unsafe.Pointer(&splitCount))
+
+	n := int(int32(result))
+	if n <= 0 {
+		return "", false
+	}
+
+	return string(buf[:min(n, len(buf)-1)]), true
+}
+
+var splitSuffix = regexp.MustCompile(`-(\d{5})-of-(\d{5})\.gguf$`)
+
+// ModelSplitPaths returns the paths of every split of a model from the path of one of them,
+// such as model-00001-of-00004.gguf. If path is not a split, it returns just path.
+// It returns an error if any of the splits does not exist.
+func ModelSplitPaths(path string) ([]string, error) {
+	m := splitSuffix.FindStringSubmatch(path)
+	if m == nil {
+		return []string{path}, nil
+	}
+
+	splitNo, _ := strconv.Atoi(m[1])
+	splitCount, _ := strconv.Atoi(m[2])
+	if splitNo < 1 || splitNo > splitCount {
+		return nil, fmt.Errorf("llama: invalid split %q", path)
+	}
+
+	prefix, ok := SplitPrefix(path, int32(splitNo-1), int32(splitCount))
+	if !ok {
+		return nil, fmt.Errorf("llama: invalid split %q", path)
+	}
+
+	paths := make([]string, splitCount)
+	for i := range paths {
+		paths[i] = SplitPath(prefix, int32(i), int32(splitCount))
+		if _, err := os.Stat(paths[i]); err != nil {
+			return nil, fmt.Errorf("llama: missing split %d of %d: %w", i+1, splitCount, err)
+		}
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,107 @@
+package llama
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitPath(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	if path := SplitPath("/models/ggml-model-q4_0", 2, 4); path != "/models/ggml-model-q4_0-00003-of-00004.gguf" {
+		t.Fatal("wrong split path", path)
+	}
+
+	prefix, ok := SplitPrefix("/models/ggml-model-q4_0-00003-of-00004.gguf", 2, 4)
+	if !ok || prefix != "/models/ggml-model-q4_0" {
+		t.Fatal("wrong split prefix", prefix, ok)
+	}
+
+	if _, ok := SplitPrefix("/models/ggml-model-q4_0-00003-of-00004.gguf", 1, 4); ok {
+		t.Fatal("expected split prefix to fail for the wrong split number")
+	}
+}
+
+func TestModelSplitPaths(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	dir := t.TempDir()
+	prefix := filepath.Join(dir, "model")
+
+	var want []string
+	for i := range int32(3) {
+		path := SplitPath(prefix, i, 3)
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, path)
+	}
+
+	paths, err := ModelSplitPaths(want[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 3 || paths[0] != want[0] || paths[2] != want[2] {
+		t.Fatal("wrong split paths", paths)
+	}
+
+	if paths, err := ModelSplitPaths(filepath.Join(dir, "single.gguf")); err != nil || len(paths) != 1 {
+		t.Fatal("a model that is not split should have one path", paths, err)
+	}
+
+	os.Remove(want[2])
+	if _, err := ModelSplitPaths(want[0]); err == nil {
+		t.Fatal("expected error for a missing split")
+	}
+}
+
+func TestModelLoadFromSplits(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	if model := ModelLoadFromSplits(nil, ModelDefaultParams()); model != 0 {
+		t.Fatal("expected loading no splits to fail")
+	}
+
+	model := ModelLoadFromSplits([]string{testModelFile(t)}, ModelDefaultParams())
+	if model == 0 {
+		t.Fatal("unable to load model")
+	}
+	defer ModelFree(model)
+
+	if ModelNCtxTrain(model) <= 0 {
+		t.Fatal("invalid model")
+	}
+}
+
+func TestModelSaveToFile(t *testing.T) {
+	if os.Getenv("YZMA_LIB") != "" {
+		t.Skip("only for the fake library")
+	}
+
+	testSetup(t)
+	defer testCleanup(t)
+
+	src := filepath.Join(t.TempDir(), "src.gguf")
+	if err := os.WriteFile(src, []byte("GGUF fake"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	model := ModelLoadFromFile(src, ModelDefaultParams())
+	if model == 0 {
+		t.Fatal("unable to load model")
+	}
+	defer ModelFree(model)
+
+	dst := filepath.Join(t.TempDir(), "dst.gguf")
+	ModelSaveToFile(model, dst)
+
+	data, err := os.ReadFile(dst)
+	if err != nil || !bytes.Equal(data, []byte("GGUF fake")) {
+		t.Fatal("model not saved", err)
+	}
+}
@@ -0,0 +1,340 @@
+package llama
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"github.com/hybridgroup/yzma/pkg/loader"
+	"github.com/jupiterrider/ffi"
+)
+
+var (
+	// LLAMA_API size_t llama_state_get_size(struct llama_context * ctx);
+	stateGetSizeFunc ffi.Fun
+
+	// LLAMA_API size_t llama_state_get_data(
+	//         struct llama_context * ctx,
+	//                      uint8_t * dst,
+	//                       size_t   size);
+	stateGetDataFunc ffi.Fun
+
+	// LLAMA_API size_t llama_state_set_data(
+	//         struct llama_context * ctx,
+	//                const uint8_t * src,
+	//                       size_t   size);
+	stateSetDataFunc ffi.Fun
+
+	// LLAMA_API bool llama_state_load_file(
+	//         struct llama_context * ctx,
+	//                   const char * path_session,
+	//                  llama_token * tokens_out,
+	//                       size_t   n_token_capacity,
+	//                       size_t * n_token_count_out);
+	stateLoadFileFunc ffi.Fun
+
+	// LLAMA_API bool llama_state_save_file(
+	//         struct llama_context * ctx,
+	//                   const char * path_session,
+	//            const llama_token * tokens,
+	//                       size_t   n_token_count);
+	stateSaveFileFunc ffi.Fun
+
+	// LLAMA_API size_t llama_state_seq_get_size(
+	//         struct llama_context * ctx,
+	//                 llama_seq_id   seq_id);
+	stateSeqGetSizeFunc ffi.Fun
+
+	// LLAMA_API size_t llama_state_seq_get_data(
+	//         struct llama_context * ctx,
+	//                      uint8_t * dst,
+	//                       size_t   size,
+	//                 llama_seq_id   seq_id);
+	stateSeqGetDataFunc ffi.Fun
+
+	// LLAMA_API size_t llama_state_seq_set_data(
+	//         struct llama_context * ctx,
+	//                const uint8_t * src,
+	//                       size_t   size,
+	//                 llama_seq_id   dest_seq_id);
+	stateSeqSetDataFunc ffi.Fun
+
+	// LLAMA_API size_t llama_state_seq_save_file(
+	//         struct llama_context * ctx,
+	//                   const char * filepath,
+	//                 llama_seq_id   seq_id,
+	//            const llama_token * tokens,
+	//                       size_t   n_token_count);
+	stateSeqSaveFileFunc ffi.Fun
+
+	// LLAMA_API size_t llama_state_seq_load_file(
+	//         struct llama_context * ctx,
+	//                   const char * filepath,
+	//                 llama_seq_id   dest_seq_id,
+	//                  llama_token * tokens_out,
+	//                       size_t   n_token_capacity,
+	//                       size_t * n_token_count_out);
+	stateSeqLoadFileFunc ffi.Fun
+)
+
+var (
+	// ErrStateSave is returned when llama.cpp is unable to save the state of a Context.
+	ErrStateSave = errors.New("llama: unable to save state")
+
+	// ErrStateLoad is returned when llama.cpp is unable to restore the state of a Context, for example
+	// because the file is for a different model or has more tokens than the capacity.
+	ErrStateLoad = errors.New("llama: unable to load state")
+)
+
+func loadStateFuncs(lib loader.Library) error {
+	var (
+		err  error
+		errs []error
+	)
+
+	if stateGetSizeFunc, err = lib.Prep("llama_state_get_size", &ffi.TypeUint64, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if stateGetDataFunc, err = lib.Prep("llama_state_get_data", &ffi.TypeUint64, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeUint64); err != nil {
+		errs = append(errs, err)
+	}
+
+	if stateSetDataFunc, err = lib.Prep("llama_state_set_data", &ffi.TypeUint64, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeUint64); err != nil {
+		errs = append(errs, err)
+	}
+
+	if stateLoadFileFunc, err = lib.Prep("llama_state_load_file", &ffi.TypeUint8, &ffi.TypePointer, &ffi.TypePointer,
+		&ffi.TypePointer, &ffi.TypeUint64, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if stateSaveFileFunc, err = lib.Prep("llama_state_save_file", &ffi.TypeUint8, &ffi.TypePointer, &ffi.TypePointer,
+		&ffi.TypePointer, &ffi.TypeUint64); err != nil {
+		errs = append(errs, err)
+	}
+
+	if stateSeqGetSizeFunc, err = lib.Prep("llama_state_seq_get_size", &ffi.TypeUint64, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if stateSeqGetDataFunc, err = lib.Prep("llama_state_seq_get_data", &ffi.TypeUint64, &ffi.TypePointer, &ffi.TypePointer,
+		&ffi.TypeUint64, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if stateSeqSetDataFunc, err = lib.Prep("llama_state_seq_set_data", &ffi.TypeUint64, &ffi.TypePointer, &ffi.TypePointer,
+		&ffi.TypeUint64, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if stateSeqSaveFileFunc, err = lib.Prep("llama_state_seq_save_file", &ffi.TypeUint64, &ffi.TypePointer, &ffi.TypePointer,
+		&ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeUint64); err != nil {
+		errs = append(errs, err)
+	}
+
+	if stateSeqLoadFileFunc, err = lib.Prep("llama_state_seq_load_file", &ffi.TypeUint64, &ffi.TypePointer, &ffi.TypePointer,
+		&ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeUint64, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	capabilities.StateSave = len(errs) == 0
+
+	return errors.Join(errs...)
+}
+
+// StateGetSize returns the size in bytes of the state of a Context, which includes its memory and
+// the logits and embeddings of the last batch.
+func StateGetSize(ctx Context) uint64 {
+	var result ffi.Arg
+	stateGetSizeFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx))
+
+	return uint64(result)
+}
+
+// StateGetData returns a copy of the state of a Context, which can be restored with [StateSetData].
+func StateGetData(ctx Context) ([]byte, error) {
+	size := StateGetSize(ctx)
+	data := make([]byte, size)
+	dst := unsafe.SliceData(data)
+
+	var result ffi.Arg
+	stateGetDataFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx), unsafe.Pointer(&dst), &size)
+	if uint64(result) == 0 {
+		return nil, ErrStateSave
+	}
+
+	return data[:result], nil
+}
+
+// StateSetData restores the state of a Context from data returned by [StateGetData]. The Context
+// must be for the same Model, and have the same params.
+func StateSetData(ctx Context, data []byte) error {
+	src := unsafe.SliceData(data)
+	size := uint64(len(data))
+
+	var result ffi.Arg
+	stateSetDataFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx), unsafe.Pointer(&src), &size)
+	if uint64(result) == 0 {
+		return ErrStateLoad
+	}
+
+	return nil
+}
+
+// StateSaveFile saves the state of a Context to a file at path, along with the tokens that were
+// decoded to create it, so that they can be compared with a new prompt when the state is loaded.
+func StateSaveFile(ctx Context, path string, tokens []Token) error {
+	file := &[]byte(path + "\x00")[0]
+	toks := unsafe.SliceData(tokens)
+	nTokens := uint64(len(tokens))
+
+	var result ffi.Arg
+	stateSaveFileFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx), unsafe.Pointer(&file), unsafe.Pointer(&toks), &nTokens)
+	if !result.Bool() {
+		return fmt.Errorf("%w to %q", ErrStateSave, path)
+	}
+
+	return nil
+}
+
+// StateLoadFile restores the state of a Context from a file saved with [StateSaveFile], and returns
+// the tokens that were saved with it. capacity is the largest number of tokens to accept, which is
+// usually the size of the context.
+func StateLoadFile(ctx Context, path string, capacity int) ([]Token, error) {
+	if capacity < 0 {
+		return nil, fmt.Errorf("%w from %q: negative capacity %d", ErrStateLoad, path, capacity)
+	}
+
+	if err := checkStateFile(path, SESSION_MAGIC, SESSION_VERSION); err != nil {
+		return nil, err
+	}
+
+	file := &[]byte(path + "\x00")[0]
+	tokens := make([]Token, capacity)
+	toks := unsafe.SliceData(tokens)
+	nCapacity := uint64(capacity)
+	var nTokens uint64
+	nTokensPtr := &nTokens
+
+	var result ffi.Arg
+	stateLoadFileFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx), unsafe.Pointer(&file), unsafe.Pointer(&toks), &nCapacity,
+		unsafe.Pointer(&nTokensPtr))
+	if !result.Bool() {
+		return nil, fmt.Errorf("%w from %q", ErrStateLoad, path)
+	}
+
+	return tokens[:nTokens], nil
+}
+
+// StateSeqGetSize returns the size in bytes of the state of a single sequence.
+func StateSeqGetSize(ctx Context, seqID SeqId) uint64 {
+	var result ffi.Arg
+	stateSeqGetSizeFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx), &seqID)
+
+	return uint64(result)
+}
+
+// StateSeqGetData returns a copy of the state of a single sequence, which can be restored into any
+// sequence with [StateSeqSetData].
+func StateSeqGetData(ctx Context, seqID SeqId) ([]byte, error) {
+	size := StateSeqGetSize(ctx, seqID)
+	data := make([]byte, size)
+	dst := unsafe.SliceData(data)
+
+	var result ffi.Arg
+	stateSeqGetDataFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx), unsafe.Pointer(&dst), &size, &seqID)
+	if uint64(result) == 0 {
+		return nil, ErrStateSave
+	}
+
+	return data[:result], nil
+}
+
+// StateSeqSetData restores the state of a single sequence from data returned by [StateSeqGetData]
+// into destSeqID, replacing what was in that sequence.
+func StateSeqSetData(ctx Context, data []byte, destSeqID SeqId) error {
+	src := unsafe.SliceData(data)
+	size := uint64(len(data))
+
+	var result ffi.Arg
+	stateSeqSetDataFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx), unsafe.Pointer(&src), &size, &destSeqID)
+	if uint64(result) == 0 {
+		return ErrStateLoad
+	}
+
+	return nil
+}
+
+// StateSeqSaveFile saves the state of a single sequence to a file at path, along with the tokens
+// that were decoded to create it.
+func StateSeqSaveFile(ctx Context, path string, seqID SeqId, tokens []Token) error {
+	file := &[]byte(path + "\x00")[0]
+	toks := unsafe.SliceData(tokens)
+	nTokens := uint64(len(tokens))
+
+	var result ffi.Arg
+	stateSeqSaveFileFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx), unsafe.Pointer(&file), &seqID, unsafe.Pointer(&toks), &nTokens)
+	if uint64(result) == 0 {
+		return fmt.Errorf("%w to %q", ErrStateSave, path)
+	}
+
+	return nil
+}
+
+// StateSeqLoadFile restores the state of a single sequence from a file saved with [StateSeqSaveFile]
+// into destSeqID, and returns the tokens that were saved with it. capacity is the largest number of
+// tokens to accept.
+func StateSeqLoadFile(ctx Context, path string, destSeqID SeqId, capacity int) ([]Token, error) {
+	if capacity < 0 {
+		return nil, fmt.Errorf("%w from %q: negative capacity %d", ErrStateLoad, path, capacity)
+	}
+
+	if err := checkStateFile(path, STATE_SEQ_MAGIC, STATE_SEQ_VERSION); err != nil {
+		return nil, err
+	}
+
+	file := &[]byte(path + "\x00")[0]
+	tokens := make([]Token, capacity)
+	toks := unsafe.SliceData(tokens)
+	nCapacity := uint64(capacity)
+	var nTokens uint64
+	nTokensPtr := &nTokens
+
+	var result ffi.Arg
+	stateSeqLoadFileFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&ctx), unsafe.Pointer(&file), &destSeqID, unsafe.Pointer(&toks),
+		&nCapacity, unsafe.Pointer(&nTokensPtr))
+	if uint64(result) == 0 {
+		return nil, fmt.Errorf("%w from %q", ErrStateLoad, path)
+	}
+
+	return tokens[:nTokens], nil
+}
+
+// checkStateFile checks the magic number and version at the start of a state file, which llama.cpp
+// only reports by logging them, so that a file of the wrong kind or from another version of
+// llama.cpp returns a precise error.
+func checkStateFile(path string, magic, version uint32) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrStateLoad, err)
+	}
+	defer f.Close()
+
+	var header [2]uint32
+	if err := binary.Read(f, binary.LittleEndian, &header); err != nil {
+		return fmt.Errorf("%w from %q: unable to read header: %w", ErrStateLoad, path, err)
+	}
+
+	if header[0] != magic {
+		return fmt.Errorf("%w from %q: magic is %#x, expected %#x", ErrStateLoad, path, header[0], magic)
+	}
+
+	if header[1] != version {
+		return fmt.Errorf("%w from %q: version is %d, expected %d", ErrStateLoad, path, header[1], version)
+	}
+
+	return nil
+}
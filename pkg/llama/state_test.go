@@ -0,0 +1,169 @@
+package llama
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hybridgroup/yzma/pkg/fake"
+)
+
+// testStateContext returns a context that has decoded a prompt, along with the tokens of the prompt.
+func testStateContext(t *testing.T, model Model) (Context, []Token) {
+	lctx, err := NewContext(model, ContextDefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := TokenizeString(ModelGetVocab(model), "Are you ready to rock?", TokenizeOptions{AddSpecial: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lctx.Decode(BatchGetOne(tokens)); err != nil {
+		t.Fatal(err)
+	}
+
+	return lctx, tokens
+}
+
+func TestStateData(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer model.Close()
+
+	lctx, tokens := testStateContext(t, model)
+	defer lctx.Close()
+
+	if StateGetSize(lctx) == 0 {
+		t.Fatal("expected state to be supported")
+	}
+
+	data, err := StateGetData(lctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewContext(model, ContextDefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	if err := StateSetData(other, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if MemorySeqPosMax(GetMemory(other), 0) != Pos(len(tokens)-1) {
+		t.Fatal("state was not restored", MemorySeqPosMax(GetMemory(other), 0))
+	}
+
+	seq, err := StateSeqGetData(lctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := StateSeqSetData(other, seq, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if MemorySeqPosMax(GetMemory(other), 1) != Pos(len(tokens)-1) {
+		t.Fatal("sequence was not restored", MemorySeqPosMax(GetMemory(other), 1))
+	}
+}
+
+func TestStateFile(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model, err := LoadModel(testModelFile(t), ModelDefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer model.Close()
+
+	lctx, tokens := testStateContext(t, model)
+	defer lctx.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.bin")
+	if err := StateSaveFile(lctx, path, tokens); err != nil {
+		t.Fatal(err)
+	}
+
+	seqPath := filepath.Join(dir, "seq.bin")
+	if err := StateSeqSaveFile(lctx, seqPath, 0, tokens); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := NewContext(model, ContextDefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	loaded, err := StateLoadFile(other, path, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(loaded, tokens) || MemorySeqPosMax(GetMemory(other), 0) != Pos(len(tokens)-1) {
+		t.Fatal("state was not restored", loaded)
+	}
+
+	loaded, err = StateSeqLoadFile(other, seqPath, 2, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(loaded, tokens) || MemorySeqPosMax(GetMemory(other), 2) != Pos(len(tokens)-1) {
+		t.Fatal("sequence was not restored", loaded)
+	}
+
+	if _, err := StateLoadFile(other, path, 1); !errors.Is(err, ErrStateLoad) {
+		t.Fatal("expected ErrStateLoad for too many tokens", err)
+	}
+
+	if _, err := StateSeqLoadFile(other, path, 0, 512); !errors.Is(err, ErrStateLoad) || !strings.Contains(err.Error(), "magic") {
+		t.Fatal("expected ErrStateLoad for the wrong kind of file", err)
+	}
+
+	oldPath := filepath.Join(dir, "old.bin")
+	if err := os.WriteFile(oldPath, binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, SESSION_MAGIC), 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := StateLoadFile(other, oldPath, 512); !errors.Is(err, ErrStateLoad) || !strings.Contains(err.Error(), "version is 1") {
+		t.Fatal("expected ErrStateLoad for an old version", err)
+	}
+
+	if _, err := StateLoadFile(other, filepath.Join(dir, "missing.bin"), 512); !errors.Is(err, ErrStateLoad) {
+		t.Fatal("expected ErrStateLoad for a missing file", err)
+	}
+
+	if _, err := StateLoadFile(other, path, -1); !errors.Is(err, ErrStateLoad) {
+		t.Fatal("expected ErrStateLoad for a negative capacity", err)
+	}
+
+	if _, err := StateSeqLoadFile(other, seqPath, 0, -1); !errors.Is(err, ErrStateLoad) {
+		t.Fatal("expected ErrStateLoad for a negative capacity", err)
+	}
+}
+
+func TestLoadMissingState(t *testing.T) {
+	err := Load(fake.New().Omit("llama_state_seq_load_file"))
+	defer Load(fake.New())
+
+	if err == nil || !strings.Contains(err.Error(), "llama_state_seq_load_file") {
+		t.Fatal("missing state functions should be an error", err)
+	}
+}
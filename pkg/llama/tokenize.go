@@ -0,0 +1,108 @@
+package llama
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/jupiterrider/ffi"
+)
+
+// TokenizeOptions are the options for [TokenizeString].
+type TokenizeOptions struct {
+	// AddSpecial adds the BOS and EOS tokens if the model is configured to use them.
+	AddSpecial bool
+
+	// ParseSpecial converts special tokens in the text, such as "<|im_start|>", into their tokens
+	// instead of tokenizing them as plain text.
+	ParseSpecial bool
+}
+
+// tokenPool has the buffers that TokenizeString tokenizes into, so that most calls only need to
+// allocate the returned slice. Buffers that grow to more than maxPooledTokens are not kept.
+const maxPooledTokens = 1 << 16
+
+var tokenPool = sync.Pool{
+	New: func() any {
+		buf := make([]Token, 512)
+		return &buf
+	},
+}
+
+// tokenize calls llama_tokenize, and returns its result without changing the sign, unlike [Tokenize].
+// The length of the text is passed to llama_tokenize, so the text does not need to be a C string and
+// can contain NUL bytes.
+func tokenize(vocab Vocab, text string, tokens []Token, addSpecial bool, parseSpecial bool) int32 {
+	txt := unsafe.StringData(text)
+	txtLen := int32(len(text))
+
+	toks := unsafe.SliceData(tokens)
+	nTokensMax := int32(len(tokens))
+
+	var result ffi.Arg
+	tokenizeFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&vocab), unsafe.Pointer(&txt), &txtLen,
+		unsafe.Pointer(&toks), &nTokensMax, &addSpecial, &parseSpecial)
+
+	return int32(result)
+}
+
+// checkTokenizeText returns an error if text cannot be tokenized.
+func checkTokenizeText(text string) error {
+	if !utf8.ValidString(text) {
+		return ErrInvalidUTF8
+	}
+
+	if len(text) > math.MaxInt32 {
+		return fmt.Errorf("%w: text is %d bytes", ErrTokenOverflow, len(text))
+	}
+
+	return nil
+}
+
+// TokenizeString converts text into tokens. Unlike [Tokenize], it sizes the result itself.
+// It returns [ErrInvalidUTF8] if text is not valid UTF-8, and [ErrTokenOverflow] if the text is
+// too long for llama.cpp to tokenize.
+func TokenizeString(vocab Vocab, text string, opts TokenizeOptions) ([]Token, error) {
+	if err := checkTokenizeText(text); err != nil {
+		return nil, err
+	}
+
+	bufp := tokenPool.Get().(*[]Token)
+	defer func() {
+		if cap(*bufp) <= maxPooledTokens {
+			tokenPool.Put(bufp)
+		}
+	}()
+
+	n := tokenize(vocab, text, *bufp, opts.AddSpecial, opts.ParseSpecial)
+	if n == math.MinInt32 {
+		return nil, ErrTokenOverflow
+	}
+
+	if n < 0 {
+		// the buffer is too small, and -n is the number of tokens
+		*bufp = make([]Token, -n)
+		n = tokenize(vocab, text, *bufp, opts.AddSpecial, opts.ParseSpecial)
+		if n < 0 {
+			return nil, ErrTokenOverflow
+		}
+	}
+
+	return append([]Token(nil), (*bufp)[:n]...), nil
+}
+
+// PieceString returns the text for a token, which can be part of a UTF-8 character. Use a
+// [Detokenizer] to convert tokens into text one at a time. If special is true, the text of special
+// tokens such as EOS is returned. It returns [ErrInvalidToken] if the token is not in the vocabulary.
+func PieceString(vocab Vocab, token Token, special bool) (string, error) {
+	if token < 0 || int32(token) >= VocabNTokens(vocab) {
+		return "", fmt.Errorf("%w %d", ErrInvalidToken, token)
+	}
+
+	var buf [64]byte
+	piece, _ := tokenPiece(vocab, buf[:], token, 0, special)
+
+	return string(piece), nil
+}
@@ -0,0 +1,103 @@
+package llama
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTokenizeString(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model := ModelLoadFromFile(testModelFile(t), ModelDefaultParams())
+	if model == 0 {
+		t.Fatal("unable to load model")
+	}
+	defer ModelFree(model)
+
+	vocab := ModelGetVocab(model)
+	text := "Hello, world"
+
+	tokens, err := TokenizeString(vocab, text, TokenizeOptions{AddSpecial: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tokens) == 0 || tokens[0] != VocabBOS(vocab) {
+		t.Fatal("first token is not BOS", tokens)
+	}
+
+	if n := Tokenize(vocab, text, nil, true, false); int(n) != len(tokens) {
+		t.Fatal("wrong number of tokens", len(tokens), n)
+	}
+
+	// longer than the pooled buffer
+	long := strings.Repeat("Are you ready to rock? ", 100)
+	tokens, err = TokenizeString(vocab, long, TokenizeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	for _, token := range tokens {
+		piece, err := PieceString(vocab, token, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sb.WriteString(piece)
+	}
+
+	if sb.String() != long {
+		t.Fatal("tokens do not match the text")
+	}
+
+	// the text is passed with its length, so it can contain NUL bytes
+	withNUL := "ab\x00cd"
+	tokens, err = TokenizeString(vocab, withNUL, TokenizeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sb.Reset()
+	for _, token := range tokens {
+		piece, err := PieceString(vocab, token, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sb.WriteString(piece)
+	}
+
+	if sb.String() != withNUL {
+		t.Fatalf("tokens do not match the text with a NUL: %q", sb.String())
+	}
+
+	if tokens, err := TokenizeString(vocab, "", TokenizeOptions{}); err != nil || len(tokens) != 0 {
+		t.Fatal("expected no tokens for empty text", tokens, err)
+	}
+}
+
+func TestTokenizeStringError(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model := ModelLoadFromFile(testModelFile(t), ModelDefaultParams())
+	if model == 0 {
+		t.Fatal("unable to load model")
+	}
+	defer ModelFree(model)
+
+	vocab := ModelGetVocab(model)
+
+	if _, err := TokenizeString(vocab, "caf\xe9", TokenizeOptions{}); !errors.Is(err, ErrInvalidUTF8) {
+		t.Fatal("expected ErrInvalidUTF8", err)
+	}
+
+	if _, err := PieceString(vocab, -1, false); !errors.Is(err, ErrInvalidToken) {
+		t.Fatal("expected ErrInvalidToken", err)
+	}
+
+	if _, err := PieceString(vocab, Token(VocabNTokens(vocab)), false); !errors.Is(err, ErrInvalidToken) {
+		t.Fatal("expected ErrInvalidToken", err)
+	}
+}
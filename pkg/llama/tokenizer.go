@@ -0,0 +1,165 @@
+package llama
+
+import (
+	"math"
+	"sync"
+)
+
+// Tokenizer converts between text and the tokens of a model's vocabulary. A Tokenizer loaded with
+// [LoadTokenizer] only loads the vocabulary and not the weights of the model, so it is cheap to keep
+// around for counting tokens, for example to check that a prompt fits in a context.
+//
+// A Tokenizer is safe for concurrent use.
+type Tokenizer struct {
+	mu    sync.RWMutex
+	model Model
+	vocab Vocab
+	owned bool
+}
+
+// LoadTokenizer loads just the vocabulary of the model at path. Call [Tokenizer.Close] to free it.
+func LoadTokenizer(path string) (*Tokenizer, error) {
+	params := ModelDefaultParams()
+	params.VocabOnly = 1
+
+	model, err := LoadModel(path, params)
+	if err != nil {
+		return nil, err
+	}
+
+	t := NewTokenizer(model)
+	t.owned = true
+
+	return t, nil
+}
+
+// NewTokenizer returns a Tokenizer for the vocabulary of a Model that is already loaded.
+// The Model must not be freed while the Tokenizer is in use.
+func NewTokenizer(model Model) *Tokenizer {
+	return &Tokenizer{model: model, vocab: ModelGetVocab(model)}
+}
+
+// Close frees the model if the Tokenizer was loaded with [LoadTokenizer].
+// The Tokenizer must not be used afterwards.
+func (t *Tokenizer) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.owned && t.model != 0 {
+		ModelFree(t.model)
+	}
+	t.model, t.vocab = 0, 0
+
+	return nil
+}
+
+// Model returns the Model of the Tokenizer.
+func (t *Tokenizer) Model() Model {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.model
+}
+
+// Vocab returns the Vocab of the Tokenizer.
+func (t *Tokenizer) Vocab() Vocab {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return t.vocab
+}
+
+// Encode converts text into tokens. If addSpecial is true, the BOS and EOS tokens are added if the
+// model is configured to use them. If parseSpecial is true, special tokens in the text such as
+// "<|im_start|>" are converted into their tokens instead of being tokenized as plain text.
+// See [TokenizeString] for the errors that it returns.
+func (t *Tokenizer) Encode(text string, addSpecial, parseSpecial bool) ([]Token, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return TokenizeString(t.vocab, text, TokenizeOptions{AddSpecial: addSpecial, ParseSpecial: parseSpecial})
+}
+
+// Count returns the number of tokens that Encode would return for text, without allocating them.
+func (t *Tokenizer) Count(text string, addSpecial, parseSpecial bool) (int, error) {
+	if err := checkTokenizeText(text); err != nil {
+		return 0, err
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	// with no buffer, llama.cpp returns the negative of the number of tokens
+	n := tokenize(t.vocab, text, nil, addSpecial, parseSpecial)
+	if n == math.MinInt32 {
+		return 0, ErrTokenOverflow
+	}
+
+	return int(-n), nil
+}
+
+// Decode converts tokens back into text. If special is true, special tokens such as BOS and EOS
+// are included in the text. Use a [Detokenizer] to convert tokens one at a time.
+func (t *Tokenizer) Decode(tokens []Token, special bool) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	buf := make([]byte, 4*len(tokens)+16)
+	n := Detokenize(t.vocab, tokens, buf, false, special)
+	if n < 0 {
+		buf = make([]byte, -n)
+		n = Detokenize(t.vocab, tokens, buf, false, special)
+	}
+
+	return string(buf[:max(n, 0)])
+}
+
+// Piece returns the text for a single token. The piece can be part of a UTF-8 character.
+func (t *Tokenizer) Piece(token Token, special bool) string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	piece, _ := tokenPiece(t.vocab, make([]byte, 64), token, 0, special)
+
+	return string(piece)
+}
+
+// NTokens returns the number of tokens in the vocabulary.
+func (t *Tokenizer) NTokens() int32 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return VocabNTokens(t.vocab)
+}
+
+// BOS returns the beginning of sentence token.
+func (t *Tokenizer) BOS() Token {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return VocabBOS(t.vocab)
+}
+
+// EOS returns the end of sentence token.
+func (t *Tokenizer) EOS() Token {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return VocabEOS(t.vocab)
+}
+
+// IsEOG reports whether token marks the end of generation, such as EOS or EOT.
+func (t *Tokenizer) IsEOG(token Token) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return VocabIsEOG(t.vocab, token)
+}
+
+// IsControl reports whether token is a control token, such as BOS or "<|im_start|>".
+func (t *Tokenizer) IsControl(token Token) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return VocabIsControl(t.vocab, token)
+}
@@ -0,0 +1,95 @@
+package llama
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestLoadTokenizer(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	tok, err := LoadTokenizer(testModelFile(t))
+	if err != nil {
+		t.Fatal("unable to load tokenizer", err)
+	}
+	defer tok.Close()
+
+	text := "Are you ready to rock?"
+	tokens, err := tok.Encode(text, true, false)
+	if err != nil || len(tokens) == 0 || tokens[0] != tok.BOS() {
+		t.Fatal("expected tokens starting with BOS", tokens)
+	}
+
+	if n, err := tok.Count(text, true, false); err != nil || n != len(tokens) {
+		t.Fatal("count does not match encode", n, len(tokens), err)
+	}
+
+	if _, err := tok.Count("\xff", false, false); !errors.Is(err, ErrInvalidUTF8) {
+		t.Fatal("expected ErrInvalidUTF8", err)
+	}
+
+	withNUL, err := tok.Encode("a\x00b", false, false)
+	if err != nil || tok.Decode(withNUL, false) != "a\x00b" {
+		t.Fatal("text with a NUL not encoded", withNUL, err)
+	}
+
+	if decoded := tok.Decode(tokens, false); decoded != text {
+		t.Fatalf("decoded %q, expected %q", decoded, text)
+	}
+
+	if piece := tok.Piece(tok.BOS(), false); piece != "" {
+		t.Fatalf("special token should have no piece, got %q", piece)
+	}
+
+	if !tok.IsEOG(tok.EOS()) || tok.NTokens() <= 0 {
+		t.Fatal("wrong special token info")
+	}
+
+	// a vocab only model cannot be used for inference
+	if _, err := NewContext(tok.Model(), ContextDefaultParams()); !errors.Is(err, ErrContextInit) {
+		t.Fatal("expected ErrContextInit", err)
+	}
+}
+
+func TestTokenizerConcurrent(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	tok, err := LoadTokenizer(testModelFile(t))
+	if err != nil {
+		t.Fatal("unable to load tokenizer", err)
+	}
+	defer tok.Close()
+
+	want, err := tok.Count("hello world", false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for range 100 {
+				if n, err := tok.Count("hello world", false, false); err != nil || n != want {
+					t.Error("wrong count", n, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLoadTokenizerError(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	if _, err := LoadTokenizer(""); !errors.Is(err, ErrModelLoad) {
+		t.Fatal("expected ErrModelLoad", err)
+	}
+}
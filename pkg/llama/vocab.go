@@ -1,8 +1,10 @@
 package llama
 
 import (
+	"errors"
 	"unsafe"
 
+	"github.com/hybridgroup/yzma/pkg/loader"
 	"github.com/hybridgroup/yzma/pkg/utils"
 	"github.com/jupiterrider/ffi"
 )
@@ -44,46 +46,175 @@ var (
 	//                         bool   add_special,
 	//                         bool   parse_special);
 	tokenizeFunc ffi.Fun
+
+	// LLAMA_API int32_t llama_detokenize(
+	//     const struct llama_vocab * vocab,
+	//            const llama_token * tokens,
+	//                      int32_t   n_tokens,
+	//                         char * text,
+	//                      int32_t   text_len_max,
+	//                         bool   remove_special,
+	//                         bool   unparse_special);
+	detokenizeFunc ffi.Fun
+
+	// LLAMA_API enum llama_vocab_type llama_vocab_type(const struct llama_vocab * vocab);
+	vocabTypeFunc ffi.Fun
+
+	// LLAMA_API const char * llama_vocab_get_text(const struct llama_vocab * vocab, llama_token token);
+	vocabGetTextFunc ffi.Fun
+
+	// LLAMA_API float llama_vocab_get_score(const struct llama_vocab * vocab, llama_token token);
+	vocabGetScoreFunc ffi.Fun
+
+	// LLAMA_API enum llama_token_attr llama_vocab_get_attr(const struct llama_vocab * vocab, llama_token token);
+	vocabGetAttrFunc ffi.Fun
+
+	// LLAMA_API llama_token llama_vocab_eot(const struct llama_vocab * vocab); // end-of-turn
+	vocabEOTFunc ffi.Fun
+
+	// LLAMA_API llama_token llama_vocab_sep(const struct llama_vocab * vocab); // sentence separator
+	vocabSEPFunc ffi.Fun
+
+	// LLAMA_API llama_token llama_vocab_nl (const struct llama_vocab * vocab); // next-line
+	vocabNLFunc ffi.Fun
+
+	// LLAMA_API llama_token llama_vocab_pad(const struct llama_vocab * vocab); // padding
+	vocabPADFunc ffi.Fun
+
+	// LLAMA_API llama_token llama_vocab_fim_pre(const struct llama_vocab * vocab);
+	vocabFIMPreFunc ffi.Fun
+
+	// LLAMA_API llama_token llama_vocab_fim_suf(const struct llama_vocab * vocab);
+	vocabFIMSufFunc ffi.Fun
+
+	// LLAMA_API llama_token llama_vocab_fim_mid(const struct llama_vocab * vocab);
+	vocabFIMMidFunc ffi.Fun
+
+	// LLAMA_API llama_token llama_vocab_fim_pad(const struct llama_vocab * vocab);
+	vocabFIMPadFunc ffi.Fun
+
+	// LLAMA_API llama_token llama_vocab_fim_rep(const struct llama_vocab * vocab);
+	vocabFIMRepFunc ffi.Fun
+
+	// LLAMA_API llama_token llama_vocab_fim_sep(const struct llama_vocab * vocab);
+	vocabFIMSepFunc ffi.Fun
+
+	// LLAMA_API bool llama_vocab_get_add_bos(const struct llama_vocab * vocab);
+	vocabGetAddBOSFunc ffi.Fun
+
+	// LLAMA_API bool llama_vocab_get_add_eos(const struct llama_vocab * vocab);
+	vocabGetAddEOSFunc ffi.Fun
 )
 
-func loadVocabFuncs(lib ffi.Lib) error {
-	var err error
+func loadVocabFuncs(lib loader.Library) error {
+	var (
+		err  error
+		errs []error
+	)
 	if modelGetVocabFunc, err = lib.Prep("llama_model_get_vocab", &ffi.TypePointer, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if vocabBOSFunc, err = lib.Prep("llama_vocab_bos", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if vocabEOSFunc, err = lib.Prep("llama_vocab_eos", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if vocabIsEOGFunc, err = lib.Prep("llama_vocab_is_eog", &ffi.TypeUint8, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if vocabIsControlFunc, err = lib.Prep("llama_vocab_is_control", &ffi.TypeUint8, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if vocabNTokensFunc, err = lib.Prep("llama_vocab_n_tokens", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if tokenToPieceFunc, err = lib.Prep("llama_token_to_piece", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeSint32,
 		&ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypeUint8); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if tokenizeFunc, err = lib.Prep("llama_tokenize", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32,
 		&ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeUint8, &ffi.TypeUint8); err != nil {
-		return err
+		errs = append(errs, err)
+	}
+
+	if detokenizeFunc, err = lib.Prep("llama_detokenize", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32,
+		&ffi.TypePointer, &ffi.TypeSint32, &ffi.TypeUint8, &ffi.TypeUint8); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabTypeFunc, err = lib.Prep("llama_vocab_type", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabGetTextFunc, err = lib.Prep("llama_vocab_get_text", &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabGetScoreFunc, err = lib.Prep("llama_vocab_get_score", &ffi.TypeFloat, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabGetAttrFunc, err = lib.Prep("llama_vocab_get_attr", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypeSint32); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabEOTFunc, err = lib.Prep("llama_vocab_eot", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabSEPFunc, err = lib.Prep("llama_vocab_sep", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabNLFunc, err = lib.Prep("llama_vocab_nl", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabPADFunc, err = lib.Prep("llama_vocab_pad", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabFIMPreFunc, err = lib.Prep("llama_vocab_fim_pre", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabFIMSufFunc, err = lib.Prep("llama_vocab_fim_suf", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabFIMMidFunc, err = lib.Prep("llama_vocab_fim_mid", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabFIMPadFunc, err = lib.Prep("llama_vocab_fim_pad", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabFIMRepFunc, err = lib.Prep("llama_vocab_fim_rep", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabFIMSepFunc, err = lib.Prep("llama_vocab_fim_sep", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
 	}
 
-	return nil
+	if vocabGetAddBOSFunc, err = lib.Prep("llama_vocab_get_add_bos", &ffi.TypeUint8, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
+
+	if vocabGetAddEOSFunc, err = lib.Prep("llama_vocab_get_add_eos", &ffi.TypeUint8, &ffi.TypePointer); err != nil {
+		errs = append(errs, err)
+	}
 
+	return errors.Join(errs...)
 }
 
 func ModelGetVocab(model Model) Vocab {
@@ -141,16 +272,112 @@ func TokenToPiece(vocab Vocab, token Token, buf []byte, lstrip int32, special bo
 }
 
 func Tokenize(vocab Vocab, text string, tokens []Token, addSpecial bool, parseSpecial bool) int32 {
-	txt, _ := utils.BytePtrFromString(text)
-	txtLen := int32(len(text))
+	// for whatever reason, llama.cpp returns a negative number.
+	return -tokenize(vocab, text, tokens, addSpecial, parseSpecial)
+}
 
-	toks := unsafe.SliceData(tokens)
-	nTokensMax := len(tokens)
+// VocabGetType returns the type of tokenizer that the vocabulary uses.
+func VocabGetType(vocab Vocab) VocabType {
+	var result ffi.Arg
+	vocabTypeFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&vocab))
 
+	return VocabType(int32(result))
+}
+
+// VocabGetText returns the text of a token as it is stored in the vocabulary. Unlike [TokenToPiece],
+// the text is not decoded, so for example a BPE vocabulary returns "Ġhello" for " hello".
+func VocabGetText(vocab Vocab, token Token) string {
+	var text *byte
+	vocabGetTextFunc.Call(unsafe.Pointer(&text), unsafe.Pointer(&vocab), &token)
+
+	return utils.BytePtrToString(text)
+}
+
+// VocabGetScore returns the score of a token, which is used by some tokenizers to choose between merges.
+func VocabGetScore(vocab Vocab, token Token) float32 {
+	var result float32
+	vocabGetScoreFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&vocab), &token)
+
+	return result
+}
+
+// VocabGetAttr returns the attributes of a token.
+func VocabGetAttr(vocab Vocab, token Token) TokenAttr {
 	var result ffi.Arg
-	tokenizeFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&vocab), unsafe.Pointer(&txt), &txtLen,
-		unsafe.Pointer(&toks), &nTokensMax, &addSpecial, &parseSpecial)
+	vocabGetAttrFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&vocab), &token)
 
-	// for whatever reason, llama.cpp returns a negative number.
-	return -int32(result)
+	return TokenAttr(int32(result))
+}
+
+func vocabToken(fn ffi.Fun, vocab Vocab) Token {
+	var token ffi.Arg
+	fn.Call(unsafe.Pointer(&token), unsafe.Pointer(&vocab))
+
+	return Token(int32(token))
+}
+
+// VocabEOT returns the end of turn token, or [TOKEN_NULL] if there is none.
+func VocabEOT(vocab Vocab) Token {
+	return vocabToken(vocabEOTFunc, vocab)
+}
+
+// VocabSEP returns the sentence separator token, or [TOKEN_NULL] if there is none.
+func VocabSEP(vocab Vocab) Token {
+	return vocabToken(vocabSEPFunc, vocab)
+}
+
+// VocabNL returns the newline token, or [TOKEN_NULL] if there is none.
+func VocabNL(vocab Vocab) Token {
+	return vocabToken(vocabNLFunc, vocab)
+}
+
+// VocabPAD returns the padding token, or [TOKEN_NULL] if there is none.
+func VocabPAD(vocab Vocab) Token {
+	return vocabToken(vocabPADFunc, vocab)
+}
+
+// VocabFIMPre returns the fill-in-the-middle prefix token, or [TOKEN_NULL] if there is none.
+func VocabFIMPre(vocab Vocab) Token {
+	return vocabToken(vocabFIMPreFunc, vocab)
+}
+
+// VocabFIMSuf returns the fill-in-the-middle suffix token, or [TOKEN_NULL] if there is none.
+func VocabFIMSuf(vocab Vocab) Token {
+	return vocabToken(vocabFIMSufFunc, vocab)
+}
+
+// VocabFIMMid returns the fill-in-the-middle middle token, or [TOKEN_NULL] if there is none.
+func VocabFIMMid(vocab Vocab) Token {
+	return vocabToken(vocabFIMMidFunc, vocab)
+}
+
+// VocabFIMPad returns the fill-in-the-middle padding token, or [TOKEN_NULL] if there is none.
+func VocabFIMPad(vocab Vocab) Token {
+	return vocabToken(vocabFIMPadFunc, vocab)
+}
+
+// VocabFIMRep returns the fill-in-the-middle repository token, or [TOKEN_NULL] if there is none.
+func VocabFIMRep(vocab Vocab) Token {
+	return vocabToken(vocabFIMRepFunc, vocab)
+}
+
+// VocabFIMSep returns the fill-in-the-middle file separator token, or [TOKEN_NULL] if there is none.
+func VocabFIMSep(vocab Vocab) Token {
+	return vocabToken(vocabFIMSepFunc, vocab)
+}
+
+// VocabGetAddBOS reports whether the BOS token is added when tokenizing with special tokens.
+func VocabGetAddBOS(vocab Vocab) bool {
+	var result ffi.Arg
+	vocabGetAddBOSFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&vocab))
+
+	return result.Bool()
+}
+
+// VocabGetAddEOS reports whether the EOS token is added when tokenizing with special tokens.
+func VocabGetAddEOS(vocab Vocab) bool {
+	var result ffi.Arg
+	vocabGetAddEOSFunc.Call(unsafe.Pointer(&result), unsafe.Pointer(&vocab))
+
+	return result.Bool()
 }
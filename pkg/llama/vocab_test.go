@@ -0,0 +1,109 @@
+package llama
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model := ModelLoadFromFile(testModelFile(t), ModelDefaultParams())
+	if model == 0 {
+		t.Fatal("unable to load model")
+	}
+	defer ModelFree(model)
+
+	vocab := ModelGetVocab(model)
+	text := "Hello, world"
+
+	count := Tokenize(vocab, text, nil, true, false)
+	if count <= 0 {
+		t.Fatal("invalid token count", count)
+	}
+
+	tokens := make([]Token, count)
+	Tokenize(vocab, text, tokens, true, false)
+
+	if tokens[0] != VocabBOS(vocab) {
+		t.Fatal("first token is not BOS", tokens[0])
+	}
+
+	var sb strings.Builder
+	for _, token := range tokens {
+		buf := make([]byte, 64)
+		l := TokenToPiece(vocab, token, buf, 0, false)
+		sb.Write(buf[:l])
+	}
+
+	if strings.TrimSpace(sb.String()) != text {
+		t.Fatalf("tokens do not convert back to text: %q", sb.String())
+	}
+}
+
+func TestVocabIntrospection(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	model := ModelLoadFromFile(testModelFile(t), ModelDefaultParams())
+	if model == 0 {
+		t.Fatal("unable to load model")
+	}
+	defer ModelFree(model)
+
+	vocab := ModelGetVocab(model)
+
+	if typ := VocabGetType(vocab); typ == VOCAB_TYPE_NONE || typ.String() == "unknown" {
+		t.Fatal("invalid vocab type", typ)
+	}
+
+	bos := VocabBOS(vocab)
+	if VocabGetText(vocab, bos) == "" {
+		t.Fatal("BOS token has no text")
+	}
+
+	if attr := VocabGetAttr(vocab, bos); attr&TOKEN_ATTR_CONTROL == 0 {
+		t.Fatal("BOS token should be a control token", attr)
+	}
+
+	if nl := VocabNL(vocab); nl != TOKEN_NULL && VocabGetAttr(vocab, nl)&TOKEN_ATTR_CONTROL != 0 {
+		t.Fatal("newline token should not be a control token")
+	}
+
+	if !VocabGetAddBOS(vocab) {
+		t.Fatal("model should add BOS")
+	}
+
+	// every token should have attributes
+	for token := range Token(VocabNTokens(vocab)) {
+		if VocabGetAttr(vocab, token) == TOKEN_ATTR_UNDEFINED {
+			t.Fatal("token has no attributes", token, VocabGetText(vocab, token), VocabGetScore(vocab, token))
+		}
+	}
+}
+
+func TestVocabTypeString(t *testing.T) {
+	if s := VOCAB_TYPE_BPE.String(); s != "BPE" {
+		t.Fatal("wrong name", s)
+	}
+
+	if s := VocabType(99).String(); s != "unknown" {
+		t.Fatal("wrong name", s)
+	}
+}
+
+func TestTokenAttrString(t *testing.T) {
+	tests := map[TokenAttr]string{
+		TOKEN_ATTR_UNDEFINED:                   "undefined",
+		TOKEN_ATTR_NORMAL:                      "normal",
+		TOKEN_ATTR_CONTROL | TOKEN_ATTR_RSTRIP: "control|rstrip",
+		TOKEN_ATTR_USER_DEF | TokenAttr(1<<12): "user_defined|0x1000",
+	}
+
+	for attr, want := range tests {
+		if s := attr.String(); s != want {
+			t.Fatalf("got %q, want %q", s, want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package loader
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+const guardSize = 256
+
+const guardByte = 0xa5
+
+// CallStruct calls fn with a pointer to memory for a struct of type T, and returns the struct.
+// The memory is followed by guard bytes, so that if the C struct that fn returns is larger than T
+// it returns an error instead of corrupting memory.
+func CallStruct[T any](name string, fn func(p unsafe.Pointer)) (T, error) {
+	var guarded struct {
+		value T
+		guard [guardSize]byte
+	}
+
+	for i := range guarded.guard {
+		guarded.guard[i] = guardByte
+	}
+
+	fn(unsafe.Pointer(&guarded.value))
+
+	written := 0
+	for i, b := range guarded.guard {
+		if b != guardByte {
+			written = i + 1
+		}
+	}
+
+	if written > 0 {
+		return guarded.value, fmt.Errorf("%s is larger than the %d bytes expected (at least %d bytes)",
+			name, unsafe.Sizeof(guarded.value), int(unsafe.Sizeof(guarded.value))+written)
+	}
+
+	return guarded.value, nil
+}
@@ -0,0 +1,30 @@
+package loader
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestCallStruct(t *testing.T) {
+	type params struct {
+		A int32
+		B int32
+	}
+
+	p, err := CallStruct[params]("params", func(ptr unsafe.Pointer) {
+		*(*params)(ptr) = params{A: 1, B: 2}
+	})
+	if err != nil {
+		t.Fatal("unexpected error", err)
+	}
+	if p.A != 1 || p.B != 2 {
+		t.Fatal("struct not returned", p)
+	}
+
+	_, err = CallStruct[params]("params", func(ptr unsafe.Pointer) {
+		*(*[3]int32)(ptr) = [3]int32{1, 2, 3}
+	})
+	if err == nil {
+		t.Fatal("expected an error when writing past the end of the struct")
+	}
+}
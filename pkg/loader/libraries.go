@@ -0,0 +1,271 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/jupiterrider/ffi"
+)
+
+// Names of the llama.cpp libraries, in the order that they need to be loaded.
+var libraryNames = []string{"ggml-base", "ggml", "llama", "mtmd"}
+
+// optionalLibraries are the libraries that are only needed by some packages, such as mtmd for
+// multimodal models, so that they do not need to be installed to use the others.
+var optionalLibraries = map[string]bool{"mtmd": true}
+
+// LoadError is returned when the llama.cpp libraries cannot be found, or when they do not
+// have all of the functions that yzma needs.
+type LoadError struct {
+	// Tried is every library file that was tried, in order.
+	Tried []string
+
+	// Libraries are the libraries that could not be loaded from any of the search paths.
+	Libraries []string
+
+	// Errors are the errors from the library files that were found but could not be loaded, for
+	// example because they are for another architecture or one of their dependencies is missing.
+	Errors []error
+
+	// Symbols are the functions that could not be found in the loaded libraries.
+	Symbols []string
+}
+
+func (e *LoadError) Error() string {
+	var msgs []string
+	if len(e.Libraries) > 0 {
+		msgs = append(msgs, fmt.Sprintf("unable to load %s (tried %s)",
+			strings.Join(e.Libraries, ", "), strings.Join(e.Tried, ", ")))
+	}
+
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+
+	if len(e.Symbols) > 0 {
+		msgs = append(msgs, fmt.Sprintf("missing symbols %s", strings.Join(e.Symbols, ", ")))
+	}
+
+	return "loader: " + strings.Join(msgs, "; ")
+}
+
+// Libraries are the llama.cpp libraries, loaded by their absolute path so that
+// LD_LIBRARY_PATH does not need to be set.
+type Libraries struct {
+	// Paths are the absolute paths of the loaded libraries.
+	Paths []string
+
+	libs    []ffi.Lib
+	missing []string
+
+	// unloaded are the optional libraries that could not be loaded, so that missing symbols from
+	// them can be explained.
+	unloaded []unloadedLibrary
+}
+
+// unloadedLibrary is an optional library that could not be loaded, with every file that was tried
+// and the errors from the files that were found.
+type unloadedLibrary struct {
+	name  string
+	tried []string
+	errs  []error
+}
+
+// LibraryFilename returns the platform specific filename for a library, for example "llama" is
+// "libllama.so" on Linux, "libllama.dylib" on macOS and "llama.dll" on Windows.
+func LibraryFilename(name string) string {
+	switch runtime.GOOS {
+	case "windows":
+		return name + ".dll"
+	case "darwin":
+		return "lib" + name + ".dylib"
+	default:
+		return "lib" + name + ".so"
+	}
+}
+
+// SearchPaths returns the directories that are searched for the llama.cpp libraries, in order:
+// the explicit path if there is one, the YZMA_LIB env variable, the directory of the executable,
+// and then the system library directories.
+func SearchPaths(path string) []string {
+	var paths []string
+	if path != "" {
+		paths = append(paths, path)
+	}
+
+	if os.Getenv("YZMA_LIB") != "" {
+		paths = append(paths, os.Getenv("YZMA_LIB"))
+	}
+
+	if exe, err := os.Executable(); err == nil {
+		paths = append(paths, filepath.Dir(exe))
+	}
+
+	switch runtime.GOOS {
+	case "linux", "freebsd":
+		paths = append(paths, "/usr/local/lib", "/usr/lib", "/usr/lib64")
+	case "darwin":
+		paths = append(paths, "/usr/local/lib", "/opt/homebrew/lib")
+	}
+
+	return paths
+}
+
+// OpenLibraries finds each of the llama.cpp libraries in the [SearchPaths] for path, and loads them.
+// If any of them cannot be loaded, it returns a [*LoadError] with every file that was tried.
+// The mtmd library is optional, so if it cannot be found only the functions of the mtmd package
+// are missing, which is reported by [Libraries.Load] if they are loaded.
+func OpenLibraries(path string) (*Libraries, error) {
+	l := &Libraries{}
+	loadErr := &LoadError{}
+
+	dirs := SearchPaths(path)
+	for _, name := range libraryNames {
+		lib, filename, tried, errs := openLibrary(dirs, LibraryFilename(name))
+		if filename == "" && optionalLibraries[name] {
+			l.unloaded = append(l.unloaded, unloadedLibrary{name: name, tried: tried, errs: errs})
+			continue
+		}
+
+		loadErr.Tried = append(loadErr.Tried, tried...)
+		if filename == "" {
+			loadErr.Libraries = append(loadErr.Libraries, LibraryFilename(name))
+			loadErr.Errors = append(loadErr.Errors, errs...)
+			continue
+		}
+
+		l.libs = append(l.libs, lib)
+		l.Paths = append(l.Paths, filename)
+	}
+
+	if len(loadErr.Libraries) > 0 {
+		l.Close()
+		return nil, loadErr
+	}
+
+	return l, nil
+}
+
+// openLibrary loads the first file with filename in dirs. It returns the absolute path of the
+// file that was loaded, or an empty string if none could be loaded, along with every path tried
+// and the error for each file that was found but could not be loaded.
+func openLibrary(dirs []string, filename string) (ffi.Lib, string, []string, []error) {
+	var tried []string
+	var errs []error
+	for _, dir := range dirs {
+		file, err := filepath.Abs(filepath.Join(dir, filename))
+		if err != nil {
+			continue
+		}
+
+		tried = append(tried, file)
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+
+		lib, err := ffi.Load(file)
+		if err != nil {
+			// dlerror includes the path, but the error on Windows does not
+			if !strings.Contains(err.Error(), file) {
+				err = fmt.Errorf("%s: %w", file, err)
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		return lib, file, tried, nil
+	}
+
+	return ffi.Lib{}, "", tried, errs
+}
+
+// LoadLibraries opens the llama.cpp libraries using [OpenLibraries], and then calls each of the
+// load functions with them, for example:
+//
+//	libs, err := loader.LoadLibraries(path, llama.Load, mtmd.Load)
+//
+// If any of the functions that they need are missing, it returns a [*LoadError] with all of them.
+func LoadLibraries(path string, loads ...func(Library) error) (*Libraries, error) {
+	l, err := OpenLibraries(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.Load(loads...); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Load calls each of the load functions with the libraries. If any of the functions that they
+// need are missing, it returns a [*LoadError] with all of them.
+func (l *Libraries) Load(loads ...func(Library) error) error {
+	l.missing = nil
+
+	var errs []error
+	for _, load := range loads {
+		if err := load(l); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(l.missing) > 0 {
+		loadErr := &LoadError{Symbols: l.missing}
+
+		// the symbols of an optional library are prefixed with its name, such as mtmd_init_from_file
+		for _, u := range l.unloaded {
+			if slices.ContainsFunc(l.missing, func(symbol string) bool { return strings.HasPrefix(symbol, u.name+"_") }) {
+				loadErr.Libraries = append(loadErr.Libraries, LibraryFilename(u.name))
+				loadErr.Tried = append(loadErr.Tried, u.tried...)
+				loadErr.Errors = append(loadErr.Errors, u.errs...)
+			}
+		}
+
+		return loadErr
+	}
+
+	return errors.Join(errs...)
+}
+
+// Prep prepares a function from whichever of the libraries has it.
+func (l *Libraries) Prep(name string, ret *ffi.Type, args ...*ffi.Type) (ffi.Fun, error) {
+	for _, lib := range l.libs {
+		if _, err := lib.Get(name); err != nil {
+			continue
+		}
+
+		return lib.Prep(name, ret, args...)
+	}
+
+	l.missing = append(l.missing, name)
+	return ffi.Fun{}, fmt.Errorf("%s: symbol not found in %s", name, strings.Join(l.Paths, ", "))
+}
+
+// Get returns the address of a function from whichever of the libraries has it.
+func (l *Libraries) Get(name string) (uintptr, error) {
+	for _, lib := range l.libs {
+		if addr, err := lib.Get(name); err == nil {
+			return addr, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%s: symbol not found in %s", name, strings.Join(l.Paths, ", "))
+}
+
+// Close closes all of the libraries.
+func (l *Libraries) Close() error {
+	var errs []error
+	for _, lib := range l.libs {
+		errs = append(errs, lib.Close())
+	}
+	l.libs = nil
+
+	return errors.Join(errs...)
+}
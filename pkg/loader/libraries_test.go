@@ -0,0 +1,148 @@
+package loader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jupiterrider/ffi"
+)
+
+func TestSearchPaths(t *testing.T) {
+	t.Setenv("YZMA_LIB", "/opt/yzma/lib")
+
+	paths := SearchPaths("./lib")
+	if len(paths) < 3 {
+		t.Fatal("not enough search paths", paths)
+	}
+
+	if paths[0] != "./lib" || paths[1] != "/opt/yzma/lib" {
+		t.Fatal("search paths are in the wrong order", paths)
+	}
+}
+
+func TestOpenLibrariesMissing(t *testing.T) {
+	t.Setenv("YZMA_LIB", "")
+	dir := t.TempDir()
+
+	_, err := OpenLibraries(dir)
+	if err == nil {
+		t.Skip("llama.cpp libraries are installed on this system")
+	}
+
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatal("expected a LoadError", err)
+	}
+
+	if !slices.Contains(loadErr.Libraries, LibraryFilename("llama")) {
+		t.Fatal("missing library not reported", loadErr.Libraries)
+	}
+
+	if !slices.Contains(loadErr.Tried, filepath.Join(dir, LibraryFilename("llama"))) {
+		t.Fatal("tried path not reported", loadErr.Tried)
+	}
+
+	if slices.Contains(loadErr.Libraries, LibraryFilename("mtmd")) {
+		t.Fatal("optional library reported as missing", loadErr.Libraries)
+	}
+}
+
+func TestLoadMissingOptionalLibrary(t *testing.T) {
+	tried := filepath.Join("lib", LibraryFilename("mtmd"))
+	l := &Libraries{unloaded: []unloadedLibrary{{name: "mtmd", tried: []string{tried}}}}
+
+	if err := l.Load(func(lib Library) error { return nil }); err != nil {
+		t.Fatal("a missing optional library should not be an error if it is not used", err)
+	}
+
+	err := l.Load(func(lib Library) error {
+		_, err := lib.Prep("llama_init_from_model", &ffi.TypePointer)
+		return err
+	})
+
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatal("expected a LoadError", err)
+	}
+
+	if len(loadErr.Libraries) != 0 || len(loadErr.Tried) != 0 {
+		t.Fatal("optional library reported for a symbol of another library", loadErr.Libraries, loadErr.Tried)
+	}
+
+	err = l.Load(func(lib Library) error {
+		_, err := lib.Prep("mtmd_init_from_file", &ffi.TypePointer)
+		return err
+	})
+
+	if !errors.As(err, &loadErr) {
+		t.Fatal("expected a LoadError", err)
+	}
+
+	if !slices.Equal(loadErr.Libraries, []string{LibraryFilename("mtmd")}) || !slices.Equal(loadErr.Tried, []string{tried}) {
+		t.Fatal("missing optional library not reported", loadErr.Libraries, loadErr.Tried)
+	}
+}
+
+func TestOpenLibrariesInvalid(t *testing.T) {
+	t.Setenv("YZMA_LIB", "")
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, LibraryFilename("ggml-base"))
+	if err := os.WriteFile(file, []byte("not a library"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := OpenLibraries(dir)
+	if err == nil {
+		t.Skip("llama.cpp libraries are installed on this system")
+	}
+
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatal("expected a LoadError", err)
+	}
+
+	if len(loadErr.Errors) == 0 || !strings.Contains(loadErr.Errors[0].Error(), file) {
+		t.Fatal("error loading the library file not reported", loadErr.Errors)
+	}
+
+	if !strings.Contains(err.Error(), loadErr.Errors[0].Error()) {
+		t.Fatal("error loading the library file not in the message", err)
+	}
+}
+
+func TestLoadMissingSymbols(t *testing.T) {
+	l := &Libraries{}
+	err := l.Load(func(lib Library) error {
+		_, err1 := lib.Prep("llama_one", &ffi.TypeVoid)
+		_, err2 := lib.Prep("llama_two", &ffi.TypeVoid)
+		return errors.Join(err1, err2)
+	})
+
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatal("expected a LoadError", err)
+	}
+
+	if !slices.Equal(loadErr.Symbols, []string{"llama_one", "llama_two"}) {
+		t.Fatal("missing symbols not reported", loadErr.Symbols)
+	}
+}
+
+func TestLoadMissingOptional(t *testing.T) {
+	l := &Libraries{}
+	err := l.Load(func(lib Library) error {
+		if _, ok := PrepOptional(lib, "llama_optional", &ffi.TypeVoid); ok {
+			t.Fatal("missing optional function reported as found")
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal("missing optional function should not be an error", err)
+	}
+}
@@ -8,6 +8,12 @@ import (
 	"github.com/jupiterrider/ffi"
 )
 
+// Library is a shared library that functions can be prepared from.
+// It is satisfied by [ffi.Lib], and by the pure-Go backend in the fake package.
+type Library interface {
+	Prep(name string, ret *ffi.Type, args ...*ffi.Type) (ffi.Fun, error)
+}
+
 func LoadLibrary(path string) (ffi.Lib, error) {
 	if os.Getenv("YZMA_LIB") != "" {
 		path = os.Getenv("YZMA_LIB")
@@ -25,3 +31,31 @@ func LoadLibrary(path string) (ffi.Lib, error) {
 
 	return ffi.Load(filename)
 }
+
+// Has reports whether lib has the named function, without preparing it.
+func Has(lib Library, name string) bool {
+	if g, ok := lib.(interface {
+		Get(name string) (uintptr, error)
+	}); ok {
+		_, err := g.Get(name)
+		return err == nil
+	}
+
+	_, err := lib.Prep(name, &ffi.TypeVoid)
+	return err == nil
+}
+
+// PrepOptional prepares a function that is not in every build of llama.cpp. Unlike Prep, a missing
+// function is not an error. It returns false instead, and is not reported by [Libraries.Load].
+func PrepOptional(lib Library, name string, ret *ffi.Type, args ...*ffi.Type) (ffi.Fun, bool) {
+	if !Has(lib, name) {
+		return ffi.Fun{}, false
+	}
+
+	fn, err := lib.Prep(name, ret, args...)
+	if err != nil {
+		return ffi.Fun{}, false
+	}
+
+	return fn, true
+}
@@ -0,0 +1,54 @@
+package mtmd
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/hybridgroup/yzma/pkg/llama"
+	"github.com/hybridgroup/yzma/pkg/loader"
+)
+
+// checkABI calls mtmd_context_params_default and checks that the struct it returns matches the
+// layout of [ContextParamsType].
+func checkABI() error {
+	p, err := loader.CallStruct[ContextParamsType]("mtmd_context_params", func(p unsafe.Pointer) {
+		contextParamsDefaultFunc.Call(p)
+	})
+	if err != nil {
+		return abiError([]string{err.Error()})
+	}
+
+	if problems := checkContextParams(p); len(problems) > 0 {
+		return abiError(problems)
+	}
+
+	return nil
+}
+
+func abiError(problems []string) *llama.ABIError {
+	version, _ := llama.Version()
+	return &llama.ABIError{Struct: "mtmd_context_params", Problems: problems, Version: version}
+}
+
+// checkContextParams returns a description of every field in the default context params that does not
+// have the value that it has in mtmd.
+func checkContextParams(p ContextParamsType) []string {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	// the bools are read as bytes, since a Go bool with any value other than 0 or 1 is invalid.
+	useGPU := *(*uint8)(unsafe.Pointer(&p.UseGPU))
+	printTimings := *(*uint8)(unsafe.Pointer(&p.PrintTimings))
+
+	check(useGPU <= 1, "use_gpu is %d, expected a bool", useGPU)
+	check(printTimings <= 1, "print_timings is %d, expected a bool", printTimings)
+	check(p.Threads > 0, "n_threads is %d", p.Threads)
+	check(p.Verbosity >= llama.LogLevelNone && p.Verbosity <= llama.LogLevelContinue, "verbosity is %d", p.Verbosity)
+	check(p.MediaMarker != nil, "media_marker is NULL")
+
+	return problems
+}
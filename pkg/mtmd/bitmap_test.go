@@ -7,6 +7,7 @@ import (
 	"testing"
 	"unsafe"
 
+	"github.com/hybridgroup/yzma/pkg/fake"
 	"github.com/hybridgroup/yzma/pkg/llama"
 	"github.com/hybridgroup/yzma/pkg/loader"
 )
@@ -29,12 +30,7 @@ func TestBitmap(t *testing.T) {
 }
 
 func testSetup(t *testing.T) {
-	testPath := "../../lib"
-
-	lib, err := loader.LoadLibrary(testPath)
-	if err != nil {
-		t.Fatal("unable to load libary", err.Error())
-	}
+	lib := testLibrary(t)
 	if err := llama.Load(lib); err != nil {
 		t.Fatal("unable to load libary", err.Error())
 	}
@@ -45,6 +41,34 @@ func testSetup(t *testing.T) {
 	llama.BackendInit()
 }
 
+// testLibrary returns the llama.cpp library in YZMA_LIB, or the fake backend if YZMA_LIB is not set.
+func testLibrary(t *testing.T) loader.Library {
+	if os.Getenv("YZMA_LIB") == "" {
+		return fake.New()
+	}
+
+	lib, err := loader.OpenLibraries(os.Getenv("YZMA_LIB"))
+	if err != nil {
+		t.Fatal("unable to load libary", err.Error())
+	}
+
+	return lib
+}
+
+// testModelFiles returns the model and projector to use for tests. When using llama.cpp, the tests
+// that need them are skipped unless YZMA_TEST_MODEL and YZMA_TEST_MMPROJ are set.
+func testModelFiles(t *testing.T) (string, string) {
+	if os.Getenv("YZMA_LIB") == "" {
+		return "fake.gguf", "fake-mmproj.gguf"
+	}
+
+	if os.Getenv("YZMA_TEST_MODEL") == "" || os.Getenv("YZMA_TEST_MMPROJ") == "" {
+		t.Skip("YZMA_TEST_MODEL or YZMA_TEST_MMPROJ not set")
+	}
+
+	return os.Getenv("YZMA_TEST_MODEL"), os.Getenv("YZMA_TEST_MMPROJ")
+}
+
 func testCleanup(t *testing.T) {
 	llama.BackendFree()
 }
@@ -1,16 +1,39 @@
 package mtmd
 
 import (
+	"errors"
 	"sync"
 
-	"github.com/jupiterrider/ffi"
+	"github.com/hybridgroup/yzma/pkg/loader"
 )
 
 var muHelperEvalChunks sync.Mutex
 
-func Load(lib ffi.Lib) error {
-	loadFuncs(lib)
-	loadBitmapFuncs(lib)
+// Load loads the mtmd functions from lib. If any of the required functions cannot be found,
+// the returned error reports every one of them. Optional functions that are missing are not an
+// error, see [GetCapabilities] for which of them are available.
+//
+// Once the functions are loaded, Load checks that the struct returned by the library matches the
+// layout of [ContextParamsType], and returns a [*llama.ABIError] if it does not.
+func Load(lib loader.Library) error {
+	if err := errors.Join(
+		loadFuncs(lib),
+		loadBitmapFuncs(lib),
+	); err != nil {
+		return err
+	}
 
-	return nil
+	return checkABI()
+}
+
+// Capabilities reports which optional features are supported by the loaded mtmd library.
+type Capabilities struct {
+	Audio bool // audio input
+}
+
+var capabilities Capabilities
+
+// GetCapabilities returns the optional features supported by the library that was passed to [Load].
+func GetCapabilities() Capabilities {
+	return capabilities
 }
@@ -0,0 +1,35 @@
+package mtmd
+
+import (
+	"testing"
+
+	"github.com/hybridgroup/yzma/pkg/fake"
+	"github.com/hybridgroup/yzma/pkg/llama"
+)
+
+func TestCapabilities(t *testing.T) {
+	if err := Load(fake.New().Omit("mtmd_support_audio")); err != nil {
+		t.Fatal("missing optional functions should not be an error", err)
+	}
+
+	if GetCapabilities().Audio {
+		t.Fatal("missing audio support reported as supported")
+	}
+
+	if SupportAudio(0) {
+		t.Fatal("audio should not be supported")
+	}
+}
+
+func TestCheckContextParams(t *testing.T) {
+	marker := &[]byte("<__media__>\x00")[0]
+	p := ContextParamsType{UseGPU: true, Threads: 4, Verbosity: llama.LogLevelInfo, MediaMarker: marker}
+	if problems := checkContextParams(p); len(problems) > 0 {
+		t.Fatal("unexpected problems", problems)
+	}
+
+	p.Threads, p.MediaMarker = 0, nil
+	if problems := checkContextParams(p); len(problems) != 2 {
+		t.Fatal("expected 2 problems", problems)
+	}
+}
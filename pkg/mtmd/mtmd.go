@@ -1,9 +1,11 @@
 package mtmd
 
 import (
+	"errors"
 	"unsafe"
 
 	"github.com/hybridgroup/yzma/pkg/llama"
+	"github.com/hybridgroup/yzma/pkg/loader"
 	"github.com/hybridgroup/yzma/pkg/utils"
 	"github.com/jupiterrider/ffi"
 )
@@ -74,6 +76,9 @@ var (
 	// MTMD_API bool mtmd_support_vision(mtmd_context * ctx);
 	supportVisionFunc ffi.Fun
 
+	// MTMD_API bool mtmd_support_audio(mtmd_context * ctx);
+	supportAudioFunc ffi.Fun
+
 	// MTMD_API mtmd_input_chunks *      mtmd_input_chunks_init(void);
 	inputChunksInitFunc ffi.Fun
 
@@ -101,51 +106,56 @@ var (
 	helperEvalChunksFunc ffi.Fun
 )
 
-func loadFuncs(lib ffi.Lib) error {
-	var err error
+func loadFuncs(lib loader.Library) error {
+	var (
+		err  error
+		errs []error
+	)
 
 	if defaultMarkerFunc, err = lib.Prep("mtmd_default_marker", &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if contextParamsDefaultFunc, err = lib.Prep("mtmd_context_params_default", &FFITypeContextParams); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if initFromFileFunc, err = lib.Prep("mtmd_init_from_file", &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &FFITypeContextParams); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if freeFunc, err = lib.Prep("mtmd_free", &ffi.TypeVoid, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if supportVisionFunc, err = lib.Prep("mtmd_support_vision", &ffi.TypeUint8, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
+	supportAudioFunc, capabilities.Audio = loader.PrepOptional(lib, "mtmd_support_audio", &ffi.TypeUint8, &ffi.TypePointer)
+
 	if inputChunksInitFunc, err = lib.Prep("mtmd_input_chunks_init", &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if inputChunksFreeFunc, err = lib.Prep("mtmd_input_chunks_free", &ffi.TypeVoid, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if inputChunksSizeFunc, err = lib.Prep("mtmd_input_chunks_size", &ffi.TypeSint32, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if tokenizeFunc, err = lib.Prep("mtmd_tokenize", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypeUint64); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
 	if helperEvalChunksFunc, err = lib.Prep("mtmd_helper_eval_chunks", &ffi.TypeSint32, &ffi.TypePointer, &ffi.TypePointer, &ffi.TypePointer,
 		&ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypeSint32, &ffi.TypeUint8, &ffi.TypePointer); err != nil {
-		return err
+		errs = append(errs, err)
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 func DefaultMarker() string {
@@ -182,6 +192,19 @@ func SupportVision(ctx Context) bool {
 	return result.Bool()
 }
 
+// SupportAudio returns whether the current model supports audio input.
+// It returns false if the loaded mtmd library does not support audio.
+func SupportAudio(ctx Context) bool {
+	if !capabilities.Audio {
+		return false
+	}
+
+	var result ffi.Arg
+	supportAudioFunc.Call(&result, unsafe.Pointer(&ctx))
+
+	return result.Bool()
+}
+
 // InputChunksInit initializes a list of InputChunk.
 // It can only be populated via Tokenize().
 func InputChunksInit() InputChunks {
@@ -0,0 +1,48 @@
+package mtmd
+
+import (
+	"testing"
+
+	"github.com/hybridgroup/yzma/pkg/llama"
+)
+
+func TestTokenize(t *testing.T) {
+	testSetup(t)
+	defer testCleanup(t)
+
+	modelFile, projFile := testModelFiles(t)
+
+	model := llama.ModelLoadFromFile(modelFile, llama.ModelDefaultParams())
+	if model == 0 {
+		t.Fatal("unable to load model")
+	}
+	defer llama.ModelFree(model)
+
+	ctx := InitFromFile(projFile, model, ContextParamsDefault())
+	if ctx == 0 {
+		t.Fatal("unable to init mtmd context")
+	}
+	defer Free(ctx)
+
+	bitmap := BitmapInitFromFile(ctx, "../../images/domestic_llama.jpg")
+	if bitmap == 0 {
+		t.Fatal("unable to open bitmap")
+	}
+	defer BitmapFree(bitmap)
+
+	output := InputChunksInit()
+	defer InputChunksFree(output)
+
+	input := NewInputText("here is an image: "+DefaultMarker()+"\ndescribe it.", true, true)
+	if result := Tokenize(ctx, output, input, []Bitmap{bitmap}); result != 0 {
+		t.Fatal("unable to tokenize input", result)
+	}
+
+	if InputChunksSize(output) != 3 {
+		t.Fatal("invalid number of chunks", InputChunksSize(output))
+	}
+
+	if result := Tokenize(ctx, output, input, nil); result != 1 {
+		t.Fatal("tokenize should fail when bitmaps do not match markers", result)
+	}
+}